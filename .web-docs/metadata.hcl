@@ -12,4 +12,19 @@ integration {
     name = "Hetzner Cloud"
     slug = "hcloud"
   }
+  component {
+    type = "post-processor"
+    name = "Hetzner Cloud Smoke Test"
+    slug = "smoke-test"
+  }
+  component {
+    type = "post-processor"
+    name = "Hetzner Cloud Promote"
+    slug = "promote"
+  }
+  component {
+    type = "data-source"
+    name = "Hetzner Cloud Channel"
+    slug = "channel"
+  }
 }
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"strings"
+)
+
+// apiRoutes overrides the endpoint and token of the read and write operations.
+type apiRoutes struct {
+	ReadEndpoint  string `mapstructure:"read_endpoint"`
+	ReadToken     string `mapstructure:"read_token"`
+	WriteEndpoint string `mapstructure:"write_endpoint"`
+	WriteToken    string `mapstructure:"write_token"`
+}
+
+// apiRoutingTransport sends the read requests (GET, HEAD) and the write
+// requests (all others) of the client to separate endpoints, e.g. to route
+// the mutations through an approval proxy.
+type apiRoutingTransport struct {
+	endpoint string
+	routes   *apiRoutes
+	next     http.RoundTripper
+}
+
+func (t *apiRoutingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	endpoint, token := t.routes.WriteEndpoint, t.routes.WriteToken
+	if req.Method == http.MethodGet || req.Method == http.MethodHead {
+		endpoint, token = t.routes.ReadEndpoint, t.routes.ReadToken
+	}
+
+	url := req.URL.String()
+	if endpoint == "" || !strings.HasPrefix(url, t.endpoint) {
+		endpoint = t.endpoint
+	}
+
+	// The request must not be modified by a RoundTripper
+	req = req.Clone(req.Context())
+	if endpoint != t.endpoint {
+		routed, err := req.URL.Parse(strings.TrimSuffix(endpoint, "/") + strings.TrimPrefix(url, t.endpoint))
+		if err != nil {
+			return nil, err
+		}
+		req.URL = routed
+		req.Host = routed.Host
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	return t.next.RoundTrip(req)
+}
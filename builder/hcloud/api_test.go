@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+func TestAPIRoutingTransport(t *testing.T) {
+	newServer := func(name string, requests *[]string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*requests = append(*requests, name+" "+r.Method+" "+r.URL.Path+" "+r.Header.Get("Authorization"))
+			w.Header().Set("Content-Type", "application/json")
+			switch r.Method {
+			case http.MethodGet:
+				w.Write([]byte(`{"ssh_key": {"id": 1}}`))
+			default:
+				w.WriteHeader(http.StatusNoContent)
+			}
+		}))
+	}
+
+	var requests []string
+	defaultServer := newServer("default", &requests)
+	defer defaultServer.Close()
+	readServer := newServer("read", &requests)
+	defer readServer.Close()
+	writeServer := newServer("write", &requests)
+	defer writeServer.Close()
+
+	client := hcloud.NewClient(
+		hcloud.WithToken("token"),
+		hcloud.WithEndpoint(defaultServer.URL+"/v1"),
+		hcloud.WithHTTPClient(&http.Client{
+			Transport: &apiRoutingTransport{
+				endpoint: defaultServer.URL + "/v1",
+				routes: &apiRoutes{
+					ReadEndpoint:  readServer.URL + "/proxy/v1",
+					WriteEndpoint: writeServer.URL + "/approval/v1/",
+					WriteToken:    "write-token",
+				},
+				next: http.DefaultTransport,
+			},
+		}),
+	)
+
+	_, _, err := client.SSHKey.GetByID(context.Background(), 1)
+	require.NoError(t, err)
+	_, err = client.SSHKey.Delete(context.Background(), &hcloud.SSHKey{ID: 1})
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"read GET /proxy/v1/ssh_keys/1 Bearer token",
+		"write DELETE /approval/v1/ssh_keys/1 Bearer write-token",
+	}, requests)
+}
@@ -8,7 +8,10 @@ import (
 	"fmt"
 	"log"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
@@ -21,6 +24,15 @@ type Artifact struct {
 	// The ID of the image
 	snapshotId int64
 
+	// The format of the artifact ID, the location and the architecture of the
+	// structured format
+	idFormat     string
+	location     string
+	architecture string
+
+	// hcpLabels are added to the labels of the HCP Packer registry metadata
+	hcpLabels map[string]string
+
 	// The hcloudClient for making API calls
 	hcloudClient *hcloud.Client
 
@@ -38,9 +50,21 @@ func (*Artifact) Files() []string {
 }
 
 func (a *Artifact) Id() string {
+	if a.idFormat == artifactIDFormatStructured {
+		return fmt.Sprintf("%s:%s:%d", a.location, a.architecture, a.snapshotId)
+	}
 	return strconv.FormatInt(a.snapshotId, 10)
 }
 
+// ParseArtifactID returns the snapshot ID of an artifact ID, in the legacy or
+// the structured format.
+func ParseArtifactID(id string) (int64, error) {
+	if i := strings.LastIndex(id, ":"); i >= 0 {
+		id = id[i+1:]
+	}
+	return strconv.ParseInt(id, 10, 64)
+}
+
 func (a *Artifact) String() string {
 	return fmt.Sprintf("A snapshot was created: '%v' (ID: %v)", a.snapshotName, a.snapshotId)
 }
@@ -64,9 +88,14 @@ func (a *Artifact) stateHCPPackerRegistryMetadata() interface{} {
 	if ok {
 		labels["server_type"] = serverType
 	}
+	for key, value := range a.hcpLabels {
+		if _, ok := labels[key]; !ok {
+			labels[key] = value
+		}
+	}
 
 	img := &registryimage.Image{
-		ImageID:      a.Id(),
+		ImageID:      strconv.FormatInt(a.snapshotId, 10),
 		ProviderName: "hetznercloud", // Use explicit name over the builder ID
 		Labels:       labels,
 	}
@@ -79,6 +108,30 @@ func (a *Artifact) stateHCPPackerRegistryMetadata() interface{} {
 	return img
 }
 
+// buildHCPLabels returns the traceability data of the build for the HCP Packer
+// registry: the snapshot labels selected with hcp_labels, the estimated cost,
+// the duration of the build and the summary of the server metrics.
+func buildHCPLabels(c *Config, state multistep.StateBag, duration time.Duration) map[string]string {
+	labels := map[string]string{
+		"build_duration": duration.Round(time.Second).String(),
+	}
+	for _, key := range c.HCPLabels {
+		if value, ok := c.SnapshotLabels[key]; ok {
+			labels[key] = value
+		}
+	}
+	if estimate, ok := state.Get(StateCostEstimate).(*costEstimate); ok {
+		labels["estimated_cost"] = fmt.Sprintf("%.4f %s", estimate.Cost, estimate.Currency)
+	}
+	if metrics, ok := state.Get(StateServerMetrics).(map[string]metricSummary); ok {
+		for name, summary := range metrics {
+			labels[name+".avg"] = strconv.FormatFloat(summary.Avg, 'f', 2, 64)
+			labels[name+".max"] = strconv.FormatFloat(summary.Max, 'f', 2, 64)
+		}
+	}
+	return labels
+}
+
 func (a *Artifact) Destroy() error {
 	log.Printf("Destroying image: %d (%s)", a.snapshotId, a.snapshotName)
 	_, err := a.hcloudClient.Image.Delete(context.TODO(), &hcloud.Image{ID: a.snapshotId})
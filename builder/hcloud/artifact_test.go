@@ -5,7 +5,9 @@ package hcloud
 
 import (
 	"testing"
+	"time"
 
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	registryimage "github.com/hashicorp/packer-plugin-sdk/packer/registry/image"
 	"github.com/mitchellh/mapstructure"
@@ -19,7 +21,7 @@ func TestArtifact_Impl(t *testing.T) {
 
 func TestArtifactId(t *testing.T) {
 	generatedData := make(map[string]interface{})
-	a := &Artifact{"packer-foobar", 42, nil, generatedData}
+	a := &Artifact{snapshotName: "packer-foobar", snapshotId: 42, StateData: generatedData}
 	expected := "42"
 
 	if a.Id() != expected {
@@ -29,7 +31,7 @@ func TestArtifactId(t *testing.T) {
 
 func TestArtifactString(t *testing.T) {
 	generatedData := make(map[string]interface{})
-	a := &Artifact{"packer-foobar", 42, nil, generatedData}
+	a := &Artifact{snapshotName: "packer-foobar", snapshotId: 42, StateData: generatedData}
 	expected := "A snapshot was created: 'packer-foobar' (ID: 42)"
 
 	if a.String() != expected {
@@ -92,3 +94,49 @@ func TestArtifactState_hcpPackerRegistryMetadata(t *testing.T) {
 		},
 	}, image)
 }
+
+func TestArtifactState_hcpPackerRegistryMetadataLabels(t *testing.T) {
+	state := &multistep.BasicStateBag{}
+	state.Put(StateCostEstimate, &costEstimate{Cost: 0.0123, Currency: "EUR"})
+	state.Put(StateServerMetrics, map[string]metricSummary{"cpu": {Avg: 12.5, Max: 98}})
+
+	c := &Config{
+		HCPLabels:      []string{"team", "missing"},
+		SnapshotLabels: map[string]string{"team": "builds", "env": "staging"},
+	}
+
+	artifact := &Artifact{
+		snapshotId: 167438588,
+		StateData: map[string]interface{}{
+			"server_type": "cpx11",
+		},
+		hcpLabels: buildHCPLabels(c, state, 90*time.Second+400*time.Millisecond),
+	}
+
+	var image registryimage.Image
+	require.NoError(t, mapstructure.Decode(artifact.State(registryimage.ArtifactStateURI), &image))
+	assert.Equal(t, map[string]string{
+		"server_type":    "cpx11",
+		"team":           "builds",
+		"build_duration": "1m30s",
+		"estimated_cost": "0.0123 EUR",
+		"cpu.avg":        "12.50",
+		"cpu.max":        "98.00",
+	}, image.Labels)
+}
+
+func TestArtifactIdStructured(t *testing.T) {
+	a := &Artifact{snapshotId: 214093032, idFormat: artifactIDFormatStructured, location: "hel1", architecture: "arm"}
+	assert.Equal(t, "hel1:arm:214093032", a.Id())
+
+	id, err := ParseArtifactID(a.Id())
+	require.NoError(t, err)
+	assert.Equal(t, int64(214093032), id)
+
+	id, err = ParseArtifactID("42")
+	require.NoError(t, err)
+	assert.Equal(t, int64(42), id)
+
+	_, err = ParseArtifactID("hel1:arm:")
+	assert.Error(t, err)
+}
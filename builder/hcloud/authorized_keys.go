@@ -0,0 +1,203 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+const (
+	listAuthorizedKeysPath   = "/tmp/packer-list-authorized-keys.sh"
+	removeAuthorizedKeysPath = "/tmp/packer-remove-authorized-keys.sh"
+	foreignKeysPath          = "/tmp/packer-foreign-authorized-keys"
+)
+
+// listAuthorizedKeysScript prints the entries of the authorized_keys files of
+// all users, prefixed with the path of their file.
+const listAuthorizedKeysScript = `for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys; do
+  [ -f "$f" ] && awk -v f="$f" 'NF { print f "\t" $0 }' "$f"
+done
+rm -f ` + listAuthorizedKeysPath + `
+true
+`
+
+// authorizedKey is an entry of an authorized_keys file.
+type authorizedKey struct {
+	Path string
+	Line string
+}
+
+// stepRecordAuthorizedKeys records the authorized_keys entries of the base
+// image, once connected, so the entries not introduced by the build can be
+// removed before the snapshot.
+type stepRecordAuthorizedKeys struct{}
+
+func (s *stepRecordAuthorizedKeys) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if !c.RemoveForeignAuthorizedKeys {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	ui.Say("Recording authorized SSH keys of the base image...")
+	keys, err := listAuthorizedKeys(ctx, c, comm)
+	if err != nil {
+		return errorHandler(state, ui, "Could not list authorized SSH keys", err)
+	}
+	state.Put(StateAuthorizedKeys, keys)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRecordAuthorizedKeys) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// stepRemoveForeignAuthorizedKeys removes the authorized_keys entries of the
+// base image before the snapshot, except the keys of the build, so stale keys
+// of prior image generations do not persist.
+type stepRemoveForeignAuthorizedKeys struct{}
+
+func (s *stepRemoveForeignAuthorizedKeys) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if !c.RemoveForeignAuthorizedKeys || c.SkipSnapshot {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+	recorded, _ := state.Get(StateAuthorizedKeys).([]authorizedKey)
+
+	buildKeys := [][]byte{}
+	if c.Comm.SSHPublicKey != nil {
+		buildKeys = append(buildKeys, c.Comm.SSHPublicKey)
+	}
+	if publicKeys, ok := state.Get(StateSSHKeysPublic).([]string); ok {
+		for _, publicKey := range publicKeys {
+			buildKeys = append(buildKeys, []byte(publicKey))
+		}
+	}
+
+	foreign := foreignAuthorizedKeys(recorded, buildKeys)
+	if len(foreign) == 0 {
+		ui.Say("No foreign authorized SSH keys found")
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Removing foreign authorized SSH keys...")
+	lines := make([]string, 0, len(foreign))
+	for _, key := range foreign {
+		ui.Message(fmt.Sprintf("Removing '%s' from %s", describeAuthorizedKey(key.Line), key.Path))
+		lines = append(lines, key.Line)
+	}
+	if err := comm.Upload(foreignKeysPath, strings.NewReader(strings.Join(lines, "\n")+"\n"), nil); err != nil {
+		return errorHandler(state, ui, "Could not upload foreign authorized SSH keys", err)
+	}
+
+	script := fmt.Sprintf(`for f in /root/.ssh/authorized_keys /home/*/.ssh/authorized_keys; do
+  [ -f "$f" ] || continue
+  grep -v -x -F -f %[1]s "$f" > "$f.packer" || true
+  cat "$f.packer" > "$f" && rm -f "$f.packer"
+done
+rm -f %[1]s %[2]s
+`, foreignKeysPath, removeAuthorizedKeysPath)
+	if err := comm.Upload(removeAuthorizedKeysPath, strings.NewReader(script), nil); err != nil {
+		return errorHandler(state, ui, "Could not upload script", err)
+	}
+	if _, err := runScript(ctx, c, comm, removeAuthorizedKeysPath); err != nil {
+		return errorHandler(state, ui, "Could not remove foreign authorized SSH keys", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRemoveForeignAuthorizedKeys) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// listAuthorizedKeys returns the entries of the authorized_keys files of all
+// users of the server.
+func listAuthorizedKeys(ctx context.Context, c *Config, comm packersdk.Communicator) ([]authorizedKey, error) {
+	if err := comm.Upload(listAuthorizedKeysPath, strings.NewReader(listAuthorizedKeysScript), nil); err != nil {
+		return nil, err
+	}
+	output, err := runScript(ctx, c, comm, listAuthorizedKeysPath)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := []authorizedKey{}
+	for _, line := range strings.Split(output, "\n") {
+		path, entry, ok := strings.Cut(line, "\t")
+		if !ok {
+			continue
+		}
+		keys = append(keys, authorizedKey{Path: path, Line: entry})
+	}
+	return keys, nil
+}
+
+// runScript runs the uploaded script as root, and returns its output.
+func runScript(ctx context.Context, c *Config, comm packersdk.Communicator, path string) (string, error) {
+	command := "sh " + path
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo -n " + command
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &stdout, Stderr: &stderr}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return "", err
+	}
+	if status := cmd.Wait(); status != 0 {
+		return "", fmt.Errorf("exit status %d: %s", status, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.String(), nil
+}
+
+// foreignAuthorizedKeys returns the recorded entries which are not one of the
+// build keys. Entries which cannot be parsed are foreign as well.
+func foreignAuthorizedKeys(recorded []authorizedKey, buildKeys [][]byte) []authorizedKey {
+	known := map[string]bool{}
+	for _, buildKey := range buildKeys {
+		publicKey, _, _, _, err := gossh.ParseAuthorizedKey(buildKey)
+		if err == nil {
+			known[string(publicKey.Marshal())] = true
+		}
+	}
+
+	foreign := []authorizedKey{}
+	for _, key := range recorded {
+		if strings.HasPrefix(strings.TrimSpace(key.Line), "#") {
+			continue
+		}
+		publicKey, _, _, _, err := gossh.ParseAuthorizedKey([]byte(key.Line))
+		if err == nil && known[string(publicKey.Marshal())] {
+			continue
+		}
+		foreign = append(foreign, key)
+	}
+	return foreign
+}
+
+// describeAuthorizedKey returns the comment of the entry, or its fingerprint.
+func describeAuthorizedKey(line string) string {
+	publicKey, comment, _, _, err := gossh.ParseAuthorizedKey([]byte(line))
+	if err != nil {
+		return "invalid entry"
+	}
+	if comment != "" {
+		return comment
+	}
+	return gossh.FingerprintSHA256(publicKey)
+}
@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"strings"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+func generateAuthorizedKey(t *testing.T, comment string) string {
+	t.Helper()
+
+	publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sshPublicKey, err := gossh.NewPublicKey(publicKey)
+	require.NoError(t, err)
+	return strings.TrimSpace(string(gossh.MarshalAuthorizedKey(sshPublicKey))) + " " + comment
+}
+
+func TestListAuthorizedKeys(t *testing.T) {
+	comm := &packersdk.MockCommunicator{
+		StartStdout: "/root/.ssh/authorized_keys\tssh-ed25519 AAAA old@example\n" +
+			"/home/admin/.ssh/authorized_keys\tno-pty ssh-ed25519 AAAA admin@example\n",
+	}
+	c := &Config{}
+	c.Comm.SSHUsername = "admin"
+
+	keys, err := listAuthorizedKeys(context.Background(), c, comm)
+	require.NoError(t, err)
+	assert.Equal(t, []authorizedKey{
+		{Path: "/root/.ssh/authorized_keys", Line: "ssh-ed25519 AAAA old@example"},
+		{Path: "/home/admin/.ssh/authorized_keys", Line: "no-pty ssh-ed25519 AAAA admin@example"},
+	}, keys)
+	assert.Equal(t, "sudo -n sh /tmp/packer-list-authorized-keys.sh", comm.StartCmd.Command)
+}
+
+func TestForeignAuthorizedKeys(t *testing.T) {
+	temporary := generateAuthorizedKey(t, "packer")
+	configured := generateAuthorizedKey(t, "ops@example")
+	stale := generateAuthorizedKey(t, "old@example")
+
+	recorded := []authorizedKey{
+		{Path: "/root/.ssh/authorized_keys", Line: `command="echo" ` + configured},
+		{Path: "/root/.ssh/authorized_keys", Line: stale},
+		{Path: "/root/.ssh/authorized_keys", Line: "# managed by packer"},
+		{Path: "/home/admin/.ssh/authorized_keys", Line: temporary},
+		{Path: "/home/admin/.ssh/authorized_keys", Line: "garbage"},
+	}
+
+	foreign := foreignAuthorizedKeys(recorded, [][]byte{[]byte(temporary + "\n"), []byte(configured)})
+	assert.Equal(t, []authorizedKey{
+		{Path: "/root/.ssh/authorized_keys", Line: stale},
+		{Path: "/home/admin/.ssh/authorized_keys", Line: "garbage"},
+	}, foreign)
+
+	assert.Equal(t, "old@example", describeAuthorizedKey(stale))
+	assert.Equal(t, "invalid entry", describeAuthorizedKey("garbage"))
+}
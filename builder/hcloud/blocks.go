@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+)
+
+// serverBlock groups the options of the build server. Each option is an
+// alternative to the flat option of the same meaning.
+type serverBlock struct {
+	Name         string            `mapstructure:"name"`
+	Location     string            `mapstructure:"location"`
+	Type         string            `mapstructure:"type"`
+	UpgradeType  string            `mapstructure:"upgrade_type"`
+	Labels       map[string]string `mapstructure:"labels"`
+	Image        string            `mapstructure:"image"`
+	UserData     string            `mapstructure:"user_data"`
+	UserDataFile string            `mapstructure:"user_data_file"`
+}
+
+// snapshotBlock groups the options of the created snapshot. Each option is an
+// alternative to the flat option of the same meaning.
+type snapshotBlock struct {
+	Name    string            `mapstructure:"name"`
+	Labels  map[string]string `mapstructure:"labels"`
+	Notes   string            `mapstructure:"notes"`
+	Retries int               `mapstructure:"retries"`
+}
+
+// connectionBlock groups the options of the plugin to connect to the build
+// server. Each option is an alternative to the flat option of the same
+// meaning, the communicator options remain flat.
+type connectionBlock struct {
+	InitialUsername   string            `mapstructure:"initial_username"`
+	SSHKeys           []string          `mapstructure:"ssh_keys"`
+	SSHKeysLabels     map[string]string `mapstructure:"ssh_keys_labels"`
+	ShareTemporaryKey bool              `mapstructure:"share_temporary_key"`
+}
+
+// mergeBlocks copies the options of the nested blocks into their flat
+// equivalent, and returns an error for each option set in both places.
+func (c *Config) mergeBlocks() []error {
+	var errs []error
+	merge := func(set bool, flatSet bool, blockName, flatName string, apply func()) {
+		if !set {
+			return
+		}
+		if flatSet {
+			errs = append(errs, fmt.Errorf("only one of %s or %s can be specified", blockName, flatName))
+			return
+		}
+		apply()
+	}
+
+	if s := c.Server; s != nil {
+		merge(s.Name != "", c.ServerName != "", "server.name", "server_name", func() { c.ServerName = s.Name })
+		merge(s.Location != "", c.Location != "", "server.location", "location", func() { c.Location = s.Location })
+		merge(s.Type != "", c.ServerType != "", "server.type", "server_type", func() { c.ServerType = s.Type })
+		merge(s.UpgradeType != "", c.UpgradeServerType != "", "server.upgrade_type", "upgrade_server_type", func() { c.UpgradeServerType = s.UpgradeType })
+		merge(s.Labels != nil, c.ServerLabels != nil, "server.labels", "server_labels", func() { c.ServerLabels = s.Labels })
+		merge(s.Image != "", c.Image != "", "server.image", "image", func() { c.Image = s.Image })
+		merge(s.UserData != "", c.UserData != "", "server.user_data", "user_data", func() { c.UserData = s.UserData })
+		merge(s.UserDataFile != "", c.UserDataFile != "", "server.user_data_file", "user_data_file", func() { c.UserDataFile = s.UserDataFile })
+	}
+
+	if s := c.Snapshot; s != nil {
+		merge(s.Name != "", c.SnapshotName != "", "snapshot.name", "snapshot_name", func() { c.SnapshotName = s.Name })
+		merge(s.Labels != nil, c.SnapshotLabels != nil, "snapshot.labels", "snapshot_labels", func() { c.SnapshotLabels = s.Labels })
+		merge(s.Notes != "", c.SnapshotNotes != "", "snapshot.notes", "snapshot_notes", func() { c.SnapshotNotes = s.Notes })
+		merge(s.Retries != 0, c.SnapshotRetries != 0, "snapshot.retries", "snapshot_retries", func() { c.SnapshotRetries = s.Retries })
+	}
+
+	if s := c.Connection; s != nil {
+		merge(s.InitialUsername != "", c.SSHInitialUsername != "", "connection.initial_username", "ssh_initial_username", func() { c.SSHInitialUsername = s.InitialUsername })
+		merge(s.SSHKeys != nil, c.SSHKeys != nil, "connection.ssh_keys", "ssh_keys", func() { c.SSHKeys = s.SSHKeys })
+		merge(s.SSHKeysLabels != nil, c.SSHKeysLabels != nil, "connection.ssh_keys_labels", "ssh_keys_labels", func() { c.SSHKeysLabels = s.SSHKeysLabels })
+		merge(s.ShareTemporaryKey, c.ShareTemporaryKey, "connection.share_temporary_key", "share_temporary_key", func() { c.ShareTemporaryKey = s.ShareTemporaryKey })
+	}
+
+	return errs
+}
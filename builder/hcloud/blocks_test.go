@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeBlocks(t *testing.T) {
+	t.Run("merged", func(t *testing.T) {
+		c := &Config{
+			Server:     &serverBlock{Type: "cpx11", Location: "fsn1", Labels: map[string]string{"key": "value"}},
+			Snapshot:   &snapshotBlock{Name: "snapshot"},
+			Connection: &connectionBlock{InitialUsername: "root"},
+			Image:      "debian-12",
+		}
+		assert.Empty(t, c.mergeBlocks())
+		assert.Equal(t, "cpx11", c.ServerType)
+		assert.Equal(t, "fsn1", c.Location)
+		assert.Equal(t, map[string]string{"key": "value"}, c.ServerLabels)
+		assert.Equal(t, "debian-12", c.Image)
+		assert.Equal(t, "snapshot", c.SnapshotName)
+		assert.Equal(t, "root", c.SSHInitialUsername)
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		c := &Config{
+			Server:       &serverBlock{Type: "cpx11"},
+			Snapshot:     &snapshotBlock{Name: "snapshot"},
+			ServerType:   "cx22",
+			SnapshotName: "other",
+		}
+		errs := c.mergeBlocks()
+		assert.Len(t, errs, 2)
+		assert.EqualError(t, errs[0], "only one of server.type or server_type can be specified")
+		assert.EqualError(t, errs[1], "only one of snapshot.name or snapshot_name can be specified")
+		assert.Equal(t, "cx22", c.ServerType)
+	})
+}
@@ -7,15 +7,20 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/hcl/v2/hcldec"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 
-	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 	"github.com/heroalex/packer-plugin-hcloud/version"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
 
 // The unique id for the builder
@@ -35,10 +40,15 @@ func (b *Builder) Prepare(raws ...interface{}) ([]string, []string, error) {
 		return nil, warnings, errs
 	}
 
-	return nil, nil, nil
+	generatedData := []string{"BuildID", "PrivateIPs", "FloatingIPs",
+		"FirewallIDs", "NetworkIDs", "NetworkNames", "VolumeIDs", "PrimaryIPIDs"}
+
+	return generatedData, warnings, nil
 }
 
 func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook) (packersdk.Artifact, error) {
+	started := time.Now()
+
 	opts := []hcloud.ClientOption{
 		hcloud.WithToken(b.config.HCloudToken),
 		hcloud.WithEndpoint(b.config.Endpoint),
@@ -48,7 +58,30 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 		// This is being redirect by Packer to the appropriate location. If users set `PACKER_LOG=1` it is shown on stderr
 		hcloud.WithDebugWriter(log.Writer()),
 	}
+	var transport http.RoundTripper = http.DefaultTransport
+	if b.config.API != nil {
+		transport = &apiRoutingTransport{
+			endpoint: strings.TrimSuffix(b.config.Endpoint, "/"),
+			routes:   b.config.API,
+			next:     transport,
+		}
+	}
+	var bundle *supportBundle
+	if b.config.SupportBundleDir != "" {
+		bundle = newSupportBundle(&b.config)
+		transport = bundle.transport(transport)
+	}
+	if transport != http.DefaultTransport {
+		opts = append(opts, hcloud.WithHTTPClient(&http.Client{Transport: transport}))
+	}
 	b.hcloudClient = hcloud.NewClient(opts...)
+
+	if b.config.HeartbeatInterval > 0 {
+		heartbeat := newHeartbeatUi(ui, b.config.HeartbeatInterval)
+		defer heartbeat.Stop()
+		ui = heartbeat
+	}
+
 	// Set up the state
 	state := new(multistep.BasicStateBag)
 	state.Put(StateConfig, &b.config)
@@ -56,36 +89,94 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	state.Put(StateHook, hook)
 	state.Put(StateUI, ui)
 
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+	generatedData.Put("BuildID", b.config.buildID)
+
+	// The communicator used to connect as the initial user of the image
+	initialComm := b.config.Comm
+	initialComm.SSHUsername = b.config.SSHInitialUsername
+
 	// Build the steps
 	steps := []multistep.Step{
 		&stepPreValidate{
 			Force:        b.config.PackerForce,
 			SnapshotName: b.config.SnapshotName,
 		},
+		&stepCheckStatus{Stage: "before the build"},
+		&stepEstimateCost{},
 		&communicator.StepSSHKeyGen{
 			CommConf:            &b.config.Comm,
 			SSHTemporaryKeyPair: b.config.Comm.SSH.SSHTemporaryKeyPair,
 		},
-		multistep.If(b.config.PackerDebug && b.config.Comm.SSHPrivateKeyFile == "",
+		&stepCreateSSHKey{},
+		// The key is dumped once uploaded, as a key shared with the other
+		// sources replaces the generated one
+		multistep.If((b.config.PackerDebug || b.config.DevMode) && b.config.Comm.SSHPrivateKeyFile == "",
 			&communicator.StepDumpSSHKey{
 				Path: fmt.Sprintf("ssh_key_%s.pem", b.config.PackerBuildName),
 				SSH:  &b.config.Comm.SSH,
 			},
 		),
-		&stepCreateSSHKey{},
+		&stepCreateNetwork{},
+		&stepCreatePlacementGroup{},
+		&stepCreateFirewall{},
 		&stepCreateServer{},
-		&communicator.StepConnect{
+		&stepLeaveRescue{},
+		&stepCreateScratchVolume{},
+		&stepAssignFloatingIPs{},
+		&stepAttachVirtIOISO{},
+		&stepPowerOnServer{},
+		&stepTypeBootCommand{},
+		&stepProtectBuildServer{},
+		&stepEnableBackups{},
+		&stepRunRescueCommands{},
+		&stepWaitForPort{},
+		multistep.If(b.config.SSHInitialUsername != "",
+			&stepConnectDiagnostics{communicator.StepConnect{
+				Config:    &initialComm,
+				Host:      getServerIP,
+				SSHConfig: initialSSHConfig(&initialComm, &b.config.Comm),
+			}},
+		),
+		&stepSwitchUser{},
+		&stepConnectDiagnostics{communicator.StepConnect{
 			Config:    &b.config.Comm,
 			Host:      getServerIP,
 			SSHConfig: b.config.Comm.SSHConfigFunc(),
+		}},
+		&stepMountScratchVolume{},
+		&stepMountVolumes{},
+		&stepWaitForBootstrap{},
+		&stepCheckConnectivity{},
+		&stepRecordAuthorizedKeys{},
+		&stepDevModeProvision{},
+		&stepCollectMetrics{},
+		&stepWriteImageInfo{},
+		&stepValidateSSHD{
+			SSHConfig: b.config.Comm.SSHConfigFunc(),
 		},
-		&commonsteps.StepProvision{},
+		&stepCheckTaint{},
+		&stepRemoveForeignAuthorizedKeys{},
 		&commonsteps.StepCleanupTempKeys{
 			Comm: &b.config.Comm,
 		},
+		&stepPauseBeforeSnapshot{},
+		&stepPostProvisionRescue{},
 		&stepShutdownServer{},
+		&stepUpgradeServerType{},
+		&stepDetachVolumes{},
+		&stepCaptureServerMetadata{},
+		&stepCheckStatus{Stage: "before the snapshot"},
 		&stepCreateSnapshot{},
+		&stepShowLabelsDiff{},
+		&stepVerifyBoot{},
+		&stepFinalizeKeptServer{},
+		&stepProtectKeptServer{},
 	}
+	if bundle != nil {
+		steps = bundle.wrap(steps)
+	}
+
 	// Run the steps
 	b.runner = commonsteps.NewRunner(steps, b.config.PackerConfig, ui)
 	b.runner.Run(ctx, state)
@@ -101,14 +192,27 @@ func (b *Builder) Run(ctx context.Context, ui packersdk.Ui, hook packersdk.Hook)
 	artifact := &Artifact{
 		snapshotName: state.Get(StateSnapshotName).(string),
 		snapshotId:   state.Get(StateSnapshotID).(int64),
+		idFormat:     b.config.ArtifactIDFormat,
+		location:     b.config.Location,
 		hcloudClient: b.hcloudClient,
 		StateData: map[string]interface{}{
+			"snapshot_id":     strconv.FormatInt(state.Get(StateSnapshotID).(int64), 10),
 			"generated_data":  state.Get(StateGeneratedData),
 			"source_image":    b.config.Image,
 			"source_image_id": state.Get(StateSourceImageID),
 			"server_type":     b.config.ServerType,
 		},
 	}
+	if serverType, ok := state.Get(StateServerType).(*hcloud.ServerType); ok {
+		artifact.architecture = string(serverType.Architecture)
+	}
+	if metadata, ok := state.GetOk(StateServerMetadata); ok {
+		artifact.StateData["server_metadata"] = metadata
+	}
+	if metrics, ok := state.GetOk(StateServerMetrics); ok {
+		artifact.StateData["server_metrics"] = metrics
+	}
+	artifact.hcpLabels = buildHCPLabels(&b.config, state, time.Since(started))
 
 	return artifact, nil
 }
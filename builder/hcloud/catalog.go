@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// The catalog of the locations and server types offered by Hetzner Cloud. It is
+// refreshed at release time, and only used to detect typos before a build
+// starts, the API remains the source of truth.
+var (
+	catalogLocations = []string{
+		"ash",
+		"fsn1",
+		"hel1",
+		"hil",
+		"nbg1",
+		"sin",
+	}
+	catalogServerTypes = []string{
+		"cax11", "cax21", "cax31", "cax41",
+		"ccx13", "ccx23", "ccx33", "ccx43", "ccx53", "ccx63",
+		"cpx11", "cpx21", "cpx31", "cpx41", "cpx51",
+		"cpx12", "cpx22", "cpx32", "cpx42", "cpx52", "cpx62",
+		"cx22", "cx32", "cx42", "cx52",
+		"cx23", "cx33", "cx43", "cx53",
+	}
+)
+
+// maxSuggestionDistance is the maximum edit distance for a catalog entry to be
+// suggested as a replacement of an unknown value.
+const maxSuggestionDistance = 2
+
+// validateCatalogValue returns an error suggesting the closest catalog entries
+// when the value is unknown to the catalog. Values that are IDs, or too far away
+// from any catalog entry, are left for the API to validate.
+func validateCatalogValue(option string, value string, catalog []string) error {
+	if value == "" || slices.Contains(catalog, value) {
+		return nil
+	}
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return nil
+	}
+
+	suggestions := suggest(value, catalog)
+	if len(suggestions) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%s '%s' is unknown, did you mean %s?", option, value, strings.Join(suggestions, " / "))
+}
+
+// suggest returns up to three catalog entries closest to the value.
+func suggest(value string, catalog []string) []string {
+	type candidate struct {
+		name     string
+		distance int
+	}
+
+	candidates := make([]candidate, 0)
+	for _, name := range catalog {
+		distance := levenshtein(strings.ToLower(value), name)
+		if distance <= maxSuggestionDistance {
+			candidates = append(candidates, candidate{name, distance})
+		}
+	}
+	slices.SortStableFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.name, b.name)
+	})
+
+	result := make([]string, 0, 3)
+	for _, c := range candidates {
+		if len(result) == cap(result) {
+			break
+		}
+		result = append(result, c.name)
+	}
+	return result
+}
+
+// levenshtein returns the edit distance between a and b.
+func levenshtein(a, b string) int {
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(prev[j]+1, curr[j-1]+1, prev[j-1]+cost)
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(b)]
+}
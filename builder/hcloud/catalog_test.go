@@ -0,0 +1,63 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCatalogValue(t *testing.T) {
+	testCases := []struct {
+		name    string
+		option  string
+		value   string
+		catalog []string
+		wantErr string
+	}{
+		{
+			name:    "known location",
+			option:  "location",
+			value:   "fsn1",
+			catalog: catalogLocations,
+		},
+		{
+			name:    "misspelled location",
+			option:  "location",
+			value:   "fns1",
+			catalog: catalogLocations,
+			wantErr: "location 'fns1' is unknown, did you mean fsn1?",
+		},
+		{
+			name:    "misspelled server type",
+			option:  "server_type",
+			value:   "cx22x",
+			catalog: catalogServerTypes,
+			wantErr: "server_type 'cx22x' is unknown, did you mean cx22 / cpx22 / cx23?",
+		},
+		{
+			name:    "server type id",
+			option:  "server_type",
+			value:   "22",
+			catalog: catalogServerTypes,
+		},
+		{
+			name:    "unknown server type without suggestion",
+			option:  "server_type",
+			value:   "gpu-large",
+			catalog: catalogServerTypes,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := validateCatalogValue(tc.option, tc.value, tc.catalog)
+			if tc.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.EqualError(t, err, tc.wantErr)
+			}
+		})
+	}
+}
@@ -1,16 +1,23 @@
 // Copyright (c) HashiCorp, Inc.
 // SPDX-License-Identifier: MPL-2.0
 
-//go:generate packer-sdc mapstructure-to-hcl2 -type Config,imageFilter
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,imageFilter,temporaryNetwork,networkAttachment,scratchVolume,verifyBootTarget,serverTypeSpec,apiRoutes,naming,serverBlock,snapshotBlock,connectionBlock,policy
 
 package hcloud
 
 import (
 	"errors"
 	"fmt"
+	"net/netip"
+	"net/url"
 	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/hashicorp/packer-plugin-sdk/bootcommand"
 	"github.com/hashicorp/packer-plugin-sdk/common"
 	"github.com/hashicorp/packer-plugin-sdk/communicator"
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -20,55 +27,303 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/uuid"
 	"github.com/mitchellh/mapstructure"
 
+	"github.com/heroalex/packer-plugin-hcloud/version"
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
 
+// snapshotNotesLabel is the label holding the snapshot_notes.
+const snapshotNotesLabel = "notes"
+
+// buildIDLabel is the label applied to every resource created by a build,
+// holding the unique id of the build.
+const buildIDLabel = "packer.build_id"
+
+// The timings of the upgrade of the server type.
+const (
+	upgradeBeforeProvisioning = "before_provisioning"
+	upgradeAfterProvisioning  = "after_provisioning"
+)
+
+// The power states of the kept server at the end of the build.
+const (
+	keptServerStateOff     = "off"
+	keptServerStateRunning = "running"
+)
+
+// The labels describing the build, applied to the build server.
+const (
+	packerVersionLabel = "packer.version"
+	pluginVersionLabel = "packer.plugin_version"
+	buildNameLabel     = "packer.build_name"
+	sourceImageLabel   = "packer.source_image"
+)
+
+// defaultSnapshotNameTemplate is the template of the snapshot name, when the
+// environment does not configure another one.
+const defaultSnapshotNameTemplate = "packer-{{timestamp}}"
+
+var validUsername = regexp.MustCompile(`^[a-z_][a-z0-9_-]*$`)
+
 type Config struct {
 	common.PackerConfig `mapstructure:",squash"`
 	Comm                communicator.Config `mapstructure:",squash"`
 
-	HCloudToken string `mapstructure:"token"`
-	Endpoint    string `mapstructure:"endpoint"`
+	HCloudToken string     `mapstructure:"token"`
+	Endpoint    string     `mapstructure:"endpoint"`
+	API         *apiRoutes `mapstructure:"api"`
+
+	APIPageSize  int `mapstructure:"api_page_size"`
+	APIListLimit int `mapstructure:"api_list_limit"`
+
+	PollInterval      time.Duration `mapstructure:"poll_interval"`
+	HeartbeatInterval time.Duration `mapstructure:"heartbeat_interval"`
 
-	PollInterval time.Duration `mapstructure:"poll_interval"`
+	PauseAfterServerReady time.Duration `mapstructure:"pause_after_server_ready"`
+	PortCheckTimeout      time.Duration `mapstructure:"port_check_timeout"`
+	PauseBeforeSnapshot   time.Duration `mapstructure:"pause_before_snapshot"`
+
+	Naming *naming `mapstructure:"naming"`
+
+	ServerNameConflict string `mapstructure:"server_name_conflict"`
+
+	Server     *serverBlock     `mapstructure:"server"`
+	Snapshot   *snapshotBlock   `mapstructure:"snapshot"`
+	Connection *connectionBlock `mapstructure:"connection"`
 
 	ServerName        string            `mapstructure:"server_name"`
 	Location          string            `mapstructure:"location"`
+	Datacenter        string            `mapstructure:"datacenter"`
 	ServerType        string            `mapstructure:"server_type"`
 	ServerLabels      map[string]string `mapstructure:"server_labels"`
 	UpgradeServerType string            `mapstructure:"upgrade_server_type"`
+	Architecture      string            `mapstructure:"architecture"`
 	Image             string            `mapstructure:"image"`
 	ImageFilter       *imageFilter      `mapstructure:"image_filter"`
+	FailOnEOL         bool              `mapstructure:"fail_on_eol"`
+	EOLWarningPeriod  time.Duration     `mapstructure:"eol_warning_period"`
+
+	ServerTypeClass     string  `mapstructure:"server_type_class"`
+	ServerTypeMinCores  int     `mapstructure:"server_type_min_cores"`
+	ServerTypeMinMemory float32 `mapstructure:"server_type_min_memory"`
+
+	ServerTypeSpec *serverTypeSpec `mapstructure:"server_type_spec"`
+
+	SnapshotName    string            `mapstructure:"snapshot_name"`
+	SnapshotLabels  map[string]string `mapstructure:"snapshot_labels"`
+	SnapshotNotes   string            `mapstructure:"snapshot_notes"`
+	SnapshotRetries int               `mapstructure:"snapshot_retries"`
+	UserData        string            `mapstructure:"user_data"`
+	UserDataFile    string            `mapstructure:"user_data_file"`
+	DNSServers      []string          `mapstructure:"dns_servers"`
+	SSHKeys         []string          `mapstructure:"ssh_keys"`
+	SSHKeysLabels   map[string]string `mapstructure:"ssh_keys_labels"`
+
+	InheritServerLabels     bool     `mapstructure:"inherit_server_labels"`
+	InheritServerLabelsKeys []string `mapstructure:"inherit_server_labels_keys"`
+
+	APTMirror         string `mapstructure:"apt_mirror"`
+	APTSecurityMirror string `mapstructure:"apt_security_mirror"`
+
+	BootstrapScript  string        `mapstructure:"bootstrap_script"`
+	BootstrapTimeout time.Duration `mapstructure:"bootstrap_timeout"`
+
+	ConnectivityCheckURL      string `mapstructure:"connectivity_check_url"`
+	ConnectivityCheckIPFamily string `mapstructure:"connectivity_check_ip_family"`
+
+	RemoveForeignAuthorizedKeys bool `mapstructure:"remove_foreign_authorized_keys"`
 
-	SnapshotName   string            `mapstructure:"snapshot_name"`
-	SnapshotLabels map[string]string `mapstructure:"snapshot_labels"`
-	UserData       string            `mapstructure:"user_data"`
-	UserDataFile   string            `mapstructure:"user_data_file"`
-	SSHKeys        []string          `mapstructure:"ssh_keys"`
-	SSHKeysLabels  map[string]string `mapstructure:"ssh_keys_labels"`
+	DevMode      bool     `mapstructure:"dev_mode"`
+	DevModeWatch []string `mapstructure:"dev_mode_watch"`
 
-	Networks           []int64  `mapstructure:"networks"`
+	ShareTemporaryKey bool `mapstructure:"share_temporary_key"`
+
+	SSHInitialUsername string `mapstructure:"ssh_initial_username"`
+
+	Networks           []string `mapstructure:"networks"`
 	PublicIPv4         string   `mapstructure:"public_ipv4"`
 	PublicIPv4Disabled bool     `mapstructure:"public_ipv4_disabled"`
 	PublicIPv6         string   `mapstructure:"public_ipv6"`
 	PublicIPv6Disabled bool     `mapstructure:"public_ipv6_disabled"`
 	Firewalls          []string `mapstructure:"firewalls"`
 	Volumes            []string `mapstructure:"volumes"`
+	VolumeSelector     string   `mapstructure:"volume_selector"`
+
+	VolumeAutomount  bool              `mapstructure:"volume_automount"`
+	VolumeMountPaths map[string]string `mapstructure:"volume_mount_paths"`
+
+	IgnoreMissing []string `mapstructure:"ignore_missing"`
+
+	TemporaryFirewall      bool   `mapstructure:"temporary_firewall"`
+	TemporaryFirewallIPURL string `mapstructure:"temporary_firewall_ip_url"`
+
+	NetworkAttachments []networkAttachment `mapstructure:"network"`
+
+	PrimaryIPAutoDelete *bool             `mapstructure:"primary_ip_auto_delete"`
+	PrimaryIPLabels     map[string]string `mapstructure:"primary_ip_labels"`
+
+	KeepPrimaryIP bool `mapstructure:"keep_primary_ip"`
+
+	FloatingIPs []string `mapstructure:"floating_ips"`
+
+	ValidateSSHD bool `mapstructure:"validate_sshd"`
+
+	Profile      string `mapstructure:"profile"`
+	ProfilesFile string `mapstructure:"profiles_file"`
+
+	ConsoleFile string `mapstructure:"console_file"`
+
+	StartAfterCreate *bool `mapstructure:"start_after_create"`
+
+	HCPLabels []string `mapstructure:"hcp_labels"`
+
+	NoSSHKeys bool `mapstructure:"no_ssh_keys"`
+
+	bootcommand.BootConfig `mapstructure:",squash"`
+	BootKeyInterval        time.Duration `mapstructure:"boot_key_interval"`
+
+	DatacenterFallback bool `mapstructure:"datacenter_fallback"`
+
+	StatusCheck string `mapstructure:"status_check"`
+	StatusURL   string `mapstructure:"status_url"`
+
+	LabelsDiffSelector string `mapstructure:"labels_diff_selector"`
+
+	TaintFile string `mapstructure:"taint_file"`
+
+	LiveSnapshot      bool     `mapstructure:"live_snapshot"`
+	QuiesceCommands   []string `mapstructure:"quiesce_commands"`
+	UnquiesceCommands []string `mapstructure:"unquiesce_commands"`
+
+	UpgradeServerTypeTiming string `mapstructure:"upgrade_server_type_timing"`
+	UpgradeDisk             bool   `mapstructure:"upgrade_disk"`
 
-	RescueMode string `mapstructure:"rescue"`
+	VerifyBoot        []verifyBootTarget `mapstructure:"verify_boot"`
+	VerifyBootTimeout time.Duration      `mapstructure:"verify_boot_timeout"`
 
-	KeepServer bool     `mapstructure:"keep_server"`
-	SkipSnapshot bool     `mapstructure:"skip_snapshot"`
+	TemporaryNetwork *temporaryNetwork `mapstructure:"temporary_network"`
 
-	ctx interpolate.Context
+	ScratchVolume *scratchVolume `mapstructure:"scratch_volume"`
+
+	PlacementGroup          string `mapstructure:"placement_group"`
+	PlacementGroupSelector  string `mapstructure:"placement_group_selector"`
+	TemporaryPlacementGroup bool   `mapstructure:"temporary_placement_group"`
+
+	RescueMode                  string   `mapstructure:"rescue"`
+	RescueCommands              []string `mapstructure:"rescue_commands"`
+	ProvisionInRescue           bool     `mapstructure:"provision_in_rescue"`
+	PostProvisionRescueCommands []string `mapstructure:"post_provision_rescue_commands"`
+	LeaveRescueEnabled          bool     `mapstructure:"leave_rescue_enabled"`
+	ShrinkDiskToGB              int      `mapstructure:"shrink_disk_to_gb"`
+
+	VirtIOISO bool `mapstructure:"virtio_iso"`
+
+	SkipCatalogValidation bool `mapstructure:"skip_catalog_validation"`
+
+	ProtectedLabelSelector string `mapstructure:"protected_label_selector"`
+
+	KeepServer         bool `mapstructure:"keep_server"`
+	SkipSnapshot       bool `mapstructure:"skip_snapshot"`
+	ProtectBuildServer bool `mapstructure:"protect_build_server"`
+	EnableBackups      bool `mapstructure:"enable_backups"`
+	ProtectServer      bool `mapstructure:"protect_server"`
+
+	KeptServerName   string            `mapstructure:"kept_server_name"`
+	KeptServerLabels map[string]string `mapstructure:"kept_server_labels"`
+	KeptServerState  string            `mapstructure:"kept_server_state"`
+
+	StepRetries    int           `mapstructure:"step_retries"`
+	StepRetryDelay time.Duration `mapstructure:"step_retry_delay"`
+
+	CostEstimate           bool          `mapstructure:"cost_estimate"`
+	DryRun                 bool          `mapstructure:"dry_run"`
+	EstimatedBuildDuration time.Duration `mapstructure:"estimated_build_duration"`
+	MaxHourlyPrice         float64       `mapstructure:"max_hourly_price"`
+
+	WriteImageInfo bool   `mapstructure:"write_image_info"`
+	ImageInfoPath  string `mapstructure:"image_info_path"`
+
+	CollectMetrics bool   `mapstructure:"collect_metrics"`
+	MetricsFile    string `mapstructure:"metrics_file"`
+
+	SupportBundleDir string `mapstructure:"support_bundle_dir"`
+
+	Policy     *policy `mapstructure:"policy"`
+	PolicyFile string  `mapstructure:"policy_file"`
+
+	ArtifactIDFormat string `mapstructure:"artifact_id_format"`
+
+	ctx      interpolate.Context
+	buildID  string
+	policies []*policy
 }
 
 type imageFilter struct {
 	WithSelector []string `mapstructure:"with_selector"`
 	MostRecent   bool     `mapstructure:"most_recent"`
+	OnMultiple   string   `mapstructure:"on_multiple"`
+}
+
+const (
+	onMultipleFail   = "fail"
+	onMultipleNewest = "newest"
+	onMultipleOldest = "oldest"
+)
+
+type temporaryNetwork struct {
+	IPRange string `mapstructure:"ip_range"`
+	Subnet  string `mapstructure:"subnet"`
+	Bastion string `mapstructure:"bastion"`
+
+	NetworkZone string `mapstructure:"network_zone"`
+}
+
+// networkAttachment attaches the server to a network with a static IP, once
+// it was created.
+type networkAttachment struct {
+	ID       int64    `mapstructure:"id"`
+	Name     string   `mapstructure:"name"`
+	IP       string   `mapstructure:"ip"`
+	AliasIPs []string `mapstructure:"alias_ips"`
+}
+
+// verifyBootTarget is a server type and location the snapshot is booted on,
+// to verify it is portable.
+type verifyBootTarget struct {
+	ServerType string `mapstructure:"server_type"`
+	Location   string `mapstructure:"location"`
+}
+
+// The formats of the artifact ID.
+const (
+	artifactIDFormatID         = "id"
+	artifactIDFormatStructured = "structured"
+)
+
+// The optional resources which may be missing, see ignore_missing.
+const (
+	ignoreMissingFirewalls = "firewalls"
+	ignoreMissingVolumes   = "volumes"
+)
+
+// scratchVolumeMinSize is the minimum size of a volume, in GB.
+const scratchVolumeMinSize = 10
+
+// minRSAKeyBits is the minimum size of the RSA keys accepted by current images.
+const minRSAKeyBits = 2048
+
+type scratchVolume struct {
+	Size       int    `mapstructure:"size"`
+	Filesystem string `mapstructure:"filesystem"`
+	MountPath  string `mapstructure:"mount_path"`
+	Automount  bool   `mapstructure:"automount"`
 }
 
 func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
+	// Allows short unique names, e.g. for the server_name of parallel builds
+	c.ctx.Funcs = map[string]interface{}{
+		"short_uuid": shortUUID,
+	}
+
 	var md mapstructure.Metadata
 	err := config.Decode(c, &config.DecodeOpts{
 		Metadata:           &md,
@@ -77,6 +332,7 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		InterpolateFilter: &interpolate.RenderFilter{
 			Exclude: []string{
 				"run_command",
+				"boot_command",
 			},
 		},
 	}, raws...)
@@ -84,6 +340,28 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		return nil, err
 	}
 
+	// The nested blocks are alternatives to the flat options
+	blockErrs := c.mergeBlocks()
+
+	// The profile provides the environment specific options not set in the
+	// template
+	if c.Profile == "" {
+		c.Profile = os.Getenv("HCLOUD_PROFILE")
+	}
+	if c.ProfilesFile == "" {
+		c.ProfilesFile = os.Getenv("HCLOUD_PROFILES_FILE")
+	}
+	if c.ProfilesFile == "" {
+		c.ProfilesFile = defaultProfilesFile()
+	}
+	var profileErr error
+	if c.Profile != "" {
+		var p *profile
+		if p, profileErr = loadProfile(c.ProfilesFile, c.Profile); profileErr == nil {
+			c.applyProfile(p)
+		}
+	}
+
 	// Defaults
 	if c.HCloudToken == "" {
 		c.HCloudToken = os.Getenv("HCLOUD_TOKEN")
@@ -98,22 +376,110 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 	if c.PollInterval == 0 {
 		c.PollInterval = 500 * time.Millisecond
 	}
+	if c.EOLWarningPeriod == 0 {
+		c.EOLWarningPeriod = 90 * 24 * time.Hour
+	}
 
+	var snapshotNameErr error
 	if c.SnapshotName == "" {
-		def, err := interpolate.Render("packer-{{timestamp}}", nil)
-		if err != nil {
-			panic(err)
-		}
-		// Default to packer-{{ unix timestamp (utc) }}
-		c.SnapshotName = def
+		// Default to packer-{{ unix timestamp (utc) }}, or the template of the
+		// environment
+		c.SnapshotName, snapshotNameErr = c.defaultSnapshotName()
 	}
 
+	if c.StepRetryDelay == 0 {
+		c.StepRetryDelay = 5 * time.Second
+	}
+
+	if c.PolicyFile == "" {
+		c.PolicyFile = os.Getenv("HCLOUD_POLICY_FILE")
+	}
+
+	if c.TemporaryFirewallIPURL == "" {
+		c.TemporaryFirewallIPURL = defaultEgressIPURL
+	}
+
+	if len(c.VerifyBoot) > 0 && c.VerifyBootTimeout == 0 {
+		c.VerifyBootTimeout = 5 * time.Minute
+	}
+	if c.BootstrapTimeout == 0 {
+		c.BootstrapTimeout = 5 * time.Minute
+	}
+
+	if c.EstimatedBuildDuration == 0 {
+		c.EstimatedBuildDuration = time.Hour
+	}
+
+	if c.ImageInfoPath != "" {
+		c.WriteImageInfo = true
+	} else if c.WriteImageInfo {
+		c.ImageInfoPath = "/etc/packer-image-info.json"
+	}
+
+	if c.MetricsFile != "" {
+		c.CollectMetrics = true
+	}
+
+	c.buildID = uuid.TimeOrderedUUID()
+
 	if c.ServerName == "" {
-		// Default to packer-[time-ordered-uuid]
-		c.ServerName = fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+		// Default to packer-[time-ordered-uuid], or the naming convention
+		c.ServerName = c.resourceName()
+	}
+	if c.InheritServerLabels {
+		c.SnapshotLabels = inheritLabels(c.SnapshotLabels, c.ServerLabels, c.InheritServerLabelsKeys)
+	}
+	c.ServerLabels = withLabel(c.ServerLabels, buildIDLabel, c.buildID)
+	c.SnapshotLabels = withLabel(c.SnapshotLabels, buildIDLabel, c.buildID)
+	c.SSHKeysLabels = withLabel(c.SSHKeysLabels, buildIDLabel, c.buildID)
+	// The build metadata makes stray servers attributable, the server_labels
+	// take precedence
+	c.ServerLabels = inheritLabels(c.ServerLabels, c.buildMetadataLabels(), nil)
+
+	var warnings []string
+	if c.SnapshotNotes != "" {
+		notes := sanitizeLabelValue(c.SnapshotNotes)
+		if notes != c.SnapshotNotes {
+			warnings = append(warnings, fmt.Sprintf(
+				"snapshot_notes is not a valid label value, it will be stored as '%s'", notes))
+		}
+		c.SnapshotLabels = withLabel(c.SnapshotLabels, snapshotNotesLabel, notes)
 	}
 
 	var errs *packersdk.MultiError
+	if len(blockErrs) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, blockErrs...)
+	}
+	if snapshotNameErr != nil {
+		errs = packersdk.MultiErrorAppend(errs, snapshotNameErr)
+	}
+	if profileErr != nil {
+		errs = packersdk.MultiErrorAppend(errs, profileErr)
+	}
+	// The whole build runs on the rescue system, as root
+	if c.ProvisionInRescue {
+		if c.RescueMode == "" {
+			c.RescueMode = string(hcloud.ServerRescueTypeLinux64)
+		}
+		if c.Comm.SSHUsername == "" {
+			c.Comm.SSHUsername = rescueUsername
+		}
+	}
+	// The temporary key must be accepted by the API and by the sshd of the
+	// images, so only RSA and Ed25519 keys are supported
+	switch c.Comm.SSHTemporaryKeyPairType {
+	case "":
+		c.Comm.SSHTemporaryKeyPairType = "ed25519"
+	case "ed25519":
+	case "rsa":
+		if c.Comm.SSHTemporaryKeyPairBits != 0 && c.Comm.SSHTemporaryKeyPairBits < minRSAKeyBits {
+			errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+				"temporary_key_pair_bits must be at least %d for rsa keys, smaller keys are rejected by current images", minRSAKeyBits))
+		}
+	default:
+		errs = packersdk.MultiErrorAppend(errs, fmt.Errorf(
+			"temporary_key_pair_type must be ed25519 or rsa, got '%s'", c.Comm.SSHTemporaryKeyPairType))
+	}
 	if es := c.Comm.Prepare(&c.ctx); len(es) > 0 {
 		errs = packersdk.MultiErrorAppend(errs, es...)
 	}
@@ -123,14 +489,118 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errs, errors.New("token is missing, make sure to configure your Hetzner Cloud token"))
 	}
 
-	if c.Location == "" {
+	switch {
+	case c.Location == "" && c.Datacenter == "":
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("location or datacenter is required"))
+	case c.Location != "" && c.Datacenter != "":
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of location or datacenter can be specified"))
+	}
+	for i, target := range c.VerifyBoot {
+		if target.ServerType == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("verify_boot[%d].server_type is required", i))
+		}
+	}
+
+	switch c.UpgradeServerTypeTiming {
+	case "", upgradeBeforeProvisioning, upgradeAfterProvisioning:
+	default:
 		errs = packersdk.MultiErrorAppend(
-			errs, errors.New("location is required"))
+			errs, fmt.Errorf("upgrade_server_type_timing must be one of %s or %s", upgradeBeforeProvisioning, upgradeAfterProvisioning))
+	}
+	if c.UpgradeServerType == "" && (c.UpgradeServerTypeTiming != "" || c.UpgradeDisk) {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("upgrade_server_type_timing and upgrade_disk require upgrade_server_type"))
 	}
 
-	if c.ServerType == "" {
+	switch c.StatusCheck {
+	case "":
+	case statusCheckWarn, statusCheckFail:
+		if c.StatusURL == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("status_check requires status_url"))
+		}
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("status_check must be one of %s or %s", statusCheckWarn, statusCheckFail))
+	}
+
+	if c.DatacenterFallback && c.Datacenter == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("datacenter_fallback requires datacenter"))
+	}
+
+	serverTypeOptions := 0
+	for _, set := range []bool{c.ServerType != "", c.ServerTypeClass != "", c.ServerTypeSpec != nil} {
+		if set {
+			serverTypeOptions++
+		}
+	}
+	switch {
+	case serverTypeOptions == 0:
 		errs = packersdk.MultiErrorAppend(
 			errs, errors.New("server type is required"))
+	case serverTypeOptions > 1:
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of server_type, server_type_class or server_type_spec can be specified"))
+	}
+	switch hcloud.CPUType(c.ServerTypeClass) {
+	case "":
+		if c.ServerTypeMinCores != 0 || c.ServerTypeMinMemory != 0 {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("server_type_min_cores and server_type_min_memory require server_type_class"))
+		}
+	case hcloud.CPUTypeShared, hcloud.CPUTypeDedicated:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("server_type_class must be one of %s or %s", hcloud.CPUTypeShared, hcloud.CPUTypeDedicated))
+	}
+	if spec := c.ServerTypeSpec; spec != nil {
+		switch hcloud.CPUType(spec.CPUType) {
+		case "", hcloud.CPUTypeShared, hcloud.CPUTypeDedicated:
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("server_type_spec.cpu_type must be one of %s or %s", hcloud.CPUTypeShared, hcloud.CPUTypeDedicated))
+		}
+		switch hcloud.Architecture(spec.Architecture) {
+		case "", hcloud.ArchitectureX86, hcloud.ArchitectureARM:
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("server_type_spec.architecture must be one of %s or %s", hcloud.ArchitectureX86, hcloud.ArchitectureARM))
+		}
+		if spec.Architecture != "" && c.Architecture != "" && spec.Architecture != c.Architecture {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("server_type_spec.architecture and architecture must match"))
+		}
+		if spec.MinCores < 0 || spec.MinMemory < 0 || spec.MinDisk < 0 {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("server_type_spec minimums must not be negative"))
+		}
+	}
+	// server_type_class is a shorthand for a server_type_spec
+	if c.ServerTypeClass != "" && c.ServerTypeSpec == nil {
+		c.ServerTypeSpec = &serverTypeSpec{
+			MinCores:  c.ServerTypeMinCores,
+			MinMemory: c.ServerTypeMinMemory,
+			CPUType:   c.ServerTypeClass,
+		}
+	}
+	if c.ServerTypeSpec != nil && c.ServerTypeSpec.Architecture == "" {
+		c.ServerTypeSpec.Architecture = c.Architecture
+	}
+
+	if !c.SkipCatalogValidation {
+		if err := validateCatalogValue("location", c.Location, catalogLocations); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+		if err := validateCatalogValue("server_type", c.ServerType, catalogServerTypes); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
+		if err := validateCatalogValue("upgrade_server_type", c.UpgradeServerType, catalogServerTypes); err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		}
 	}
 
 	if c.Image == "" && c.ImageFilter == nil {
@@ -145,6 +615,390 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 			errs = packersdk.MultiErrorAppend(
 				errs, errors.New("only one of image or image_filter can be specified"))
 		}
+
+		switch c.ImageFilter.OnMultiple {
+		case "":
+			c.ImageFilter.OnMultiple = onMultipleFail
+			if c.ImageFilter.MostRecent {
+				c.ImageFilter.OnMultiple = onMultipleNewest
+			}
+		case onMultipleFail, onMultipleNewest, onMultipleOldest:
+			if c.ImageFilter.MostRecent && c.ImageFilter.OnMultiple != onMultipleNewest {
+				errs = packersdk.MultiErrorAppend(
+					errs, errors.New("image_filter.most_recent conflicts with image_filter.on_multiple"))
+			}
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("image_filter.on_multiple must be one of %s, %s or %s", onMultipleFail, onMultipleNewest, onMultipleOldest))
+		}
+	}
+
+	if c.TemporaryNetwork != nil {
+		if c.TemporaryNetwork.IPRange == "" {
+			c.TemporaryNetwork.IPRange = "10.0.0.0/16"
+		}
+		if c.TemporaryNetwork.Subnet == "" {
+			c.TemporaryNetwork.Subnet = c.TemporaryNetwork.IPRange
+		}
+
+		ipRange, err := netip.ParsePrefix(c.TemporaryNetwork.IPRange)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("temporary_network.ip_range is invalid: %w", err))
+		}
+		subnet, err := netip.ParsePrefix(c.TemporaryNetwork.Subnet)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("temporary_network.subnet is invalid: %w", err))
+		}
+		if ipRange.IsValid() && subnet.IsValid() &&
+			(subnet.Bits() < ipRange.Bits() || !ipRange.Contains(subnet.Addr())) {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("temporary_network.subnet must be part of temporary_network.ip_range"))
+		}
+	}
+
+	for volume, mountPath := range c.VolumeMountPaths {
+		if !path.IsAbs(mountPath) {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("volume_mount_paths of '%s' must be an absolute path", volume))
+		}
+	}
+
+	if c.ScratchVolume != nil {
+		if c.ScratchVolume.Size < scratchVolumeMinSize {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("scratch_volume.size must be at least %d", scratchVolumeMinSize))
+		}
+		switch c.ScratchVolume.Filesystem {
+		case "", hcloud.VolumeFormatExt4, hcloud.VolumeFormatXFS:
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("scratch_volume.filesystem must be one of %s or %s", hcloud.VolumeFormatExt4, hcloud.VolumeFormatXFS))
+		}
+		if c.ScratchVolume.MountPath != "" {
+			if !path.IsAbs(c.ScratchVolume.MountPath) {
+				errs = packersdk.MultiErrorAppend(
+					errs, errors.New("scratch_volume.mount_path must be an absolute path"))
+			}
+			if c.ScratchVolume.Filesystem == "" {
+				errs = packersdk.MultiErrorAppend(
+					errs, errors.New("scratch_volume.mount_path requires scratch_volume.filesystem"))
+			}
+		}
+		if c.ScratchVolume.Automount && c.ScratchVolume.Filesystem == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("scratch_volume.automount requires scratch_volume.filesystem"))
+		}
+	}
+
+	placementGroups := 0
+	for _, set := range []bool{c.PlacementGroup != "", c.PlacementGroupSelector != "", c.TemporaryPlacementGroup} {
+		if set {
+			placementGroups++
+		}
+	}
+	if placementGroups > 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("only one of placement_group, placement_group_selector or temporary_placement_group can be specified"))
+	}
+
+	for i, attachment := range c.NetworkAttachments {
+		if (attachment.ID == 0) == (attachment.Name == "") {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("network[%d]: exactly one of id or name must be specified", i))
+		}
+		for _, ip := range append([]string{attachment.IP}, attachment.AliasIPs...) {
+			if ip == "" {
+				continue
+			}
+			if _, err := netip.ParseAddr(ip); err != nil {
+				errs = packersdk.MultiErrorAppend(
+					errs, fmt.Errorf("network[%d]: invalid ip '%s': %w", i, ip, err))
+			}
+		}
+	}
+
+	for _, labels := range []struct {
+		option string
+		labels map[string]string
+	}{
+		{"server_labels", c.ServerLabels},
+		{"snapshot_labels", c.SnapshotLabels},
+		{"ssh_keys_labels", c.SSHKeysLabels},
+		{"primary_ip_labels", c.PrimaryIPLabels},
+		{"kept_server_labels", c.KeptServerLabels},
+	} {
+		errs = packersdk.MultiErrorAppend(errs, validateLabels(labels.option, labels.labels)...)
+	}
+
+	if len(c.InheritServerLabelsKeys) > 0 && !c.InheritServerLabels {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("inherit_server_labels_keys requires inherit_server_labels"))
+	}
+
+	for _, firewall := range c.Firewalls {
+		if firewall == "" || firewall == firewallSelectorPrefix {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("firewalls cannot contain an empty firewall or label selector"))
+		}
+	}
+
+	if c.TaintFile != "" {
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("taint_file requires the ssh communicator"))
+		}
+		if !path.IsAbs(c.TaintFile) || strings.ContainsAny(c.TaintFile, "'\" \t") {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("taint_file must be an absolute path without quotes or spaces"))
+		}
+	}
+
+	if len(c.QuiesceCommands) > 0 || len(c.UnquiesceCommands) > 0 {
+		if !c.LiveSnapshot {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("quiesce_commands and unquiesce_commands require live_snapshot"))
+		}
+		if c.Comm.Type == "none" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("quiesce_commands and unquiesce_commands require a communicator"))
+		}
+	}
+	if c.LiveSnapshot && c.UpgradeServerType != "" && !c.upgradesBeforeProvisioning() {
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("live_snapshot cannot be used with upgrade_server_type_timing %s, which requires the server to be shut down", upgradeAfterProvisioning))
+	}
+	if c.LiveSnapshot {
+		// The rescue system replaces the OS the quiesce_commands run on
+		for _, conflict := range []struct {
+			option string
+			set    bool
+		}{
+			{"post_provision_rescue_commands", len(c.PostProvisionRescueCommands) > 0},
+			{"shrink_disk_to_gb", c.ShrinkDiskToGB > 0},
+		} {
+			if conflict.set {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("live_snapshot cannot be used with %s, which reboots the server into the rescue system", conflict.option))
+			}
+		}
+	}
+
+	if c.ValidateSSHD && c.Comm.Type != "ssh" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("validate_sshd requires the ssh communicator"))
+	}
+
+	if slices.Contains(c.FloatingIPs, "") {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("floating_ips cannot contain an empty floating ip or label selector"))
+	}
+
+	switch c.ArtifactIDFormat {
+	case "", artifactIDFormatID, artifactIDFormatStructured:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("artifact_id_format must be one of %s or %s", artifactIDFormatID, artifactIDFormatStructured))
+	}
+
+	for _, kind := range c.IgnoreMissing {
+		switch kind {
+		case ignoreMissingFirewalls, ignoreMissingVolumes:
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("ignore_missing can only contain %s or %s, got '%s'", ignoreMissingFirewalls, ignoreMissingVolumes, kind))
+		}
+	}
+
+	if len(c.BootCommand) > 0 {
+		errs = packersdk.MultiErrorAppend(errs, c.BootConfig.Prepare(&c.ctx)...)
+	}
+
+	if c.NoSSHKeys {
+		if c.Comm.Type == "ssh" && c.Comm.SSHPrivateKeyFile == "" && c.Comm.SSHPassword == "" && !c.Comm.SSHAgentAuth {
+			errs = packersdk.MultiErrorAppend(errs, errors.New(
+				"no_ssh_keys requires ssh_private_key_file, ssh_password or ssh_agent_auth for the user created by the user data"))
+		}
+		for _, conflict := range []struct {
+			option string
+			set    bool
+		}{
+			{"ssh_keys", len(c.SSHKeys) > 0},
+			{"share_temporary_key", c.ShareTemporaryKey},
+			{"ssh_initial_username", c.SSHInitialUsername != ""},
+			{"rescue", c.RescueMode != ""},
+			{"post_provision_rescue_commands", len(c.PostProvisionRescueCommands) > 0},
+			{"shrink_disk_to_gb", c.ShrinkDiskToGB > 0},
+		} {
+			if conflict.set {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("no_ssh_keys cannot be used with %s", conflict.option))
+			}
+		}
+	}
+
+	if c.SSHInitialUsername != "" {
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("ssh_initial_username can only be used with the ssh communicator"))
+		}
+		if c.Comm.SSHPrivateKeyFile != "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("ssh_initial_username requires a temporary SSH key, ssh_private_key_file cannot be used"))
+		}
+		if !validUsername.MatchString(c.SSHInitialUsername) {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("ssh_initial_username '%s' is not a valid user name", c.SSHInitialUsername))
+		}
+		if !validUsername.MatchString(c.Comm.SSHUsername) {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("ssh_username '%s' is not a valid user name", c.Comm.SSHUsername))
+		}
+	}
+
+	for _, server := range c.DNSServers {
+		if _, err := netip.ParseAddr(server); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("dns_servers contains an invalid IP address: %s", server))
+		}
+	}
+
+	if c.ShrinkDiskToGB < 0 || c.ShrinkDiskToGB == 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("shrink_disk_to_gb must be at least 2"))
+	}
+
+	if len(c.RescueCommands) > 0 && c.RescueMode == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("rescue_commands requires rescue"))
+	}
+
+	if c.ProvisionInRescue {
+		if c.Comm.Type != "ssh" {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("provision_in_rescue requires the ssh communicator"))
+		}
+		if c.Comm.SSHUsername != rescueUsername {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("provision_in_rescue requires ssh_username '%s', the user of the rescue system", rescueUsername))
+		}
+		// These options act on the installed OS, which is not running
+		for _, conflict := range []struct {
+			option string
+			set    bool
+		}{
+			{"rescue_commands", len(c.RescueCommands) > 0},
+			{"post_provision_rescue_commands", len(c.PostProvisionRescueCommands) > 0},
+			{"shrink_disk_to_gb", c.ShrinkDiskToGB > 0},
+			{"ssh_initial_username", c.SSHInitialUsername != ""},
+			{"bootstrap_script", c.BootstrapScript != ""},
+			{"validate_sshd", c.ValidateSSHD},
+			{"write_image_info", c.WriteImageInfo},
+			{"remove_foreign_authorized_keys", c.RemoveForeignAuthorizedKeys},
+		} {
+			if conflict.set {
+				errs = packersdk.MultiErrorAppend(errs, fmt.Errorf("provision_in_rescue cannot be used with %s", conflict.option))
+			}
+		}
+	}
+
+	if c.API != nil {
+		for name, endpoint := range map[string]string{
+			"api.read_endpoint":  c.API.ReadEndpoint,
+			"api.write_endpoint": c.API.WriteEndpoint,
+		} {
+			if endpoint == "" {
+				continue
+			}
+			if u, err := url.Parse(endpoint); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = packersdk.MultiErrorAppend(
+					errs, fmt.Errorf("%s must be an absolute URL: %s", name, endpoint))
+			}
+		}
+	}
+
+	if c.APIPageSize < 0 || c.APIPageSize > 50 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("api_page_size must be between 1 and 50"))
+	}
+	if c.APIListLimit < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("api_list_limit must not be negative"))
+	}
+
+	switch hcloud.Architecture(c.Architecture) {
+	case "", hcloud.ArchitectureX86, hcloud.ArchitectureARM:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("architecture must be one of %s or %s", hcloud.ArchitectureX86, hcloud.ArchitectureARM))
+	}
+
+	if c.ImageInfoPath != "" && !path.IsAbs(c.ImageInfoPath) {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("image_info_path must be an absolute path"))
+	}
+
+	if c.Naming != nil {
+		if c.Naming.Prefix != "" && !validNamingPrefix.MatchString(c.Naming.Prefix) {
+			errs = packersdk.MultiErrorAppend(
+				errs, errors.New("naming.prefix must contain at most 30 letters, digits and hyphens, and start with a letter or digit"))
+		}
+		switch c.Naming.Style {
+		case "", namingStyleUUID, namingStyleTimestamp, namingStyleSequence:
+		default:
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("naming.style must be one of %s, %s or %s", namingStyleUUID, namingStyleTimestamp, namingStyleSequence))
+		}
+	}
+
+	switch c.ServerNameConflict {
+	case "", serverNameConflictError, serverNameConflictSuffix:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("server_name_conflict must be one of %s or %s", serverNameConflictError, serverNameConflictSuffix))
+	}
+
+	if c.StepRetries < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("step_retries must not be negative"))
+	}
+	if c.SnapshotRetries < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("snapshot_retries must not be negative"))
+	}
+	if c.MaxHourlyPrice < 0 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("max_hourly_price must not be negative"))
+	}
+
+	if c.HeartbeatInterval != 0 && c.HeartbeatInterval < time.Second {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("heartbeat_interval must be at least 1s"))
+	}
+
+	if len(c.DevModeWatch) > 0 && !c.DevMode {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("dev_mode_watch can only be used with dev_mode"))
+	}
+
+	if c.EnableBackups && !c.KeepServer {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("enable_backups can only be used with keep_server"))
+	}
+
+	if c.ProtectServer && !c.KeepServer {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("protect_server can only be used with keep_server"))
+	}
+
+	if (c.KeptServerName != "" || c.KeptServerLabels != nil || c.KeptServerState != "") && !c.KeepServer {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("kept_server_name, kept_server_labels and kept_server_state can only be used with keep_server"))
+	}
+	switch c.KeptServerState {
+	case "", keptServerStateOff, keptServerStateRunning:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("kept_server_state must be one of %s or %s", keptServerStateOff, keptServerStateRunning))
 	}
 
 	if c.UserData != "" && c.UserDataFile != "" {
@@ -157,14 +1011,124 @@ func (c *Config) Prepare(raws ...interface{}) ([]string, error) {
 		}
 	}
 
+	c.policies = nil
+	if c.Policy != nil {
+		c.Policy.source = "block"
+		c.policies = append(c.policies, c.Policy)
+	}
+	if c.PolicyFile != "" {
+		p, err := loadPolicyFile(c.PolicyFile)
+		if err != nil {
+			errs = packersdk.MultiErrorAppend(errs, err)
+		} else {
+			c.policies = append(c.policies, p)
+		}
+	}
+	for _, p := range c.policies {
+		if es := p.check(c); len(es) > 0 {
+			errs = packersdk.MultiErrorAppend(errs, es...)
+		}
+	}
+
+	if c.BootstrapScript != "" {
+		if _, err := os.Stat(c.BootstrapScript); err != nil {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("bootstrap_script not found: %s", c.BootstrapScript))
+		}
+	}
+
+	switch c.ConnectivityCheckIPFamily {
+	case "", ipFamilyIPv4, ipFamilyIPv6:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("connectivity_check_ip_family must be one of %s or %s", ipFamilyIPv4, ipFamilyIPv6))
+	}
+	if c.ConnectivityCheckURL != "" {
+		if u, err := url.Parse(c.ConnectivityCheckURL); err != nil || u.Scheme == "" || u.Host == "" || strings.Contains(c.ConnectivityCheckURL, "'") {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("connectivity_check_url must be an absolute URL: %s", c.ConnectivityCheckURL))
+		}
+	}
+
+	for name, mirror := range map[string]string{
+		"apt_mirror":          c.APTMirror,
+		"apt_security_mirror": c.APTSecurityMirror,
+	} {
+		if mirror == "" {
+			continue
+		}
+		if u, err := url.Parse(mirror); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = packersdk.MultiErrorAppend(
+				errs, fmt.Errorf("%s must be an absolute URL: %s", name, mirror))
+		}
+	}
+
 	if errs != nil && len(errs.Errors) > 0 {
-		return nil, errs
+		return warnings, errs
 	}
 
 	packersdk.LogSecretFilter.Set(c.HCloudToken)
-	return nil, nil
+	if c.API != nil {
+		packersdk.LogSecretFilter.Set(c.API.ReadToken, c.API.WriteToken)
+	}
+	return warnings, nil
 }
 
 func getServerIP(state multistep.StateBag) (string, error) {
 	return state.Get(StateServerIP).(string), nil
 }
+
+// startsAfterCreate reports whether the server is started by stepCreateServer,
+// see start_after_create.
+func (c *Config) startsAfterCreate() bool {
+	return c.StartAfterCreate == nil || *c.StartAfterCreate
+}
+
+// ignoresMissing reports whether the missing resources of the kind are only
+// warned about.
+func (c *Config) ignoresMissing(kind string) bool {
+	return slices.Contains(c.IgnoreMissing, kind)
+}
+
+// defaultSnapshotName renders the default snapshot name, from the template of
+// the HCLOUD_SNAPSHOT_NAME_TEMPLATE environment variable if set.
+func (c *Config) defaultSnapshotName() (string, error) {
+	tpl := os.Getenv("HCLOUD_SNAPSHOT_NAME_TEMPLATE")
+	if tpl == "" {
+		tpl = defaultSnapshotNameTemplate
+	}
+	name, err := interpolate.Render(tpl, &c.ctx)
+	if err != nil {
+		return "", fmt.Errorf("could not render the default snapshot name '%s': %w", tpl, err)
+	}
+	if name == "" {
+		return "", fmt.Errorf("the default snapshot name '%s' renders to an empty name", tpl)
+	}
+	return name, nil
+}
+
+// upgradesBeforeProvisioning reports whether the server type is upgraded when
+// the server is created, before the provisioning.
+func (c *Config) upgradesBeforeProvisioning() bool {
+	return c.UpgradeServerType != "" && c.UpgradeServerTypeTiming != upgradeAfterProvisioning
+}
+
+// buildMetadataLabels returns the labels describing the build, applied to the
+// build server.
+func (c *Config) buildMetadataLabels() map[string]string {
+	labels := map[string]string{
+		pluginVersionLabel: sanitizeLabelValue(version.PluginVersion.String()),
+	}
+	if c.PackerCoreVersion != "" {
+		labels[packerVersionLabel] = sanitizeLabelValue(c.PackerCoreVersion)
+	}
+	if c.PackerBuildName != "" {
+		labels[buildNameLabel] = sanitizeLabelValue(c.PackerBuildName)
+	}
+	return labels
+}
+
+// buildLabels returns the labels applied to the temporary resources of the build.
+func (c *Config) buildLabels() map[string]string {
+	return map[string]string{buildIDLabel: c.buildID}
+}
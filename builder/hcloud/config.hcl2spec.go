@@ -10,89 +10,192 @@ import (
 // FlatConfig is an auto-generated flat version of Config.
 // Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
 type FlatConfig struct {
-	PackerBuildName           *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
-	PackerBuilderType         *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
-	PackerCoreVersion         *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
-	PackerDebug               *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
-	PackerForce               *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
-	PackerOnError             *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
-	PackerUserVars            map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
-	PackerSensitiveVars       []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
-	Type                      *string           `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
-	PauseBeforeConnect        *string           `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
-	SSHHost                   *string           `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
-	SSHPort                   *int              `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
-	SSHUsername               *string           `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
-	SSHPassword               *string           `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
-	SSHKeyPairName            *string           `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
-	SSHTemporaryKeyPairName   *string           `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
-	SSHTemporaryKeyPairType   *string           `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
-	SSHTemporaryKeyPairBits   *int              `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
-	SSHCiphers                []string          `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
-	SSHClearAuthorizedKeys    *bool             `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
-	SSHKEXAlgos               []string          `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
-	SSHPrivateKeyFile         *string           `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
-	SSHCertificateFile        *string           `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
-	SSHPty                    *bool             `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
-	SSHTimeout                *string           `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
-	SSHWaitTimeout            *string           `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
-	SSHAgentAuth              *bool             `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
-	SSHDisableAgentForwarding *bool             `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
-	SSHHandshakeAttempts      *int              `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
-	SSHBastionHost            *string           `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
-	SSHBastionPort            *int              `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
-	SSHBastionAgentAuth       *bool             `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
-	SSHBastionUsername        *string           `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
-	SSHBastionPassword        *string           `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
-	SSHBastionInteractive     *bool             `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
-	SSHBastionPrivateKeyFile  *string           `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
-	SSHBastionCertificateFile *string           `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
-	SSHFileTransferMethod     *string           `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
-	SSHProxyHost              *string           `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
-	SSHProxyPort              *int              `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
-	SSHProxyUsername          *string           `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
-	SSHProxyPassword          *string           `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
-	SSHKeepAliveInterval      *string           `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
-	SSHReadWriteTimeout       *string           `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
-	SSHRemoteTunnels          []string          `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
-	SSHLocalTunnels           []string          `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
-	SSHPublicKey              []byte            `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
-	SSHPrivateKey             []byte            `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
-	WinRMUser                 *string           `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
-	WinRMPassword             *string           `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
-	WinRMHost                 *string           `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
-	WinRMNoProxy              *bool             `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
-	WinRMPort                 *int              `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
-	WinRMTimeout              *string           `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
-	WinRMUseSSL               *bool             `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
-	WinRMInsecure             *bool             `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
-	WinRMUseNTLM              *bool             `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
-	HCloudToken               *string           `mapstructure:"token" cty:"token" hcl:"token"`
-	Endpoint                  *string           `mapstructure:"endpoint" cty:"endpoint" hcl:"endpoint"`
-	PollInterval              *string           `mapstructure:"poll_interval" cty:"poll_interval" hcl:"poll_interval"`
-	ServerName                *string           `mapstructure:"server_name" cty:"server_name" hcl:"server_name"`
-	Location                  *string           `mapstructure:"location" cty:"location" hcl:"location"`
-	ServerType                *string           `mapstructure:"server_type" cty:"server_type" hcl:"server_type"`
-	ServerLabels              map[string]string `mapstructure:"server_labels" cty:"server_labels" hcl:"server_labels"`
-	UpgradeServerType         *string           `mapstructure:"upgrade_server_type" cty:"upgrade_server_type" hcl:"upgrade_server_type"`
-	Image                     *string           `mapstructure:"image" cty:"image" hcl:"image"`
-	ImageFilter               *FlatimageFilter  `mapstructure:"image_filter" cty:"image_filter" hcl:"image_filter"`
-	SnapshotName              *string           `mapstructure:"snapshot_name" cty:"snapshot_name" hcl:"snapshot_name"`
-	SnapshotLabels            map[string]string `mapstructure:"snapshot_labels" cty:"snapshot_labels" hcl:"snapshot_labels"`
-	UserData                  *string           `mapstructure:"user_data" cty:"user_data" hcl:"user_data"`
-	UserDataFile              *string           `mapstructure:"user_data_file" cty:"user_data_file" hcl:"user_data_file"`
-	SSHKeys                   []string          `mapstructure:"ssh_keys" cty:"ssh_keys" hcl:"ssh_keys"`
-	SSHKeysLabels             map[string]string `mapstructure:"ssh_keys_labels" cty:"ssh_keys_labels" hcl:"ssh_keys_labels"`
-	Networks                  []int64           `mapstructure:"networks" cty:"networks" hcl:"networks"`
-	PublicIPv4                *string           `mapstructure:"public_ipv4" cty:"public_ipv4" hcl:"public_ipv4"`
-	PublicIPv4Disabled        *bool             `mapstructure:"public_ipv4_disabled" cty:"public_ipv4_disabled" hcl:"public_ipv4_disabled"`
-	PublicIPv6                *string           `mapstructure:"public_ipv6" cty:"public_ipv6" hcl:"public_ipv6"`
-	PublicIPv6Disabled        *bool             `mapstructure:"public_ipv6_disabled" cty:"public_ipv6_disabled" hcl:"public_ipv6_disabled"`
-	Firewalls                 []string          `mapstructure:"firewalls" cty:"firewalls" hcl:"firewalls"`
-	Volumes                   []string          `mapstructure:"volumes" cty:"volumes" hcl:"volumes"`
-	RescueMode                *string           `mapstructure:"rescue" cty:"rescue" hcl:"rescue"`
-	KeepServer	        *bool             `mapstructure:"keep_server" cty:"keep_server" hcl:"keep_server"`
-	SkipSnapshot 		*bool     	`mapstructure:"skip_snapshot" cty:"skip_snapshot" hcl:"skip_snapshot"`
+	PackerBuildName             *string                 `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType           *string                 `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion           *string                 `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug                 *bool                   `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce                 *bool                   `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError               *string                 `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars              map[string]string       `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars         []string                `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	Type                        *string                 `mapstructure:"communicator" cty:"communicator" hcl:"communicator"`
+	PauseBeforeConnect          *string                 `mapstructure:"pause_before_connecting" cty:"pause_before_connecting" hcl:"pause_before_connecting"`
+	SSHHost                     *string                 `mapstructure:"ssh_host" cty:"ssh_host" hcl:"ssh_host"`
+	SSHPort                     *int                    `mapstructure:"ssh_port" cty:"ssh_port" hcl:"ssh_port"`
+	SSHUsername                 *string                 `mapstructure:"ssh_username" cty:"ssh_username" hcl:"ssh_username"`
+	SSHPassword                 *string                 `mapstructure:"ssh_password" cty:"ssh_password" hcl:"ssh_password"`
+	SSHKeyPairName              *string                 `mapstructure:"ssh_keypair_name" undocumented:"true" cty:"ssh_keypair_name" hcl:"ssh_keypair_name"`
+	SSHTemporaryKeyPairName     *string                 `mapstructure:"temporary_key_pair_name" undocumented:"true" cty:"temporary_key_pair_name" hcl:"temporary_key_pair_name"`
+	SSHTemporaryKeyPairType     *string                 `mapstructure:"temporary_key_pair_type" cty:"temporary_key_pair_type" hcl:"temporary_key_pair_type"`
+	SSHTemporaryKeyPairBits     *int                    `mapstructure:"temporary_key_pair_bits" cty:"temporary_key_pair_bits" hcl:"temporary_key_pair_bits"`
+	SSHCiphers                  []string                `mapstructure:"ssh_ciphers" cty:"ssh_ciphers" hcl:"ssh_ciphers"`
+	SSHClearAuthorizedKeys      *bool                   `mapstructure:"ssh_clear_authorized_keys" cty:"ssh_clear_authorized_keys" hcl:"ssh_clear_authorized_keys"`
+	SSHKEXAlgos                 []string                `mapstructure:"ssh_key_exchange_algorithms" cty:"ssh_key_exchange_algorithms" hcl:"ssh_key_exchange_algorithms"`
+	SSHPrivateKeyFile           *string                 `mapstructure:"ssh_private_key_file" undocumented:"true" cty:"ssh_private_key_file" hcl:"ssh_private_key_file"`
+	SSHCertificateFile          *string                 `mapstructure:"ssh_certificate_file" cty:"ssh_certificate_file" hcl:"ssh_certificate_file"`
+	SSHPty                      *bool                   `mapstructure:"ssh_pty" cty:"ssh_pty" hcl:"ssh_pty"`
+	SSHTimeout                  *string                 `mapstructure:"ssh_timeout" cty:"ssh_timeout" hcl:"ssh_timeout"`
+	SSHWaitTimeout              *string                 `mapstructure:"ssh_wait_timeout" undocumented:"true" cty:"ssh_wait_timeout" hcl:"ssh_wait_timeout"`
+	SSHAgentAuth                *bool                   `mapstructure:"ssh_agent_auth" undocumented:"true" cty:"ssh_agent_auth" hcl:"ssh_agent_auth"`
+	SSHDisableAgentForwarding   *bool                   `mapstructure:"ssh_disable_agent_forwarding" cty:"ssh_disable_agent_forwarding" hcl:"ssh_disable_agent_forwarding"`
+	SSHHandshakeAttempts        *int                    `mapstructure:"ssh_handshake_attempts" cty:"ssh_handshake_attempts" hcl:"ssh_handshake_attempts"`
+	SSHBastionHost              *string                 `mapstructure:"ssh_bastion_host" cty:"ssh_bastion_host" hcl:"ssh_bastion_host"`
+	SSHBastionPort              *int                    `mapstructure:"ssh_bastion_port" cty:"ssh_bastion_port" hcl:"ssh_bastion_port"`
+	SSHBastionAgentAuth         *bool                   `mapstructure:"ssh_bastion_agent_auth" cty:"ssh_bastion_agent_auth" hcl:"ssh_bastion_agent_auth"`
+	SSHBastionUsername          *string                 `mapstructure:"ssh_bastion_username" cty:"ssh_bastion_username" hcl:"ssh_bastion_username"`
+	SSHBastionPassword          *string                 `mapstructure:"ssh_bastion_password" cty:"ssh_bastion_password" hcl:"ssh_bastion_password"`
+	SSHBastionInteractive       *bool                   `mapstructure:"ssh_bastion_interactive" cty:"ssh_bastion_interactive" hcl:"ssh_bastion_interactive"`
+	SSHBastionPrivateKeyFile    *string                 `mapstructure:"ssh_bastion_private_key_file" cty:"ssh_bastion_private_key_file" hcl:"ssh_bastion_private_key_file"`
+	SSHBastionCertificateFile   *string                 `mapstructure:"ssh_bastion_certificate_file" cty:"ssh_bastion_certificate_file" hcl:"ssh_bastion_certificate_file"`
+	SSHFileTransferMethod       *string                 `mapstructure:"ssh_file_transfer_method" cty:"ssh_file_transfer_method" hcl:"ssh_file_transfer_method"`
+	SSHProxyHost                *string                 `mapstructure:"ssh_proxy_host" cty:"ssh_proxy_host" hcl:"ssh_proxy_host"`
+	SSHProxyPort                *int                    `mapstructure:"ssh_proxy_port" cty:"ssh_proxy_port" hcl:"ssh_proxy_port"`
+	SSHProxyUsername            *string                 `mapstructure:"ssh_proxy_username" cty:"ssh_proxy_username" hcl:"ssh_proxy_username"`
+	SSHProxyPassword            *string                 `mapstructure:"ssh_proxy_password" cty:"ssh_proxy_password" hcl:"ssh_proxy_password"`
+	SSHKeepAliveInterval        *string                 `mapstructure:"ssh_keep_alive_interval" cty:"ssh_keep_alive_interval" hcl:"ssh_keep_alive_interval"`
+	SSHReadWriteTimeout         *string                 `mapstructure:"ssh_read_write_timeout" cty:"ssh_read_write_timeout" hcl:"ssh_read_write_timeout"`
+	SSHRemoteTunnels            []string                `mapstructure:"ssh_remote_tunnels" cty:"ssh_remote_tunnels" hcl:"ssh_remote_tunnels"`
+	SSHLocalTunnels             []string                `mapstructure:"ssh_local_tunnels" cty:"ssh_local_tunnels" hcl:"ssh_local_tunnels"`
+	SSHPublicKey                []byte                  `mapstructure:"ssh_public_key" undocumented:"true" cty:"ssh_public_key" hcl:"ssh_public_key"`
+	SSHPrivateKey               []byte                  `mapstructure:"ssh_private_key" undocumented:"true" cty:"ssh_private_key" hcl:"ssh_private_key"`
+	WinRMUser                   *string                 `mapstructure:"winrm_username" cty:"winrm_username" hcl:"winrm_username"`
+	WinRMPassword               *string                 `mapstructure:"winrm_password" cty:"winrm_password" hcl:"winrm_password"`
+	WinRMHost                   *string                 `mapstructure:"winrm_host" cty:"winrm_host" hcl:"winrm_host"`
+	WinRMNoProxy                *bool                   `mapstructure:"winrm_no_proxy" cty:"winrm_no_proxy" hcl:"winrm_no_proxy"`
+	WinRMPort                   *int                    `mapstructure:"winrm_port" cty:"winrm_port" hcl:"winrm_port"`
+	WinRMTimeout                *string                 `mapstructure:"winrm_timeout" cty:"winrm_timeout" hcl:"winrm_timeout"`
+	WinRMUseSSL                 *bool                   `mapstructure:"winrm_use_ssl" cty:"winrm_use_ssl" hcl:"winrm_use_ssl"`
+	WinRMInsecure               *bool                   `mapstructure:"winrm_insecure" cty:"winrm_insecure" hcl:"winrm_insecure"`
+	WinRMUseNTLM                *bool                   `mapstructure:"winrm_use_ntlm" cty:"winrm_use_ntlm" hcl:"winrm_use_ntlm"`
+	HCloudToken                 *string                 `mapstructure:"token" cty:"token" hcl:"token"`
+	Endpoint                    *string                 `mapstructure:"endpoint" cty:"endpoint" hcl:"endpoint"`
+	API                         *FlatapiRoutes          `mapstructure:"api" cty:"api" hcl:"api"`
+	APIPageSize                 *int                    `mapstructure:"api_page_size" cty:"api_page_size" hcl:"api_page_size"`
+	APIListLimit                *int                    `mapstructure:"api_list_limit" cty:"api_list_limit" hcl:"api_list_limit"`
+	PollInterval                *string                 `mapstructure:"poll_interval" cty:"poll_interval" hcl:"poll_interval"`
+	HeartbeatInterval           *string                 `mapstructure:"heartbeat_interval" cty:"heartbeat_interval" hcl:"heartbeat_interval"`
+	PauseAfterServerReady       *string                 `mapstructure:"pause_after_server_ready" cty:"pause_after_server_ready" hcl:"pause_after_server_ready"`
+	PortCheckTimeout            *string                 `mapstructure:"port_check_timeout" cty:"port_check_timeout" hcl:"port_check_timeout"`
+	PauseBeforeSnapshot         *string                 `mapstructure:"pause_before_snapshot" cty:"pause_before_snapshot" hcl:"pause_before_snapshot"`
+	Naming                      *Flatnaming             `mapstructure:"naming" cty:"naming" hcl:"naming"`
+	ServerNameConflict          *string                 `mapstructure:"server_name_conflict" cty:"server_name_conflict" hcl:"server_name_conflict"`
+	Server                      *FlatserverBlock        `mapstructure:"server" cty:"server" hcl:"server"`
+	Snapshot                    *FlatsnapshotBlock      `mapstructure:"snapshot" cty:"snapshot" hcl:"snapshot"`
+	Connection                  *FlatconnectionBlock    `mapstructure:"connection" cty:"connection" hcl:"connection"`
+	ServerName                  *string                 `mapstructure:"server_name" cty:"server_name" hcl:"server_name"`
+	Location                    *string                 `mapstructure:"location" cty:"location" hcl:"location"`
+	Datacenter                  *string                 `mapstructure:"datacenter" cty:"datacenter" hcl:"datacenter"`
+	ServerType                  *string                 `mapstructure:"server_type" cty:"server_type" hcl:"server_type"`
+	ServerLabels                map[string]string       `mapstructure:"server_labels" cty:"server_labels" hcl:"server_labels"`
+	UpgradeServerType           *string                 `mapstructure:"upgrade_server_type" cty:"upgrade_server_type" hcl:"upgrade_server_type"`
+	Architecture                *string                 `mapstructure:"architecture" cty:"architecture" hcl:"architecture"`
+	Image                       *string                 `mapstructure:"image" cty:"image" hcl:"image"`
+	ImageFilter                 *FlatimageFilter        `mapstructure:"image_filter" cty:"image_filter" hcl:"image_filter"`
+	FailOnEOL                   *bool                   `mapstructure:"fail_on_eol" cty:"fail_on_eol" hcl:"fail_on_eol"`
+	EOLWarningPeriod            *string                 `mapstructure:"eol_warning_period" cty:"eol_warning_period" hcl:"eol_warning_period"`
+	ServerTypeClass             *string                 `mapstructure:"server_type_class" cty:"server_type_class" hcl:"server_type_class"`
+	ServerTypeMinCores          *int                    `mapstructure:"server_type_min_cores" cty:"server_type_min_cores" hcl:"server_type_min_cores"`
+	ServerTypeMinMemory         *float32                `mapstructure:"server_type_min_memory" cty:"server_type_min_memory" hcl:"server_type_min_memory"`
+	ServerTypeSpec              *FlatserverTypeSpec     `mapstructure:"server_type_spec" cty:"server_type_spec" hcl:"server_type_spec"`
+	SnapshotName                *string                 `mapstructure:"snapshot_name" cty:"snapshot_name" hcl:"snapshot_name"`
+	SnapshotLabels              map[string]string       `mapstructure:"snapshot_labels" cty:"snapshot_labels" hcl:"snapshot_labels"`
+	SnapshotNotes               *string                 `mapstructure:"snapshot_notes" cty:"snapshot_notes" hcl:"snapshot_notes"`
+	SnapshotRetries             *int                    `mapstructure:"snapshot_retries" cty:"snapshot_retries" hcl:"snapshot_retries"`
+	UserData                    *string                 `mapstructure:"user_data" cty:"user_data" hcl:"user_data"`
+	UserDataFile                *string                 `mapstructure:"user_data_file" cty:"user_data_file" hcl:"user_data_file"`
+	DNSServers                  []string                `mapstructure:"dns_servers" cty:"dns_servers" hcl:"dns_servers"`
+	SSHKeys                     []string                `mapstructure:"ssh_keys" cty:"ssh_keys" hcl:"ssh_keys"`
+	SSHKeysLabels               map[string]string       `mapstructure:"ssh_keys_labels" cty:"ssh_keys_labels" hcl:"ssh_keys_labels"`
+	InheritServerLabels         *bool                   `mapstructure:"inherit_server_labels" cty:"inherit_server_labels" hcl:"inherit_server_labels"`
+	InheritServerLabelsKeys     []string                `mapstructure:"inherit_server_labels_keys" cty:"inherit_server_labels_keys" hcl:"inherit_server_labels_keys"`
+	APTMirror                   *string                 `mapstructure:"apt_mirror" cty:"apt_mirror" hcl:"apt_mirror"`
+	APTSecurityMirror           *string                 `mapstructure:"apt_security_mirror" cty:"apt_security_mirror" hcl:"apt_security_mirror"`
+	BootstrapScript             *string                 `mapstructure:"bootstrap_script" cty:"bootstrap_script" hcl:"bootstrap_script"`
+	BootstrapTimeout            *string                 `mapstructure:"bootstrap_timeout" cty:"bootstrap_timeout" hcl:"bootstrap_timeout"`
+	ConnectivityCheckURL        *string                 `mapstructure:"connectivity_check_url" cty:"connectivity_check_url" hcl:"connectivity_check_url"`
+	ConnectivityCheckIPFamily   *string                 `mapstructure:"connectivity_check_ip_family" cty:"connectivity_check_ip_family" hcl:"connectivity_check_ip_family"`
+	RemoveForeignAuthorizedKeys *bool                   `mapstructure:"remove_foreign_authorized_keys" cty:"remove_foreign_authorized_keys" hcl:"remove_foreign_authorized_keys"`
+	DevMode                     *bool                   `mapstructure:"dev_mode" cty:"dev_mode" hcl:"dev_mode"`
+	DevModeWatch                []string                `mapstructure:"dev_mode_watch" cty:"dev_mode_watch" hcl:"dev_mode_watch"`
+	ShareTemporaryKey           *bool                   `mapstructure:"share_temporary_key" cty:"share_temporary_key" hcl:"share_temporary_key"`
+	SSHInitialUsername          *string                 `mapstructure:"ssh_initial_username" cty:"ssh_initial_username" hcl:"ssh_initial_username"`
+	Networks                    []string                `mapstructure:"networks" cty:"networks" hcl:"networks"`
+	PublicIPv4                  *string                 `mapstructure:"public_ipv4" cty:"public_ipv4" hcl:"public_ipv4"`
+	PublicIPv4Disabled          *bool                   `mapstructure:"public_ipv4_disabled" cty:"public_ipv4_disabled" hcl:"public_ipv4_disabled"`
+	PublicIPv6                  *string                 `mapstructure:"public_ipv6" cty:"public_ipv6" hcl:"public_ipv6"`
+	PublicIPv6Disabled          *bool                   `mapstructure:"public_ipv6_disabled" cty:"public_ipv6_disabled" hcl:"public_ipv6_disabled"`
+	Firewalls                   []string                `mapstructure:"firewalls" cty:"firewalls" hcl:"firewalls"`
+	Volumes                     []string                `mapstructure:"volumes" cty:"volumes" hcl:"volumes"`
+	VolumeSelector              *string                 `mapstructure:"volume_selector" cty:"volume_selector" hcl:"volume_selector"`
+	VolumeAutomount             *bool                   `mapstructure:"volume_automount" cty:"volume_automount" hcl:"volume_automount"`
+	VolumeMountPaths            map[string]string       `mapstructure:"volume_mount_paths" cty:"volume_mount_paths" hcl:"volume_mount_paths"`
+	IgnoreMissing               []string                `mapstructure:"ignore_missing" cty:"ignore_missing" hcl:"ignore_missing"`
+	TemporaryFirewall           *bool                   `mapstructure:"temporary_firewall" cty:"temporary_firewall" hcl:"temporary_firewall"`
+	TemporaryFirewallIPURL      *string                 `mapstructure:"temporary_firewall_ip_url" cty:"temporary_firewall_ip_url" hcl:"temporary_firewall_ip_url"`
+	NetworkAttachments          []FlatnetworkAttachment `mapstructure:"network" cty:"network" hcl:"network"`
+	PrimaryIPAutoDelete         *bool                   `mapstructure:"primary_ip_auto_delete" cty:"primary_ip_auto_delete" hcl:"primary_ip_auto_delete"`
+	PrimaryIPLabels             map[string]string       `mapstructure:"primary_ip_labels" cty:"primary_ip_labels" hcl:"primary_ip_labels"`
+	KeepPrimaryIP               *bool                   `mapstructure:"keep_primary_ip" cty:"keep_primary_ip" hcl:"keep_primary_ip"`
+	FloatingIPs                 []string                `mapstructure:"floating_ips" cty:"floating_ips" hcl:"floating_ips"`
+	ValidateSSHD                *bool                   `mapstructure:"validate_sshd" cty:"validate_sshd" hcl:"validate_sshd"`
+	Profile                     *string                 `mapstructure:"profile" cty:"profile" hcl:"profile"`
+	ProfilesFile                *string                 `mapstructure:"profiles_file" cty:"profiles_file" hcl:"profiles_file"`
+	ConsoleFile                 *string                 `mapstructure:"console_file" cty:"console_file" hcl:"console_file"`
+	StartAfterCreate            *bool                   `mapstructure:"start_after_create" cty:"start_after_create" hcl:"start_after_create"`
+	HCPLabels                   []string                `mapstructure:"hcp_labels" cty:"hcp_labels" hcl:"hcp_labels"`
+	NoSSHKeys                   *bool                   `mapstructure:"no_ssh_keys" cty:"no_ssh_keys" hcl:"no_ssh_keys"`
+	BootGroupInterval           *string                 `mapstructure:"boot_keygroup_interval" cty:"boot_keygroup_interval" hcl:"boot_keygroup_interval"`
+	BootWait                    *string                 `mapstructure:"boot_wait" cty:"boot_wait" hcl:"boot_wait"`
+	BootCommand                 []string                `mapstructure:"boot_command" cty:"boot_command" hcl:"boot_command"`
+	BootKeyInterval             *string                 `mapstructure:"boot_key_interval" cty:"boot_key_interval" hcl:"boot_key_interval"`
+	DatacenterFallback          *bool                   `mapstructure:"datacenter_fallback" cty:"datacenter_fallback" hcl:"datacenter_fallback"`
+	StatusCheck                 *string                 `mapstructure:"status_check" cty:"status_check" hcl:"status_check"`
+	StatusURL                   *string                 `mapstructure:"status_url" cty:"status_url" hcl:"status_url"`
+	LabelsDiffSelector          *string                 `mapstructure:"labels_diff_selector" cty:"labels_diff_selector" hcl:"labels_diff_selector"`
+	TaintFile                   *string                 `mapstructure:"taint_file" cty:"taint_file" hcl:"taint_file"`
+	LiveSnapshot                *bool                   `mapstructure:"live_snapshot" cty:"live_snapshot" hcl:"live_snapshot"`
+	QuiesceCommands             []string                `mapstructure:"quiesce_commands" cty:"quiesce_commands" hcl:"quiesce_commands"`
+	UnquiesceCommands           []string                `mapstructure:"unquiesce_commands" cty:"unquiesce_commands" hcl:"unquiesce_commands"`
+	UpgradeServerTypeTiming     *string                 `mapstructure:"upgrade_server_type_timing" cty:"upgrade_server_type_timing" hcl:"upgrade_server_type_timing"`
+	UpgradeDisk                 *bool                   `mapstructure:"upgrade_disk" cty:"upgrade_disk" hcl:"upgrade_disk"`
+	VerifyBoot                  []FlatverifyBootTarget  `mapstructure:"verify_boot" cty:"verify_boot" hcl:"verify_boot"`
+	VerifyBootTimeout           *string                 `mapstructure:"verify_boot_timeout" cty:"verify_boot_timeout" hcl:"verify_boot_timeout"`
+	TemporaryNetwork            *FlattemporaryNetwork   `mapstructure:"temporary_network" cty:"temporary_network" hcl:"temporary_network"`
+	ScratchVolume               *FlatscratchVolume      `mapstructure:"scratch_volume" cty:"scratch_volume" hcl:"scratch_volume"`
+	PlacementGroup              *string                 `mapstructure:"placement_group" cty:"placement_group" hcl:"placement_group"`
+	PlacementGroupSelector      *string                 `mapstructure:"placement_group_selector" cty:"placement_group_selector" hcl:"placement_group_selector"`
+	TemporaryPlacementGroup     *bool                   `mapstructure:"temporary_placement_group" cty:"temporary_placement_group" hcl:"temporary_placement_group"`
+	RescueMode                  *string                 `mapstructure:"rescue" cty:"rescue" hcl:"rescue"`
+	RescueCommands              []string                `mapstructure:"rescue_commands" cty:"rescue_commands" hcl:"rescue_commands"`
+	ProvisionInRescue           *bool                   `mapstructure:"provision_in_rescue" cty:"provision_in_rescue" hcl:"provision_in_rescue"`
+	PostProvisionRescueCommands []string                `mapstructure:"post_provision_rescue_commands" cty:"post_provision_rescue_commands" hcl:"post_provision_rescue_commands"`
+	LeaveRescueEnabled          *bool                   `mapstructure:"leave_rescue_enabled" cty:"leave_rescue_enabled" hcl:"leave_rescue_enabled"`
+	ShrinkDiskToGB              *int                    `mapstructure:"shrink_disk_to_gb" cty:"shrink_disk_to_gb" hcl:"shrink_disk_to_gb"`
+	VirtIOISO                   *bool                   `mapstructure:"virtio_iso" cty:"virtio_iso" hcl:"virtio_iso"`
+	SkipCatalogValidation       *bool                   `mapstructure:"skip_catalog_validation" cty:"skip_catalog_validation" hcl:"skip_catalog_validation"`
+	ProtectedLabelSelector      *string                 `mapstructure:"protected_label_selector" cty:"protected_label_selector" hcl:"protected_label_selector"`
+	KeepServer                  *bool                   `mapstructure:"keep_server" cty:"keep_server" hcl:"keep_server"`
+	SkipSnapshot                *bool                   `mapstructure:"skip_snapshot" cty:"skip_snapshot" hcl:"skip_snapshot"`
+	ProtectBuildServer          *bool                   `mapstructure:"protect_build_server" cty:"protect_build_server" hcl:"protect_build_server"`
+	EnableBackups               *bool                   `mapstructure:"enable_backups" cty:"enable_backups" hcl:"enable_backups"`
+	ProtectServer               *bool                   `mapstructure:"protect_server" cty:"protect_server" hcl:"protect_server"`
+	KeptServerName              *string                 `mapstructure:"kept_server_name" cty:"kept_server_name" hcl:"kept_server_name"`
+	KeptServerLabels            map[string]string       `mapstructure:"kept_server_labels" cty:"kept_server_labels" hcl:"kept_server_labels"`
+	KeptServerState             *string                 `mapstructure:"kept_server_state" cty:"kept_server_state" hcl:"kept_server_state"`
+	StepRetries                 *int                    `mapstructure:"step_retries" cty:"step_retries" hcl:"step_retries"`
+	StepRetryDelay              *string                 `mapstructure:"step_retry_delay" cty:"step_retry_delay" hcl:"step_retry_delay"`
+	CostEstimate                *bool                   `mapstructure:"cost_estimate" cty:"cost_estimate" hcl:"cost_estimate"`
+	DryRun                      *bool                   `mapstructure:"dry_run" cty:"dry_run" hcl:"dry_run"`
+	EstimatedBuildDuration      *string                 `mapstructure:"estimated_build_duration" cty:"estimated_build_duration" hcl:"estimated_build_duration"`
+	MaxHourlyPrice              *float64                `mapstructure:"max_hourly_price" cty:"max_hourly_price" hcl:"max_hourly_price"`
+	WriteImageInfo              *bool                   `mapstructure:"write_image_info" cty:"write_image_info" hcl:"write_image_info"`
+	ImageInfoPath               *string                 `mapstructure:"image_info_path" cty:"image_info_path" hcl:"image_info_path"`
+	CollectMetrics              *bool                   `mapstructure:"collect_metrics" cty:"collect_metrics" hcl:"collect_metrics"`
+	MetricsFile                 *string                 `mapstructure:"metrics_file" cty:"metrics_file" hcl:"metrics_file"`
+	SupportBundleDir            *string                 `mapstructure:"support_bundle_dir" cty:"support_bundle_dir" hcl:"support_bundle_dir"`
+	Policy                      *Flatpolicy             `mapstructure:"policy" cty:"policy" hcl:"policy"`
+	PolicyFile                  *string                 `mapstructure:"policy_file" cty:"policy_file" hcl:"policy_file"`
+	ArtifactIDFormat            *string                 `mapstructure:"artifact_id_format" cty:"artifact_id_format" hcl:"artifact_id_format"`
 }
 
 // FlatMapstructure returns a new FlatConfig.
@@ -107,89 +210,250 @@ func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec }
 // The decoded values from this spec will then be applied to a FlatConfig.
 func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
-		"packer_build_name":            &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
-		"packer_builder_type":          &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
-		"packer_core_version":          &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
-		"packer_debug":                 &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
-		"packer_force":                 &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
-		"packer_on_error":              &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
-		"packer_user_variables":        &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
-		"packer_sensitive_variables":   &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
-		"communicator":                 &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
-		"pause_before_connecting":      &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
-		"ssh_host":                     &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
-		"ssh_port":                     &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
-		"ssh_username":                 &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
-		"ssh_password":                 &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
-		"ssh_keypair_name":             &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_name":      &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
-		"temporary_key_pair_type":      &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
-		"temporary_key_pair_bits":      &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
-		"ssh_ciphers":                  &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
-		"ssh_clear_authorized_keys":    &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
-		"ssh_key_exchange_algorithms":  &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
-		"ssh_private_key_file":         &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
-		"ssh_certificate_file":         &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
-		"ssh_pty":                      &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
-		"ssh_timeout":                  &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
-		"ssh_wait_timeout":             &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
-		"ssh_agent_auth":               &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_disable_agent_forwarding": &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
-		"ssh_handshake_attempts":       &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
-		"ssh_bastion_host":             &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
-		"ssh_bastion_port":             &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
-		"ssh_bastion_agent_auth":       &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
-		"ssh_bastion_username":         &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
-		"ssh_bastion_password":         &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
-		"ssh_bastion_interactive":      &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
-		"ssh_bastion_private_key_file": &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
-		"ssh_bastion_certificate_file": &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
-		"ssh_file_transfer_method":     &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
-		"ssh_proxy_host":               &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
-		"ssh_proxy_port":               &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
-		"ssh_proxy_username":           &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
-		"ssh_proxy_password":           &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
-		"ssh_keep_alive_interval":      &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
-		"ssh_read_write_timeout":       &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
-		"ssh_remote_tunnels":           &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_local_tunnels":            &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
-		"ssh_public_key":               &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
-		"ssh_private_key":              &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
-		"winrm_username":               &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
-		"winrm_password":               &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
-		"winrm_host":                   &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
-		"winrm_no_proxy":               &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
-		"winrm_port":                   &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
-		"winrm_timeout":                &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
-		"winrm_use_ssl":                &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
-		"winrm_insecure":               &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
-		"winrm_use_ntlm":               &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
-		"token":                        &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
-		"endpoint":                     &hcldec.AttrSpec{Name: "endpoint", Type: cty.String, Required: false},
-		"poll_interval":                &hcldec.AttrSpec{Name: "poll_interval", Type: cty.String, Required: false},
-		"server_name":                  &hcldec.AttrSpec{Name: "server_name", Type: cty.String, Required: false},
-		"location":                     &hcldec.AttrSpec{Name: "location", Type: cty.String, Required: false},
-		"server_type":                  &hcldec.AttrSpec{Name: "server_type", Type: cty.String, Required: false},
-		"server_labels":                &hcldec.AttrSpec{Name: "server_labels", Type: cty.Map(cty.String), Required: false},
-		"upgrade_server_type":          &hcldec.AttrSpec{Name: "upgrade_server_type", Type: cty.String, Required: false},
-		"image":                        &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
-		"image_filter":                 &hcldec.BlockSpec{TypeName: "image_filter", Nested: hcldec.ObjectSpec((*FlatimageFilter)(nil).HCL2Spec())},
-		"snapshot_name":                &hcldec.AttrSpec{Name: "snapshot_name", Type: cty.String, Required: false},
-		"snapshot_labels":              &hcldec.AttrSpec{Name: "snapshot_labels", Type: cty.Map(cty.String), Required: false},
-		"user_data":                    &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
-		"user_data_file":               &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
-		"ssh_keys":                     &hcldec.AttrSpec{Name: "ssh_keys", Type: cty.List(cty.String), Required: false},
-		"ssh_keys_labels":              &hcldec.AttrSpec{Name: "ssh_keys_labels", Type: cty.Map(cty.String), Required: false},
-		"networks":                     &hcldec.AttrSpec{Name: "networks", Type: cty.List(cty.Number), Required: false},
-		"public_ipv4":                  &hcldec.AttrSpec{Name: "public_ipv4", Type: cty.String, Required: false},
-		"public_ipv4_disabled":         &hcldec.AttrSpec{Name: "public_ipv4_disabled", Type: cty.Bool, Required: false},
-		"public_ipv6":                  &hcldec.AttrSpec{Name: "public_ipv6", Type: cty.String, Required: false},
-		"public_ipv6_disabled":         &hcldec.AttrSpec{Name: "public_ipv6_disabled", Type: cty.Bool, Required: false},
-		"firewalls":                    &hcldec.AttrSpec{Name: "firewalls", Type: cty.List(cty.String), Required: false},
-		"volumes":                      &hcldec.AttrSpec{Name: "volumes", Type: cty.List(cty.String), Required: false},
-		"rescue":                       &hcldec.AttrSpec{Name: "rescue", Type: cty.String, Required: false},
-		"keep_server":         		&hcldec.AttrSpec{Name: "keep_server", Type: cty.Bool, Required: false},
-		"skip_snapshot":         	&hcldec.AttrSpec{Name: "skip_snapshot", Type: cty.Bool, Required: false},
+		"packer_build_name":              &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":            &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":            &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":                   &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":                   &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":                &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":          &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables":     &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"communicator":                   &hcldec.AttrSpec{Name: "communicator", Type: cty.String, Required: false},
+		"pause_before_connecting":        &hcldec.AttrSpec{Name: "pause_before_connecting", Type: cty.String, Required: false},
+		"ssh_host":                       &hcldec.AttrSpec{Name: "ssh_host", Type: cty.String, Required: false},
+		"ssh_port":                       &hcldec.AttrSpec{Name: "ssh_port", Type: cty.Number, Required: false},
+		"ssh_username":                   &hcldec.AttrSpec{Name: "ssh_username", Type: cty.String, Required: false},
+		"ssh_password":                   &hcldec.AttrSpec{Name: "ssh_password", Type: cty.String, Required: false},
+		"ssh_keypair_name":               &hcldec.AttrSpec{Name: "ssh_keypair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_name":        &hcldec.AttrSpec{Name: "temporary_key_pair_name", Type: cty.String, Required: false},
+		"temporary_key_pair_type":        &hcldec.AttrSpec{Name: "temporary_key_pair_type", Type: cty.String, Required: false},
+		"temporary_key_pair_bits":        &hcldec.AttrSpec{Name: "temporary_key_pair_bits", Type: cty.Number, Required: false},
+		"ssh_ciphers":                    &hcldec.AttrSpec{Name: "ssh_ciphers", Type: cty.List(cty.String), Required: false},
+		"ssh_clear_authorized_keys":      &hcldec.AttrSpec{Name: "ssh_clear_authorized_keys", Type: cty.Bool, Required: false},
+		"ssh_key_exchange_algorithms":    &hcldec.AttrSpec{Name: "ssh_key_exchange_algorithms", Type: cty.List(cty.String), Required: false},
+		"ssh_private_key_file":           &hcldec.AttrSpec{Name: "ssh_private_key_file", Type: cty.String, Required: false},
+		"ssh_certificate_file":           &hcldec.AttrSpec{Name: "ssh_certificate_file", Type: cty.String, Required: false},
+		"ssh_pty":                        &hcldec.AttrSpec{Name: "ssh_pty", Type: cty.Bool, Required: false},
+		"ssh_timeout":                    &hcldec.AttrSpec{Name: "ssh_timeout", Type: cty.String, Required: false},
+		"ssh_wait_timeout":               &hcldec.AttrSpec{Name: "ssh_wait_timeout", Type: cty.String, Required: false},
+		"ssh_agent_auth":                 &hcldec.AttrSpec{Name: "ssh_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_disable_agent_forwarding":   &hcldec.AttrSpec{Name: "ssh_disable_agent_forwarding", Type: cty.Bool, Required: false},
+		"ssh_handshake_attempts":         &hcldec.AttrSpec{Name: "ssh_handshake_attempts", Type: cty.Number, Required: false},
+		"ssh_bastion_host":               &hcldec.AttrSpec{Name: "ssh_bastion_host", Type: cty.String, Required: false},
+		"ssh_bastion_port":               &hcldec.AttrSpec{Name: "ssh_bastion_port", Type: cty.Number, Required: false},
+		"ssh_bastion_agent_auth":         &hcldec.AttrSpec{Name: "ssh_bastion_agent_auth", Type: cty.Bool, Required: false},
+		"ssh_bastion_username":           &hcldec.AttrSpec{Name: "ssh_bastion_username", Type: cty.String, Required: false},
+		"ssh_bastion_password":           &hcldec.AttrSpec{Name: "ssh_bastion_password", Type: cty.String, Required: false},
+		"ssh_bastion_interactive":        &hcldec.AttrSpec{Name: "ssh_bastion_interactive", Type: cty.Bool, Required: false},
+		"ssh_bastion_private_key_file":   &hcldec.AttrSpec{Name: "ssh_bastion_private_key_file", Type: cty.String, Required: false},
+		"ssh_bastion_certificate_file":   &hcldec.AttrSpec{Name: "ssh_bastion_certificate_file", Type: cty.String, Required: false},
+		"ssh_file_transfer_method":       &hcldec.AttrSpec{Name: "ssh_file_transfer_method", Type: cty.String, Required: false},
+		"ssh_proxy_host":                 &hcldec.AttrSpec{Name: "ssh_proxy_host", Type: cty.String, Required: false},
+		"ssh_proxy_port":                 &hcldec.AttrSpec{Name: "ssh_proxy_port", Type: cty.Number, Required: false},
+		"ssh_proxy_username":             &hcldec.AttrSpec{Name: "ssh_proxy_username", Type: cty.String, Required: false},
+		"ssh_proxy_password":             &hcldec.AttrSpec{Name: "ssh_proxy_password", Type: cty.String, Required: false},
+		"ssh_keep_alive_interval":        &hcldec.AttrSpec{Name: "ssh_keep_alive_interval", Type: cty.String, Required: false},
+		"ssh_read_write_timeout":         &hcldec.AttrSpec{Name: "ssh_read_write_timeout", Type: cty.String, Required: false},
+		"ssh_remote_tunnels":             &hcldec.AttrSpec{Name: "ssh_remote_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_local_tunnels":              &hcldec.AttrSpec{Name: "ssh_local_tunnels", Type: cty.List(cty.String), Required: false},
+		"ssh_public_key":                 &hcldec.AttrSpec{Name: "ssh_public_key", Type: cty.List(cty.Number), Required: false},
+		"ssh_private_key":                &hcldec.AttrSpec{Name: "ssh_private_key", Type: cty.List(cty.Number), Required: false},
+		"winrm_username":                 &hcldec.AttrSpec{Name: "winrm_username", Type: cty.String, Required: false},
+		"winrm_password":                 &hcldec.AttrSpec{Name: "winrm_password", Type: cty.String, Required: false},
+		"winrm_host":                     &hcldec.AttrSpec{Name: "winrm_host", Type: cty.String, Required: false},
+		"winrm_no_proxy":                 &hcldec.AttrSpec{Name: "winrm_no_proxy", Type: cty.Bool, Required: false},
+		"winrm_port":                     &hcldec.AttrSpec{Name: "winrm_port", Type: cty.Number, Required: false},
+		"winrm_timeout":                  &hcldec.AttrSpec{Name: "winrm_timeout", Type: cty.String, Required: false},
+		"winrm_use_ssl":                  &hcldec.AttrSpec{Name: "winrm_use_ssl", Type: cty.Bool, Required: false},
+		"winrm_insecure":                 &hcldec.AttrSpec{Name: "winrm_insecure", Type: cty.Bool, Required: false},
+		"winrm_use_ntlm":                 &hcldec.AttrSpec{Name: "winrm_use_ntlm", Type: cty.Bool, Required: false},
+		"token":                          &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
+		"endpoint":                       &hcldec.AttrSpec{Name: "endpoint", Type: cty.String, Required: false},
+		"api":                            &hcldec.BlockSpec{TypeName: "api", Nested: hcldec.ObjectSpec((*FlatapiRoutes)(nil).HCL2Spec())},
+		"api_page_size":                  &hcldec.AttrSpec{Name: "api_page_size", Type: cty.Number, Required: false},
+		"api_list_limit":                 &hcldec.AttrSpec{Name: "api_list_limit", Type: cty.Number, Required: false},
+		"poll_interval":                  &hcldec.AttrSpec{Name: "poll_interval", Type: cty.String, Required: false},
+		"heartbeat_interval":             &hcldec.AttrSpec{Name: "heartbeat_interval", Type: cty.String, Required: false},
+		"pause_after_server_ready":       &hcldec.AttrSpec{Name: "pause_after_server_ready", Type: cty.String, Required: false},
+		"port_check_timeout":             &hcldec.AttrSpec{Name: "port_check_timeout", Type: cty.String, Required: false},
+		"pause_before_snapshot":          &hcldec.AttrSpec{Name: "pause_before_snapshot", Type: cty.String, Required: false},
+		"naming":                         &hcldec.BlockSpec{TypeName: "naming", Nested: hcldec.ObjectSpec((*Flatnaming)(nil).HCL2Spec())},
+		"server_name_conflict":           &hcldec.AttrSpec{Name: "server_name_conflict", Type: cty.String, Required: false},
+		"server":                         &hcldec.BlockSpec{TypeName: "server", Nested: hcldec.ObjectSpec((*FlatserverBlock)(nil).HCL2Spec())},
+		"snapshot":                       &hcldec.BlockSpec{TypeName: "snapshot", Nested: hcldec.ObjectSpec((*FlatsnapshotBlock)(nil).HCL2Spec())},
+		"connection":                     &hcldec.BlockSpec{TypeName: "connection", Nested: hcldec.ObjectSpec((*FlatconnectionBlock)(nil).HCL2Spec())},
+		"server_name":                    &hcldec.AttrSpec{Name: "server_name", Type: cty.String, Required: false},
+		"location":                       &hcldec.AttrSpec{Name: "location", Type: cty.String, Required: false},
+		"datacenter":                     &hcldec.AttrSpec{Name: "datacenter", Type: cty.String, Required: false},
+		"server_type":                    &hcldec.AttrSpec{Name: "server_type", Type: cty.String, Required: false},
+		"server_labels":                  &hcldec.AttrSpec{Name: "server_labels", Type: cty.Map(cty.String), Required: false},
+		"upgrade_server_type":            &hcldec.AttrSpec{Name: "upgrade_server_type", Type: cty.String, Required: false},
+		"architecture":                   &hcldec.AttrSpec{Name: "architecture", Type: cty.String, Required: false},
+		"image":                          &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"image_filter":                   &hcldec.BlockSpec{TypeName: "image_filter", Nested: hcldec.ObjectSpec((*FlatimageFilter)(nil).HCL2Spec())},
+		"fail_on_eol":                    &hcldec.AttrSpec{Name: "fail_on_eol", Type: cty.Bool, Required: false},
+		"eol_warning_period":             &hcldec.AttrSpec{Name: "eol_warning_period", Type: cty.String, Required: false},
+		"server_type_class":              &hcldec.AttrSpec{Name: "server_type_class", Type: cty.String, Required: false},
+		"server_type_min_cores":          &hcldec.AttrSpec{Name: "server_type_min_cores", Type: cty.Number, Required: false},
+		"server_type_min_memory":         &hcldec.AttrSpec{Name: "server_type_min_memory", Type: cty.Number, Required: false},
+		"server_type_spec":               &hcldec.BlockSpec{TypeName: "server_type_spec", Nested: hcldec.ObjectSpec((*FlatserverTypeSpec)(nil).HCL2Spec())},
+		"snapshot_name":                  &hcldec.AttrSpec{Name: "snapshot_name", Type: cty.String, Required: false},
+		"snapshot_labels":                &hcldec.AttrSpec{Name: "snapshot_labels", Type: cty.Map(cty.String), Required: false},
+		"snapshot_notes":                 &hcldec.AttrSpec{Name: "snapshot_notes", Type: cty.String, Required: false},
+		"snapshot_retries":               &hcldec.AttrSpec{Name: "snapshot_retries", Type: cty.Number, Required: false},
+		"user_data":                      &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"user_data_file":                 &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+		"dns_servers":                    &hcldec.AttrSpec{Name: "dns_servers", Type: cty.List(cty.String), Required: false},
+		"ssh_keys":                       &hcldec.AttrSpec{Name: "ssh_keys", Type: cty.List(cty.String), Required: false},
+		"ssh_keys_labels":                &hcldec.AttrSpec{Name: "ssh_keys_labels", Type: cty.Map(cty.String), Required: false},
+		"inherit_server_labels":          &hcldec.AttrSpec{Name: "inherit_server_labels", Type: cty.Bool, Required: false},
+		"inherit_server_labels_keys":     &hcldec.AttrSpec{Name: "inherit_server_labels_keys", Type: cty.List(cty.String), Required: false},
+		"apt_mirror":                     &hcldec.AttrSpec{Name: "apt_mirror", Type: cty.String, Required: false},
+		"apt_security_mirror":            &hcldec.AttrSpec{Name: "apt_security_mirror", Type: cty.String, Required: false},
+		"bootstrap_script":               &hcldec.AttrSpec{Name: "bootstrap_script", Type: cty.String, Required: false},
+		"bootstrap_timeout":              &hcldec.AttrSpec{Name: "bootstrap_timeout", Type: cty.String, Required: false},
+		"connectivity_check_url":         &hcldec.AttrSpec{Name: "connectivity_check_url", Type: cty.String, Required: false},
+		"connectivity_check_ip_family":   &hcldec.AttrSpec{Name: "connectivity_check_ip_family", Type: cty.String, Required: false},
+		"remove_foreign_authorized_keys": &hcldec.AttrSpec{Name: "remove_foreign_authorized_keys", Type: cty.Bool, Required: false},
+		"dev_mode":                       &hcldec.AttrSpec{Name: "dev_mode", Type: cty.Bool, Required: false},
+		"dev_mode_watch":                 &hcldec.AttrSpec{Name: "dev_mode_watch", Type: cty.List(cty.String), Required: false},
+		"share_temporary_key":            &hcldec.AttrSpec{Name: "share_temporary_key", Type: cty.Bool, Required: false},
+		"ssh_initial_username":           &hcldec.AttrSpec{Name: "ssh_initial_username", Type: cty.String, Required: false},
+		"networks":                       &hcldec.AttrSpec{Name: "networks", Type: cty.List(cty.String), Required: false},
+		"public_ipv4":                    &hcldec.AttrSpec{Name: "public_ipv4", Type: cty.String, Required: false},
+		"public_ipv4_disabled":           &hcldec.AttrSpec{Name: "public_ipv4_disabled", Type: cty.Bool, Required: false},
+		"public_ipv6":                    &hcldec.AttrSpec{Name: "public_ipv6", Type: cty.String, Required: false},
+		"public_ipv6_disabled":           &hcldec.AttrSpec{Name: "public_ipv6_disabled", Type: cty.Bool, Required: false},
+		"firewalls":                      &hcldec.AttrSpec{Name: "firewalls", Type: cty.List(cty.String), Required: false},
+		"volumes":                        &hcldec.AttrSpec{Name: "volumes", Type: cty.List(cty.String), Required: false},
+		"volume_selector":                &hcldec.AttrSpec{Name: "volume_selector", Type: cty.String, Required: false},
+		"volume_automount":               &hcldec.AttrSpec{Name: "volume_automount", Type: cty.Bool, Required: false},
+		"volume_mount_paths":             &hcldec.AttrSpec{Name: "volume_mount_paths", Type: cty.Map(cty.String), Required: false},
+		"ignore_missing":                 &hcldec.AttrSpec{Name: "ignore_missing", Type: cty.List(cty.String), Required: false},
+		"temporary_firewall":             &hcldec.AttrSpec{Name: "temporary_firewall", Type: cty.Bool, Required: false},
+		"temporary_firewall_ip_url":      &hcldec.AttrSpec{Name: "temporary_firewall_ip_url", Type: cty.String, Required: false},
+		"network":                        &hcldec.BlockListSpec{TypeName: "network", Nested: hcldec.ObjectSpec((*FlatnetworkAttachment)(nil).HCL2Spec())},
+		"primary_ip_auto_delete":         &hcldec.AttrSpec{Name: "primary_ip_auto_delete", Type: cty.Bool, Required: false},
+		"primary_ip_labels":              &hcldec.AttrSpec{Name: "primary_ip_labels", Type: cty.Map(cty.String), Required: false},
+		"keep_primary_ip":                &hcldec.AttrSpec{Name: "keep_primary_ip", Type: cty.Bool, Required: false},
+		"floating_ips":                   &hcldec.AttrSpec{Name: "floating_ips", Type: cty.List(cty.String), Required: false},
+		"validate_sshd":                  &hcldec.AttrSpec{Name: "validate_sshd", Type: cty.Bool, Required: false},
+		"profile":                        &hcldec.AttrSpec{Name: "profile", Type: cty.String, Required: false},
+		"profiles_file":                  &hcldec.AttrSpec{Name: "profiles_file", Type: cty.String, Required: false},
+		"console_file":                   &hcldec.AttrSpec{Name: "console_file", Type: cty.String, Required: false},
+		"start_after_create":             &hcldec.AttrSpec{Name: "start_after_create", Type: cty.Bool, Required: false},
+		"hcp_labels":                     &hcldec.AttrSpec{Name: "hcp_labels", Type: cty.List(cty.String), Required: false},
+		"no_ssh_keys":                    &hcldec.AttrSpec{Name: "no_ssh_keys", Type: cty.Bool, Required: false},
+		"boot_keygroup_interval":         &hcldec.AttrSpec{Name: "boot_keygroup_interval", Type: cty.String, Required: false},
+		"boot_wait":                      &hcldec.AttrSpec{Name: "boot_wait", Type: cty.String, Required: false},
+		"boot_command":                   &hcldec.AttrSpec{Name: "boot_command", Type: cty.List(cty.String), Required: false},
+		"boot_key_interval":              &hcldec.AttrSpec{Name: "boot_key_interval", Type: cty.String, Required: false},
+		"datacenter_fallback":            &hcldec.AttrSpec{Name: "datacenter_fallback", Type: cty.Bool, Required: false},
+		"status_check":                   &hcldec.AttrSpec{Name: "status_check", Type: cty.String, Required: false},
+		"status_url":                     &hcldec.AttrSpec{Name: "status_url", Type: cty.String, Required: false},
+		"labels_diff_selector":           &hcldec.AttrSpec{Name: "labels_diff_selector", Type: cty.String, Required: false},
+		"taint_file":                     &hcldec.AttrSpec{Name: "taint_file", Type: cty.String, Required: false},
+		"live_snapshot":                  &hcldec.AttrSpec{Name: "live_snapshot", Type: cty.Bool, Required: false},
+		"quiesce_commands":               &hcldec.AttrSpec{Name: "quiesce_commands", Type: cty.List(cty.String), Required: false},
+		"unquiesce_commands":             &hcldec.AttrSpec{Name: "unquiesce_commands", Type: cty.List(cty.String), Required: false},
+		"upgrade_server_type_timing":     &hcldec.AttrSpec{Name: "upgrade_server_type_timing", Type: cty.String, Required: false},
+		"upgrade_disk":                   &hcldec.AttrSpec{Name: "upgrade_disk", Type: cty.Bool, Required: false},
+		"verify_boot":                    &hcldec.BlockListSpec{TypeName: "verify_boot", Nested: hcldec.ObjectSpec((*FlatverifyBootTarget)(nil).HCL2Spec())},
+		"verify_boot_timeout":            &hcldec.AttrSpec{Name: "verify_boot_timeout", Type: cty.String, Required: false},
+		"temporary_network":              &hcldec.BlockSpec{TypeName: "temporary_network", Nested: hcldec.ObjectSpec((*FlattemporaryNetwork)(nil).HCL2Spec())},
+		"scratch_volume":                 &hcldec.BlockSpec{TypeName: "scratch_volume", Nested: hcldec.ObjectSpec((*FlatscratchVolume)(nil).HCL2Spec())},
+		"placement_group":                &hcldec.AttrSpec{Name: "placement_group", Type: cty.String, Required: false},
+		"placement_group_selector":       &hcldec.AttrSpec{Name: "placement_group_selector", Type: cty.String, Required: false},
+		"temporary_placement_group":      &hcldec.AttrSpec{Name: "temporary_placement_group", Type: cty.Bool, Required: false},
+		"rescue":                         &hcldec.AttrSpec{Name: "rescue", Type: cty.String, Required: false},
+		"rescue_commands":                &hcldec.AttrSpec{Name: "rescue_commands", Type: cty.List(cty.String), Required: false},
+		"provision_in_rescue":            &hcldec.AttrSpec{Name: "provision_in_rescue", Type: cty.Bool, Required: false},
+		"post_provision_rescue_commands": &hcldec.AttrSpec{Name: "post_provision_rescue_commands", Type: cty.List(cty.String), Required: false},
+		"leave_rescue_enabled":           &hcldec.AttrSpec{Name: "leave_rescue_enabled", Type: cty.Bool, Required: false},
+		"shrink_disk_to_gb":              &hcldec.AttrSpec{Name: "shrink_disk_to_gb", Type: cty.Number, Required: false},
+		"virtio_iso":                     &hcldec.AttrSpec{Name: "virtio_iso", Type: cty.Bool, Required: false},
+		"skip_catalog_validation":        &hcldec.AttrSpec{Name: "skip_catalog_validation", Type: cty.Bool, Required: false},
+		"protected_label_selector":       &hcldec.AttrSpec{Name: "protected_label_selector", Type: cty.String, Required: false},
+		"keep_server":                    &hcldec.AttrSpec{Name: "keep_server", Type: cty.Bool, Required: false},
+		"skip_snapshot":                  &hcldec.AttrSpec{Name: "skip_snapshot", Type: cty.Bool, Required: false},
+		"protect_build_server":           &hcldec.AttrSpec{Name: "protect_build_server", Type: cty.Bool, Required: false},
+		"enable_backups":                 &hcldec.AttrSpec{Name: "enable_backups", Type: cty.Bool, Required: false},
+		"protect_server":                 &hcldec.AttrSpec{Name: "protect_server", Type: cty.Bool, Required: false},
+		"kept_server_name":               &hcldec.AttrSpec{Name: "kept_server_name", Type: cty.String, Required: false},
+		"kept_server_labels":             &hcldec.AttrSpec{Name: "kept_server_labels", Type: cty.Map(cty.String), Required: false},
+		"kept_server_state":              &hcldec.AttrSpec{Name: "kept_server_state", Type: cty.String, Required: false},
+		"step_retries":                   &hcldec.AttrSpec{Name: "step_retries", Type: cty.Number, Required: false},
+		"step_retry_delay":               &hcldec.AttrSpec{Name: "step_retry_delay", Type: cty.String, Required: false},
+		"cost_estimate":                  &hcldec.AttrSpec{Name: "cost_estimate", Type: cty.Bool, Required: false},
+		"dry_run":                        &hcldec.AttrSpec{Name: "dry_run", Type: cty.Bool, Required: false},
+		"estimated_build_duration":       &hcldec.AttrSpec{Name: "estimated_build_duration", Type: cty.String, Required: false},
+		"max_hourly_price":               &hcldec.AttrSpec{Name: "max_hourly_price", Type: cty.Number, Required: false},
+		"write_image_info":               &hcldec.AttrSpec{Name: "write_image_info", Type: cty.Bool, Required: false},
+		"image_info_path":                &hcldec.AttrSpec{Name: "image_info_path", Type: cty.String, Required: false},
+		"collect_metrics":                &hcldec.AttrSpec{Name: "collect_metrics", Type: cty.Bool, Required: false},
+		"metrics_file":                   &hcldec.AttrSpec{Name: "metrics_file", Type: cty.String, Required: false},
+		"support_bundle_dir":             &hcldec.AttrSpec{Name: "support_bundle_dir", Type: cty.String, Required: false},
+		"policy":                         &hcldec.BlockSpec{TypeName: "policy", Nested: hcldec.ObjectSpec((*Flatpolicy)(nil).HCL2Spec())},
+		"policy_file":                    &hcldec.AttrSpec{Name: "policy_file", Type: cty.String, Required: false},
+		"artifact_id_format":             &hcldec.AttrSpec{Name: "artifact_id_format", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatapiRoutes is an auto-generated flat version of apiRoutes.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatapiRoutes struct {
+	ReadEndpoint  *string `mapstructure:"read_endpoint" cty:"read_endpoint" hcl:"read_endpoint"`
+	ReadToken     *string `mapstructure:"read_token" cty:"read_token" hcl:"read_token"`
+	WriteEndpoint *string `mapstructure:"write_endpoint" cty:"write_endpoint" hcl:"write_endpoint"`
+	WriteToken    *string `mapstructure:"write_token" cty:"write_token" hcl:"write_token"`
+}
+
+// FlatMapstructure returns a new FlatapiRoutes.
+// FlatapiRoutes is an auto-generated flat version of apiRoutes.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*apiRoutes) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatapiRoutes)
+}
+
+// HCL2Spec returns the hcl spec of a apiRoutes.
+// This spec is used by HCL to read the fields of apiRoutes.
+// The decoded values from this spec will then be applied to a FlatapiRoutes.
+func (*FlatapiRoutes) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"read_endpoint":  &hcldec.AttrSpec{Name: "read_endpoint", Type: cty.String, Required: false},
+		"read_token":     &hcldec.AttrSpec{Name: "read_token", Type: cty.String, Required: false},
+		"write_endpoint": &hcldec.AttrSpec{Name: "write_endpoint", Type: cty.String, Required: false},
+		"write_token":    &hcldec.AttrSpec{Name: "write_token", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatconnectionBlock is an auto-generated flat version of connectionBlock.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatconnectionBlock struct {
+	InitialUsername   *string           `mapstructure:"initial_username" cty:"initial_username" hcl:"initial_username"`
+	SSHKeys           []string          `mapstructure:"ssh_keys" cty:"ssh_keys" hcl:"ssh_keys"`
+	SSHKeysLabels     map[string]string `mapstructure:"ssh_keys_labels" cty:"ssh_keys_labels" hcl:"ssh_keys_labels"`
+	ShareTemporaryKey *bool             `mapstructure:"share_temporary_key" cty:"share_temporary_key" hcl:"share_temporary_key"`
+}
+
+// FlatMapstructure returns a new FlatconnectionBlock.
+// FlatconnectionBlock is an auto-generated flat version of connectionBlock.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*connectionBlock) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatconnectionBlock)
+}
+
+// HCL2Spec returns the hcl spec of a connectionBlock.
+// This spec is used by HCL to read the fields of connectionBlock.
+// The decoded values from this spec will then be applied to a FlatconnectionBlock.
+func (*FlatconnectionBlock) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"initial_username":    &hcldec.AttrSpec{Name: "initial_username", Type: cty.String, Required: false},
+		"ssh_keys":            &hcldec.AttrSpec{Name: "ssh_keys", Type: cty.List(cty.String), Required: false},
+		"ssh_keys_labels":     &hcldec.AttrSpec{Name: "ssh_keys_labels", Type: cty.Map(cty.String), Required: false},
+		"share_temporary_key": &hcldec.AttrSpec{Name: "share_temporary_key", Type: cty.Bool, Required: false},
 	}
 	return s
 }
@@ -199,6 +463,7 @@ func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
 type FlatimageFilter struct {
 	WithSelector []string `mapstructure:"with_selector" cty:"with_selector" hcl:"with_selector"`
 	MostRecent   *bool    `mapstructure:"most_recent" cty:"most_recent" hcl:"most_recent"`
+	OnMultiple   *string  `mapstructure:"on_multiple" cty:"on_multiple" hcl:"on_multiple"`
 }
 
 // FlatMapstructure returns a new FlatimageFilter.
@@ -215,6 +480,270 @@ func (*FlatimageFilter) HCL2Spec() map[string]hcldec.Spec {
 	s := map[string]hcldec.Spec{
 		"with_selector": &hcldec.AttrSpec{Name: "with_selector", Type: cty.List(cty.String), Required: false},
 		"most_recent":   &hcldec.AttrSpec{Name: "most_recent", Type: cty.Bool, Required: false},
+		"on_multiple":   &hcldec.AttrSpec{Name: "on_multiple", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// Flatnaming is an auto-generated flat version of naming.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type Flatnaming struct {
+	Prefix *string `mapstructure:"prefix" cty:"prefix" hcl:"prefix"`
+	Style  *string `mapstructure:"style" cty:"style" hcl:"style"`
+}
+
+// FlatMapstructure returns a new Flatnaming.
+// Flatnaming is an auto-generated flat version of naming.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*naming) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(Flatnaming)
+}
+
+// HCL2Spec returns the hcl spec of a naming.
+// This spec is used by HCL to read the fields of naming.
+// The decoded values from this spec will then be applied to a Flatnaming.
+func (*Flatnaming) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"prefix": &hcldec.AttrSpec{Name: "prefix", Type: cty.String, Required: false},
+		"style":  &hcldec.AttrSpec{Name: "style", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatnetworkAttachment is an auto-generated flat version of networkAttachment.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatnetworkAttachment struct {
+	ID       *int64   `mapstructure:"id" cty:"id" hcl:"id"`
+	Name     *string  `mapstructure:"name" cty:"name" hcl:"name"`
+	IP       *string  `mapstructure:"ip" cty:"ip" hcl:"ip"`
+	AliasIPs []string `mapstructure:"alias_ips" cty:"alias_ips" hcl:"alias_ips"`
+}
+
+// FlatMapstructure returns a new FlatnetworkAttachment.
+// FlatnetworkAttachment is an auto-generated flat version of networkAttachment.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*networkAttachment) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatnetworkAttachment)
+}
+
+// HCL2Spec returns the hcl spec of a networkAttachment.
+// This spec is used by HCL to read the fields of networkAttachment.
+// The decoded values from this spec will then be applied to a FlatnetworkAttachment.
+func (*FlatnetworkAttachment) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":        &hcldec.AttrSpec{Name: "id", Type: cty.Number, Required: false},
+		"name":      &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"ip":        &hcldec.AttrSpec{Name: "ip", Type: cty.String, Required: false},
+		"alias_ips": &hcldec.AttrSpec{Name: "alias_ips", Type: cty.List(cty.String), Required: false},
+	}
+	return s
+}
+
+// Flatpolicy is an auto-generated flat version of policy.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type Flatpolicy struct {
+	RequiredLabels       []string `mapstructure:"required_labels" cty:"required_labels" hcl:"required_labels"`
+	ForbiddenLocations   []string `mapstructure:"forbidden_locations" cty:"forbidden_locations" hcl:"forbidden_locations"`
+	ForbiddenServerTypes []string `mapstructure:"forbidden_server_types" cty:"forbidden_server_types" hcl:"forbidden_server_types"`
+	MaxBuildCost         *float64 `mapstructure:"max_build_cost" cty:"max_build_cost" hcl:"max_build_cost"`
+}
+
+// FlatMapstructure returns a new Flatpolicy.
+// Flatpolicy is an auto-generated flat version of policy.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*policy) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(Flatpolicy)
+}
+
+// HCL2Spec returns the hcl spec of a policy.
+// This spec is used by HCL to read the fields of policy.
+// The decoded values from this spec will then be applied to a Flatpolicy.
+func (*Flatpolicy) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"required_labels":        &hcldec.AttrSpec{Name: "required_labels", Type: cty.List(cty.String), Required: false},
+		"forbidden_locations":    &hcldec.AttrSpec{Name: "forbidden_locations", Type: cty.List(cty.String), Required: false},
+		"forbidden_server_types": &hcldec.AttrSpec{Name: "forbidden_server_types", Type: cty.List(cty.String), Required: false},
+		"max_build_cost":         &hcldec.AttrSpec{Name: "max_build_cost", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlatscratchVolume is an auto-generated flat version of scratchVolume.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatscratchVolume struct {
+	Size       *int    `mapstructure:"size" cty:"size" hcl:"size"`
+	Filesystem *string `mapstructure:"filesystem" cty:"filesystem" hcl:"filesystem"`
+	MountPath  *string `mapstructure:"mount_path" cty:"mount_path" hcl:"mount_path"`
+	Automount  *bool   `mapstructure:"automount" cty:"automount" hcl:"automount"`
+}
+
+// FlatMapstructure returns a new FlatscratchVolume.
+// FlatscratchVolume is an auto-generated flat version of scratchVolume.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*scratchVolume) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatscratchVolume)
+}
+
+// HCL2Spec returns the hcl spec of a scratchVolume.
+// This spec is used by HCL to read the fields of scratchVolume.
+// The decoded values from this spec will then be applied to a FlatscratchVolume.
+func (*FlatscratchVolume) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"size":       &hcldec.AttrSpec{Name: "size", Type: cty.Number, Required: false},
+		"filesystem": &hcldec.AttrSpec{Name: "filesystem", Type: cty.String, Required: false},
+		"mount_path": &hcldec.AttrSpec{Name: "mount_path", Type: cty.String, Required: false},
+		"automount":  &hcldec.AttrSpec{Name: "automount", Type: cty.Bool, Required: false},
+	}
+	return s
+}
+
+// FlatserverBlock is an auto-generated flat version of serverBlock.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatserverBlock struct {
+	Name         *string           `mapstructure:"name" cty:"name" hcl:"name"`
+	Location     *string           `mapstructure:"location" cty:"location" hcl:"location"`
+	Type         *string           `mapstructure:"type" cty:"type" hcl:"type"`
+	UpgradeType  *string           `mapstructure:"upgrade_type" cty:"upgrade_type" hcl:"upgrade_type"`
+	Labels       map[string]string `mapstructure:"labels" cty:"labels" hcl:"labels"`
+	Image        *string           `mapstructure:"image" cty:"image" hcl:"image"`
+	UserData     *string           `mapstructure:"user_data" cty:"user_data" hcl:"user_data"`
+	UserDataFile *string           `mapstructure:"user_data_file" cty:"user_data_file" hcl:"user_data_file"`
+}
+
+// FlatMapstructure returns a new FlatserverBlock.
+// FlatserverBlock is an auto-generated flat version of serverBlock.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*serverBlock) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatserverBlock)
+}
+
+// HCL2Spec returns the hcl spec of a serverBlock.
+// This spec is used by HCL to read the fields of serverBlock.
+// The decoded values from this spec will then be applied to a FlatserverBlock.
+func (*FlatserverBlock) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":           &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"location":       &hcldec.AttrSpec{Name: "location", Type: cty.String, Required: false},
+		"type":           &hcldec.AttrSpec{Name: "type", Type: cty.String, Required: false},
+		"upgrade_type":   &hcldec.AttrSpec{Name: "upgrade_type", Type: cty.String, Required: false},
+		"labels":         &hcldec.AttrSpec{Name: "labels", Type: cty.Map(cty.String), Required: false},
+		"image":          &hcldec.AttrSpec{Name: "image", Type: cty.String, Required: false},
+		"user_data":      &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"user_data_file": &hcldec.AttrSpec{Name: "user_data_file", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatserverTypeSpec is an auto-generated flat version of serverTypeSpec.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatserverTypeSpec struct {
+	MinCores     *int     `mapstructure:"min_cores" cty:"min_cores" hcl:"min_cores"`
+	MinMemory    *float32 `mapstructure:"min_memory" cty:"min_memory" hcl:"min_memory"`
+	MinDisk      *int     `mapstructure:"min_disk" cty:"min_disk" hcl:"min_disk"`
+	Architecture *string  `mapstructure:"architecture" cty:"architecture" hcl:"architecture"`
+	CPUType      *string  `mapstructure:"cpu_type" cty:"cpu_type" hcl:"cpu_type"`
+}
+
+// FlatMapstructure returns a new FlatserverTypeSpec.
+// FlatserverTypeSpec is an auto-generated flat version of serverTypeSpec.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*serverTypeSpec) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatserverTypeSpec)
+}
+
+// HCL2Spec returns the hcl spec of a serverTypeSpec.
+// This spec is used by HCL to read the fields of serverTypeSpec.
+// The decoded values from this spec will then be applied to a FlatserverTypeSpec.
+func (*FlatserverTypeSpec) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"min_cores":    &hcldec.AttrSpec{Name: "min_cores", Type: cty.Number, Required: false},
+		"min_memory":   &hcldec.AttrSpec{Name: "min_memory", Type: cty.Number, Required: false},
+		"min_disk":     &hcldec.AttrSpec{Name: "min_disk", Type: cty.Number, Required: false},
+		"architecture": &hcldec.AttrSpec{Name: "architecture", Type: cty.String, Required: false},
+		"cpu_type":     &hcldec.AttrSpec{Name: "cpu_type", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatsnapshotBlock is an auto-generated flat version of snapshotBlock.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatsnapshotBlock struct {
+	Name    *string           `mapstructure:"name" cty:"name" hcl:"name"`
+	Labels  map[string]string `mapstructure:"labels" cty:"labels" hcl:"labels"`
+	Notes   *string           `mapstructure:"notes" cty:"notes" hcl:"notes"`
+	Retries *int              `mapstructure:"retries" cty:"retries" hcl:"retries"`
+}
+
+// FlatMapstructure returns a new FlatsnapshotBlock.
+// FlatsnapshotBlock is an auto-generated flat version of snapshotBlock.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*snapshotBlock) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatsnapshotBlock)
+}
+
+// HCL2Spec returns the hcl spec of a snapshotBlock.
+// This spec is used by HCL to read the fields of snapshotBlock.
+// The decoded values from this spec will then be applied to a FlatsnapshotBlock.
+func (*FlatsnapshotBlock) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"name":    &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"labels":  &hcldec.AttrSpec{Name: "labels", Type: cty.Map(cty.String), Required: false},
+		"notes":   &hcldec.AttrSpec{Name: "notes", Type: cty.String, Required: false},
+		"retries": &hcldec.AttrSpec{Name: "retries", Type: cty.Number, Required: false},
+	}
+	return s
+}
+
+// FlattemporaryNetwork is an auto-generated flat version of temporaryNetwork.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlattemporaryNetwork struct {
+	IPRange     *string `mapstructure:"ip_range" cty:"ip_range" hcl:"ip_range"`
+	Subnet      *string `mapstructure:"subnet" cty:"subnet" hcl:"subnet"`
+	Bastion     *string `mapstructure:"bastion" cty:"bastion" hcl:"bastion"`
+	NetworkZone *string `mapstructure:"network_zone" cty:"network_zone" hcl:"network_zone"`
+}
+
+// FlatMapstructure returns a new FlattemporaryNetwork.
+// FlattemporaryNetwork is an auto-generated flat version of temporaryNetwork.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*temporaryNetwork) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlattemporaryNetwork)
+}
+
+// HCL2Spec returns the hcl spec of a temporaryNetwork.
+// This spec is used by HCL to read the fields of temporaryNetwork.
+// The decoded values from this spec will then be applied to a FlattemporaryNetwork.
+func (*FlattemporaryNetwork) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"ip_range":     &hcldec.AttrSpec{Name: "ip_range", Type: cty.String, Required: false},
+		"subnet":       &hcldec.AttrSpec{Name: "subnet", Type: cty.String, Required: false},
+		"bastion":      &hcldec.AttrSpec{Name: "bastion", Type: cty.String, Required: false},
+		"network_zone": &hcldec.AttrSpec{Name: "network_zone", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatverifyBootTarget is an auto-generated flat version of verifyBootTarget.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatverifyBootTarget struct {
+	ServerType *string `mapstructure:"server_type" cty:"server_type" hcl:"server_type"`
+	Location   *string `mapstructure:"location" cty:"location" hcl:"location"`
+}
+
+// FlatMapstructure returns a new FlatverifyBootTarget.
+// FlatverifyBootTarget is an auto-generated flat version of verifyBootTarget.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*verifyBootTarget) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatverifyBootTarget)
+}
+
+// HCL2Spec returns the hcl spec of a verifyBootTarget.
+// This spec is used by HCL to read the fields of verifyBootTarget.
+// The decoded values from this spec will then be applied to a FlatverifyBootTarget.
+func (*FlatverifyBootTarget) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"server_type": &hcldec.AttrSpec{Name: "server_type", Type: cty.String, Required: false},
+		"location":    &hcldec.AttrSpec{Name: "location", Type: cty.String, Required: false},
 	}
 	return s
 }
@@ -0,0 +1,167 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigPrepare(t *testing.T) {
+	raw := func(extra map[string]interface{}) map[string]interface{} {
+		raw := map[string]interface{}{
+			"token":        "dummy-token",
+			"image":        "debian-12",
+			"location":     "nbg1",
+			"server_type":  "cpx11",
+			"ssh_username": "root",
+			"ssh_password": "dummy-password",
+		}
+		for key, value := range extra {
+			if value == nil {
+				delete(raw, key)
+				continue
+			}
+			raw[key] = value
+		}
+		return raw
+	}
+
+	testCases := []struct {
+		name  string
+		extra map[string]interface{}
+		err   string
+		check func(t *testing.T, c *Config)
+	}{
+		{
+			name:  "no_ssh_keys",
+			extra: map[string]interface{}{"no_ssh_keys": true},
+		},
+		{
+			name:  "no_ssh_keys with shrink_disk_to_gb",
+			extra: map[string]interface{}{"no_ssh_keys": true, "shrink_disk_to_gb": 10},
+			err:   "no_ssh_keys cannot be used with shrink_disk_to_gb",
+		},
+		{
+			name:  "live_snapshot",
+			extra: map[string]interface{}{"live_snapshot": true},
+		},
+		{
+			name:  "live_snapshot with post_provision_rescue_commands",
+			extra: map[string]interface{}{"live_snapshot": true, "post_provision_rescue_commands": []string{"true"}},
+			err:   "live_snapshot cannot be used with post_provision_rescue_commands",
+		},
+		{
+			name:  "live_snapshot with shrink_disk_to_gb",
+			extra: map[string]interface{}{"live_snapshot": true, "shrink_disk_to_gb": 10},
+			err:   "live_snapshot cannot be used with shrink_disk_to_gb",
+		},
+		{
+			name:  "provision_in_rescue defaults",
+			extra: map[string]interface{}{"provision_in_rescue": true, "ssh_username": nil},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, "linux64", c.RescueMode)
+				assert.Equal(t, "root", c.Comm.SSHUsername)
+			},
+		},
+		{
+			name:  "provision_in_rescue keeps rescue",
+			extra: map[string]interface{}{"provision_in_rescue": true, "rescue": "linux32"},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, "linux32", c.RescueMode)
+			},
+		},
+		{
+			name:  "provision_in_rescue with another communicator",
+			extra: map[string]interface{}{"provision_in_rescue": true, "communicator": "none"},
+			err:   "provision_in_rescue requires the ssh communicator",
+		},
+		{
+			name:  "provision_in_rescue with another ssh_username",
+			extra: map[string]interface{}{"provision_in_rescue": true, "ssh_username": "admin"},
+			err:   "provision_in_rescue requires ssh_username 'root', the user of the rescue system",
+		},
+		{
+			name:  "provision_in_rescue with rescue_commands",
+			extra: map[string]interface{}{"provision_in_rescue": true, "rescue_commands": []string{"true"}},
+			err:   "provision_in_rescue cannot be used with rescue_commands",
+		},
+		{
+			name:  "provision_in_rescue with post_provision_rescue_commands",
+			extra: map[string]interface{}{"provision_in_rescue": true, "post_provision_rescue_commands": []string{"true"}},
+			err:   "provision_in_rescue cannot be used with post_provision_rescue_commands",
+		},
+		{
+			name:  "provision_in_rescue with shrink_disk_to_gb",
+			extra: map[string]interface{}{"provision_in_rescue": true, "shrink_disk_to_gb": 10},
+			err:   "provision_in_rescue cannot be used with shrink_disk_to_gb",
+		},
+		{
+			name:  "provision_in_rescue with ssh_initial_username",
+			extra: map[string]interface{}{"provision_in_rescue": true, "ssh_initial_username": "root"},
+			err:   "provision_in_rescue cannot be used with ssh_initial_username",
+		},
+		{
+			name:  "provision_in_rescue with bootstrap_script",
+			extra: map[string]interface{}{"provision_in_rescue": true, "bootstrap_script": "bootstrap.sh"},
+			err:   "provision_in_rescue cannot be used with bootstrap_script",
+		},
+		{
+			name:  "provision_in_rescue with validate_sshd",
+			extra: map[string]interface{}{"provision_in_rescue": true, "validate_sshd": true},
+			err:   "provision_in_rescue cannot be used with validate_sshd",
+		},
+		{
+			name:  "provision_in_rescue with write_image_info",
+			extra: map[string]interface{}{"provision_in_rescue": true, "write_image_info": true},
+			err:   "provision_in_rescue cannot be used with write_image_info",
+		},
+		{
+			name:  "provision_in_rescue with remove_foreign_authorized_keys",
+			extra: map[string]interface{}{"provision_in_rescue": true, "remove_foreign_authorized_keys": true},
+			err:   "provision_in_rescue cannot be used with remove_foreign_authorized_keys",
+		},
+		{
+			name:  "temporary key pair type defaults to ed25519",
+			extra: map[string]interface{}{"ssh_password": nil},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, "ed25519", c.Comm.SSHTemporaryKeyPairType)
+			},
+		},
+		{
+			name:  "temporary key pair type rsa",
+			extra: map[string]interface{}{"ssh_password": nil, "temporary_key_pair_type": "rsa", "temporary_key_pair_bits": 4096},
+			check: func(t *testing.T, c *Config) {
+				assert.Equal(t, "rsa", c.Comm.SSHTemporaryKeyPairType)
+				assert.Equal(t, 4096, c.Comm.SSHTemporaryKeyPairBits)
+			},
+		},
+		{
+			name:  "temporary key pair type ecdsa",
+			extra: map[string]interface{}{"ssh_password": nil, "temporary_key_pair_type": "ecdsa"},
+			err:   "temporary_key_pair_type must be ed25519 or rsa, got 'ecdsa'",
+		},
+		{
+			name:  "temporary key pair bits below the rsa minimum",
+			extra: map[string]interface{}{"ssh_password": nil, "temporary_key_pair_type": "rsa", "temporary_key_pair_bits": 1024},
+			err:   "temporary_key_pair_bits must be at least 2048 for rsa keys",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var c Config
+			_, err := c.Prepare(raw(tc.extra))
+			if tc.err != "" {
+				assert.ErrorContains(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			if tc.check != nil {
+				tc.check(t, &c)
+			}
+		})
+	}
+}
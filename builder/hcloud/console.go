@@ -0,0 +1,180 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"crypto/des"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	"golang.org/x/net/websocket"
+)
+
+// consoleOrigin is the origin of the WebSocket connections to the console.
+const consoleOrigin = "https://console.hetzner.cloud"
+
+// The RFB protocol version and security type used with the console.
+const (
+	rfbProtocolVersion = "RFB 003.008\n"
+	rfbSecurityVNCAuth = 2
+)
+
+// vncConsole is a minimal RFB client of the VNC console of a server, only
+// sending key events, as needed to type the boot_command.
+type vncConsole struct {
+	conn io.ReadWriteCloser
+}
+
+// dialConsole connects to the WebSocket VNC console of a server, and
+// authenticates with the password of the console.
+func dialConsole(ctx context.Context, wssURL, password string) (*vncConsole, error) {
+	config, err := websocket.NewConfig(wssURL, consoleOrigin)
+	if err != nil {
+		return nil, err
+	}
+	config.Protocol = []string{"binary"}
+
+	conn, err := config.DialContext(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("could not connect to the console: %w", err)
+	}
+	conn.PayloadType = websocket.BinaryFrame
+
+	console := &vncConsole{conn: conn}
+	if err := console.handshake(password); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	// The server messages, e.g. bells, are not used
+	go io.Copy(io.Discard, conn) //nolint:errcheck
+
+	return console, nil
+}
+
+// handshake negotiates the protocol version and the VNC authentication, and
+// initializes a shared session.
+func (v *vncConsole) handshake(password string) error {
+	version := make([]byte, len(rfbProtocolVersion))
+	if _, err := io.ReadFull(v.conn, version); err != nil {
+		return fmt.Errorf("could not read the protocol version: %w", err)
+	}
+	if _, err := v.conn.Write([]byte(rfbProtocolVersion)); err != nil {
+		return err
+	}
+
+	var count uint8
+	if err := binary.Read(v.conn, binary.BigEndian, &count); err != nil {
+		return fmt.Errorf("could not read the security types: %w", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("the console refused the connection: %s", v.readReason())
+	}
+	types := make([]byte, count)
+	if _, err := io.ReadFull(v.conn, types); err != nil {
+		return fmt.Errorf("could not read the security types: %w", err)
+	}
+	supported := false
+	for _, t := range types {
+		supported = supported || t == rfbSecurityVNCAuth
+	}
+	if !supported {
+		return fmt.Errorf("the console does not support the VNC authentication, only %v", types)
+	}
+	if _, err := v.conn.Write([]byte{rfbSecurityVNCAuth}); err != nil {
+		return err
+	}
+
+	challenge := make([]byte, 16)
+	if _, err := io.ReadFull(v.conn, challenge); err != nil {
+		return fmt.Errorf("could not read the authentication challenge: %w", err)
+	}
+	response, err := vncAuthResponse(password, challenge)
+	if err != nil {
+		return err
+	}
+	if _, err := v.conn.Write(response); err != nil {
+		return err
+	}
+
+	var result uint32
+	if err := binary.Read(v.conn, binary.BigEndian, &result); err != nil {
+		return fmt.Errorf("could not read the authentication result: %w", err)
+	}
+	if result != 0 {
+		return fmt.Errorf("the console rejected the authentication: %s", v.readReason())
+	}
+
+	// ClientInit with a shared session, then skip the ServerInit: the size and
+	// pixel format of the framebuffer, and the name of the desktop
+	if _, err := v.conn.Write([]byte{1}); err != nil {
+		return err
+	}
+	serverInit := make([]byte, 2+2+16)
+	if _, err := io.ReadFull(v.conn, serverInit); err != nil {
+		return fmt.Errorf("could not read the server init: %w", err)
+	}
+	var nameLength uint32
+	if err := binary.Read(v.conn, binary.BigEndian, &nameLength); err != nil {
+		return fmt.Errorf("could not read the server init: %w", err)
+	}
+	if _, err := io.CopyN(io.Discard, v.conn, int64(nameLength)); err != nil {
+		return fmt.Errorf("could not read the server init: %w", err)
+	}
+	return nil
+}
+
+// readReason returns the reason string sent by the server on failures.
+func (v *vncConsole) readReason() string {
+	var length uint32
+	if err := binary.Read(v.conn, binary.BigEndian, &length); err != nil || length > 1024 {
+		return "no reason given"
+	}
+	reason := make([]byte, length)
+	if _, err := io.ReadFull(v.conn, reason); err != nil {
+		return "no reason given"
+	}
+	return string(reason)
+}
+
+// KeyEvent presses or releases a key, implementing bootcommand.VNCKeyEvent.
+func (v *vncConsole) KeyEvent(key uint32, down bool) error {
+	message := make([]byte, 8)
+	message[0] = 4 // KeyEvent
+	if down {
+		message[1] = 1
+	}
+	binary.BigEndian.PutUint32(message[4:], key)
+	_, err := v.conn.Write(message)
+	return err
+}
+
+func (v *vncConsole) Close() error {
+	return v.conn.Close()
+}
+
+// vncAuthResponse encrypts the challenge with DES, using the password with the
+// bits of each byte reversed as key, as specified by the VNC authentication.
+func vncAuthResponse(password string, challenge []byte) ([]byte, error) {
+	if len(challenge) != 16 {
+		return nil, errors.New("the authentication challenge must be 16 bytes")
+	}
+	key := make([]byte, 8)
+	copy(key, password)
+	for i := range key {
+		key[i] = bits.Reverse8(key[i])
+	}
+	cipher, err := des.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	response := make([]byte, 16)
+	cipher.Encrypt(response[:8], challenge[:8])
+	cipher.Encrypt(response[8:], challenge[8:])
+	return response, nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVNCAuthResponse(t *testing.T) {
+	challenge := []byte("0123456789abcdef")
+
+	response, err := vncAuthResponse("secret", challenge)
+	require.NoError(t, err)
+	assert.Len(t, response, 16)
+	assert.NotEqual(t, challenge, response)
+
+	again, err := vncAuthResponse("secret", challenge)
+	require.NoError(t, err)
+	assert.Equal(t, response, again)
+
+	other, err := vncAuthResponse("other", challenge)
+	require.NoError(t, err)
+	assert.NotEqual(t, response, other)
+
+	_, err = vncAuthResponse("secret", challenge[:8])
+	assert.Error(t, err)
+}
+
+func TestVNCConsole(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	challenge := []byte("0123456789abcdef")
+	want, err := vncAuthResponse("secret", challenge)
+	require.NoError(t, err)
+
+	done := make(chan []byte)
+	go func() {
+		defer server.Close()
+
+		buf := make([]byte, 12)
+		server.Write([]byte(rfbProtocolVersion))
+		io.ReadFull(server, buf)
+		assert.Equal(t, rfbProtocolVersion, string(buf))
+
+		server.Write([]byte{2, 1, rfbSecurityVNCAuth})
+		io.ReadFull(server, buf[:1])
+		assert.Equal(t, byte(rfbSecurityVNCAuth), buf[0])
+
+		server.Write(challenge)
+		response := make([]byte, 16)
+		io.ReadFull(server, response)
+		assert.Equal(t, want, response)
+		binary.Write(server, binary.BigEndian, uint32(0))
+
+		io.ReadFull(server, buf[:1])
+		assert.Equal(t, byte(1), buf[0])
+		server.Write(make([]byte, 20))
+		binary.Write(server, binary.BigEndian, uint32(4))
+		server.Write([]byte("test"))
+
+		event := make([]byte, 8)
+		io.ReadFull(server, event)
+		done <- event
+	}()
+
+	console := &vncConsole{conn: client}
+	require.NoError(t, console.handshake("secret"))
+	require.NoError(t, console.KeyEvent(0xff0d, true))
+	assert.Equal(t, []byte{4, 1, 0, 0, 0, 0, 0xff, 0x0d}, <-done)
+}
+
+func TestVNCConsoleRejected(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	go func() {
+		defer server.Close()
+
+		buf := make([]byte, 12)
+		server.Write([]byte(rfbProtocolVersion))
+		io.ReadFull(server, buf)
+		server.Write([]byte{0})
+		binary.Write(server, binary.BigEndian, uint32(4))
+		server.Write([]byte("busy"))
+	}()
+
+	console := &vncConsole{conn: client}
+	err := console.handshake("secret")
+	assert.EqualError(t, err, "the console refused the connection: busy")
+}
@@ -0,0 +1,89 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// osEndOfLife lists the end-of-life dates of the operating systems offered by
+// Hetzner Cloud, indexed by the os_flavor and os_version of the images.
+var osEndOfLife = map[string]map[string]string{
+	"ubuntu": {
+		"18.04": "2023-05-31",
+		"20.04": "2025-05-31",
+		"22.04": "2027-06-01",
+		"24.04": "2029-05-31",
+	},
+	"debian": {
+		"10": "2024-06-30",
+		"11": "2026-08-31",
+		"12": "2028-06-30",
+		"13": "2030-06-30",
+	},
+	"centos": {
+		"7":        "2024-06-30",
+		"stream-8": "2024-05-31",
+		"stream-9": "2027-05-31",
+	},
+	"rocky": {
+		"8": "2029-05-31",
+		"9": "2032-05-31",
+	},
+	"alma": {
+		"8": "2029-03-01",
+		"9": "2032-05-31",
+	},
+	"fedora": {
+		"39": "2024-11-26",
+		"40": "2025-05-13",
+		"41": "2025-12-15",
+		"42": "2026-05-13",
+	},
+}
+
+// imageEndOfLife returns the end-of-life date of the operating system of the
+// image, if it is known.
+func imageEndOfLife(image *hcloud.Image) (time.Time, bool) {
+	versions, ok := osEndOfLife[image.OSFlavor]
+	if !ok {
+		return time.Time{}, false
+	}
+	date, ok := versions[image.OSVersion]
+	if !ok {
+		return time.Time{}, false
+	}
+	eol, err := time.Parse(time.DateOnly, date)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return eol, true
+}
+
+// checkImageEndOfLife returns a message when the operating system of the image
+// is past its end-of-life, or will reach it within the warning period. The
+// returned bool reports whether the end-of-life was already reached.
+func checkImageEndOfLife(image *hcloud.Image, now time.Time, warningPeriod time.Duration) (string, bool) {
+	eol, ok := imageEndOfLife(image)
+	if !ok {
+		return "", false
+	}
+
+	switch {
+	case !now.Before(eol):
+		return fmt.Sprintf(
+			"The operating system of the image '%d' (%s %s) reached its end-of-life on the %s",
+			image.ID, image.OSFlavor, image.OSVersion, eol.Format(time.DateOnly),
+		), true
+	case now.Add(warningPeriod).After(eol):
+		return fmt.Sprintf(
+			"The operating system of the image '%d' (%s %s) will reach its end-of-life on the %s",
+			image.ID, image.OSFlavor, image.OSVersion, eol.Format(time.DateOnly),
+		), false
+	}
+	return "", false
+}
@@ -0,0 +1,54 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+func TestCheckImageEndOfLife(t *testing.T) {
+	now := time.Date(2025, 10, 1, 0, 0, 0, 0, time.UTC)
+	period := 90 * 24 * time.Hour
+
+	testCases := []struct {
+		name    string
+		image   *hcloud.Image
+		wantMsg string
+		wantEOL bool
+	}{
+		{
+			name:    "unknown flavor",
+			image:   &hcloud.Image{ID: 1, OSFlavor: "unknown", OSVersion: "unknown"},
+			wantMsg: "",
+		},
+		{
+			name:    "supported",
+			image:   &hcloud.Image{ID: 1, OSFlavor: "debian", OSVersion: "12"},
+			wantMsg: "",
+		},
+		{
+			name:    "near end-of-life",
+			image:   &hcloud.Image{ID: 1, OSFlavor: "fedora", OSVersion: "41"},
+			wantMsg: "The operating system of the image '1' (fedora 41) will reach its end-of-life on the 2025-12-15",
+		},
+		{
+			name:    "past end-of-life",
+			image:   &hcloud.Image{ID: 1, OSFlavor: "ubuntu", OSVersion: "20.04"},
+			wantMsg: "The operating system of the image '1' (ubuntu 20.04) reached its end-of-life on the 2025-05-31",
+			wantEOL: true,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			msg, eol := checkImageEndOfLife(tc.image, now, period)
+			assert.Equal(t, tc.wantMsg, msg)
+			assert.Equal(t, tc.wantEOL, eol)
+		})
+	}
+}
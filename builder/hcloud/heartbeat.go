@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// heartbeatUi prints a heartbeat line when nothing was printed for the
+// interval, e.g. while waiting for the snapshot, so CI systems with an
+// inactivity timeout do not abort the build.
+type heartbeatUi struct {
+	packersdk.Ui
+
+	mu      sync.Mutex
+	started time.Time
+	last    time.Time
+	done    chan struct{}
+}
+
+func newHeartbeatUi(ui packersdk.Ui, interval time.Duration) *heartbeatUi {
+	now := time.Now()
+	h := &heartbeatUi{Ui: ui, started: now, last: now, done: make(chan struct{})}
+
+	go func() {
+		ticker := time.NewTicker(interval / 10)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-h.done:
+				return
+			case <-ticker.C:
+				h.mu.Lock()
+				idle := time.Since(h.last) >= interval
+				h.mu.Unlock()
+				if idle {
+					h.Message(fmt.Sprintf("Still running (%s elapsed)", time.Since(h.started).Round(time.Second)))
+				}
+			}
+		}
+	}()
+
+	return h
+}
+
+// Stop stops the heartbeat.
+func (h *heartbeatUi) Stop() {
+	close(h.done)
+}
+
+func (h *heartbeatUi) touch() {
+	h.mu.Lock()
+	h.last = time.Now()
+	h.mu.Unlock()
+}
+
+func (h *heartbeatUi) Say(message string) {
+	h.touch()
+	h.Ui.Say(message)
+}
+
+func (h *heartbeatUi) Sayf(message string, args ...any) {
+	h.touch()
+	h.Ui.Sayf(message, args...)
+}
+
+func (h *heartbeatUi) Message(message string) {
+	h.touch()
+	h.Ui.Message(message)
+}
+
+func (h *heartbeatUi) Error(message string) {
+	h.touch()
+	h.Ui.Error(message)
+}
+
+func (h *heartbeatUi) Errorf(message string, args ...any) {
+	h.touch()
+	h.Ui.Errorf(message, args...)
+}
@@ -0,0 +1,45 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+)
+
+// syncBuffer is a bytes.Buffer safe for concurrent use.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestHeartbeatUi(t *testing.T) {
+	var out syncBuffer
+	ui := newHeartbeatUi(&packersdk.BasicUi{Writer: &out, ErrorWriter: &out}, 50*time.Millisecond)
+	defer ui.Stop()
+
+	ui.Say("Creating snapshot...")
+	assert.Eventually(t, func() bool {
+		return strings.Contains(out.String(), "Still running (")
+	}, time.Second, 10*time.Millisecond)
+	assert.True(t, strings.HasPrefix(out.String(), "Creating snapshot...\n"))
+}
@@ -1,10 +1,19 @@
 package hcloud
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"net"
+	"slices"
+	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/retry"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
 
 // errorHandler is a helper function to reduce the amount of bloat and complexity
@@ -14,8 +23,242 @@ func errorHandler(state multistep.StateBag, ui packersdk.Ui, prefix string, err
 	if prefix != "" {
 		wrappedError = fmt.Errorf("%s: %w", prefix, err)
 	}
+	if details := actionErrorDetails(err); details != "" {
+		wrappedError = fmt.Errorf("%w\nDetails: %s", wrappedError, details)
+	}
+	if hint := apiErrorHint(err); hint != "" {
+		wrappedError = fmt.Errorf("%w\nHint: %s", wrappedError, hint)
+	}
 
 	state.Put(StateError, wrappedError)
 	ui.Error(wrappedError.Error())
 	return multistep.ActionHalt
 }
+
+// apiErrorHints maps the API error codes to actionable remediation hints.
+var apiErrorHints = map[hcloud.ErrorCode]string{
+	hcloud.ErrorCodeUnauthorized: "The token is invalid or was revoked, make sure `token` or " +
+		"the HCLOUD_TOKEN environment variable contain a valid API token.",
+	hcloud.ErrorCodeForbidden: "The token is not allowed to perform this request, make sure " +
+		"the API token has Read & Write permissions.",
+	hcloud.ErrorCodeRateLimitExceeded: "The API rate limit was exceeded, increase `poll_interval` " +
+		"or reduce the number of parallel builds in this project.",
+	hcloud.ErrorCodeResourceUnavailable: "The requested resource is currently not available, " +
+		"try another `location` or `server_type`.",
+	hcloud.ErrorCodePlacementError: "The server could not be placed, try another `location` " +
+		"or `server_type`.",
+	hcloud.ErrorCodeResourceLimitExceeded: "A resource limit of the project was reached, delete " +
+		"unused resources or request a limit increase in the Hetzner Cloud Console.",
+	hcloud.ErrorCodeUniquenessError: "A resource with the same name already exists, change the " +
+		"name or delete the existing resource.",
+	hcloud.ErrorCodeLocked: "Another action is running on the resource, retry the build once " +
+		"it completed.",
+	hcloud.ErrorCodeProtected: "The resource is protected, disable its protection before " +
+		"retrying.",
+	hcloud.ErrorCodeMaintenance: "The API is in maintenance, retry the build later.",
+	hcloud.ErrorCodeInvalidServerType: "The server type is deprecated or does not fit the " +
+		"server, check `server_type` and `upgrade_server_type`.",
+}
+
+// configFieldsError maps the API field names of the request which failed to
+// the matching configuration options, when they differ. The names depend on the
+// request, thus the mapping is given by the step sending it.
+type configFieldsError struct {
+	err    error
+	fields map[string]string
+}
+
+// withConfigFields wraps err with the mapping of the API field names to the
+// configuration options, used in the hint of an invalid input error.
+func withConfigFields(err error, fields map[string]string) error {
+	return &configFieldsError{err: err, fields: fields}
+}
+
+func (e *configFieldsError) Error() string { return e.err.Error() }
+
+func (e *configFieldsError) Unwrap() error { return e.err }
+
+// apiErrorHint returns a remediation hint for the API errors, or an empty
+// string if none is known.
+func apiErrorHint(err error) string {
+	var apiErr hcloud.Error
+	if !errors.As(err, &apiErr) {
+		return ""
+	}
+
+	if apiErr.Code == hcloud.ErrorCodeInvalidInput {
+		details, ok := apiErr.Details.(hcloud.ErrorDetailsInvalidInput)
+		if !ok || len(details.Fields) == 0 {
+			return "The API rejected the request, check the configuration of the build."
+		}
+
+		var configFields map[string]string
+		var fieldsErr *configFieldsError
+		if errors.As(err, &fieldsErr) {
+			configFields = fieldsErr.fields
+		}
+
+		fields := make([]string, 0, len(details.Fields))
+		for _, field := range details.Fields {
+			name := field.Name
+			if configName, ok := configFields[name]; ok {
+				name = configName
+			}
+			fields = append(fields, fmt.Sprintf("`%s` (%s)", name, strings.Join(field.Messages, ", ")))
+		}
+		return fmt.Sprintf("The API rejected the value of %s, check the configuration of the build.", strings.Join(fields, ", "))
+	}
+
+	return apiErrorHints[apiErr.Code]
+}
+
+// actionErrorDetails returns the command and the resources of a failed
+// action, or an empty string if the error is not an action error.
+func actionErrorDetails(err error) string {
+	var actionErr hcloud.ActionError
+	if !errors.As(err, &actionErr) || actionErr.Action() == nil {
+		return ""
+	}
+
+	action := actionErr.Action()
+	resources := make([]string, 0, len(action.Resources))
+	for _, resource := range action.Resources {
+		resources = append(resources, fmt.Sprintf("%s %d", resource.Type, resource.ID))
+	}
+	if len(resources) == 0 {
+		resources = append(resources, "none")
+	}
+	return fmt.Sprintf("action '%s' (id %d) failed with '%s': %s, resources: %s",
+		action.Command, action.ID, actionErr.Code, actionErr.Message, strings.Join(resources, ", "))
+}
+
+// waitForActions waits for all the actions concurrently, instead of one after
+// the other, and reports the combined progress of the actions in the ui.
+func waitForActions(ctx context.Context, client *hcloud.Client, ui packersdk.Ui, actions ...*hcloud.Action) error {
+	progress := make(map[int64]int, len(actions))
+	for _, action := range actions {
+		if action != nil {
+			progress[action.ID] = action.Progress
+		}
+	}
+
+	last := -1
+	return client.Action.WaitForFunc(ctx, func(update *hcloud.Action) error {
+		if update.Status == hcloud.ActionStatusError {
+			return update.Error()
+		}
+
+		if update.Status == hcloud.ActionStatusSuccess {
+			progress[update.ID] = 100
+		} else {
+			progress[update.ID] = update.Progress
+		}
+		if len(progress) < 2 {
+			return nil
+		}
+
+		total, done := 0, 0
+		for _, p := range progress {
+			total += p
+			if p == 100 {
+				done++
+			}
+		}
+		if current := total / len(progress); current != last {
+			last = current
+			ui.Message(fmt.Sprintf("%d/%d actions completed (%d%%)", done, len(progress), current))
+		}
+		return nil
+	}, actions...)
+}
+
+// transientErrorCodes are the API error codes worth retrying.
+var transientErrorCodes = []hcloud.ErrorCode{
+	hcloud.ErrorCodeConflict,
+	hcloud.ErrorCodeLocked,
+	hcloud.ErrorCodeMaintenance,
+	hcloud.ErrorCodeRateLimitExceeded,
+	hcloud.ErrorCodeServiceError,
+	hcloud.ErrorCodeUnknownError,
+}
+
+// transientActionErrorCodes are the error codes of failed actions worth
+// retrying, in addition to the transient API error codes.
+var transientActionErrorCodes = []string{
+	"action_failed",
+	"timeout",
+}
+
+// isTransientError reports whether the error is likely to disappear when the
+// operation is retried: the API and action errors with a transient code, and
+// the network errors. Any other error, e.g. a validation error of the step, is
+// deterministic.
+func isTransientError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var apiErr hcloud.Error
+	if errors.As(err, &apiErr) {
+		return slices.Contains(transientErrorCodes, apiErr.Code)
+	}
+	var actionErr hcloud.ActionError
+	if errors.As(err, &actionErr) {
+		return slices.Contains(transientActionErrorCodes, actionErr.Code) ||
+			slices.Contains(transientErrorCodes, hcloud.ErrorCode(actionErr.Code))
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// retryStep runs the operation of a step, and retries it up to retries times
+// on transient errors. The attempt is passed to the operation, so it can check
+// whether a previous attempt already succeeded before trying again.
+func retryStep(ctx context.Context, c *Config, ui packersdk.Ui, retries int, fn func(ctx context.Context, attempt int) error) error {
+	attempt := 0
+	err := retry.Config{
+		Tries:       retries + 1,
+		ShouldRetry: isTransientError,
+		RetryDelay:  func() time.Duration { return c.StepRetryDelay },
+	}.Run(ctx, func(ctx context.Context) error {
+		if attempt > 0 {
+			ui.Say(fmt.Sprintf("Retrying (%d/%d)...", attempt, retries))
+		}
+		err := fn(ctx, attempt)
+		attempt++
+		return err
+	})
+
+	var exhausted *retry.RetryExhaustedError
+	if errors.As(err, &exhausted) {
+		return exhausted.Err
+	}
+	return err
+}
+
+// listAll fetches all the pages of a listing, using api_page_size as page size
+// and stopping with a warning once api_list_limit results were fetched. The
+// list function must use the given list options.
+func listAll[T any](ctx context.Context, c *Config, ui packersdk.Ui, resource string, opts *hcloud.ListOpts, list func(ctx context.Context) ([]T, *hcloud.Response, error)) ([]T, error) {
+	opts.PerPage = c.APIPageSize
+	opts.Page = 1
+
+	var all []T
+	for {
+		items, resp, err := list(ctx)
+		if err != nil {
+			return nil, err
+		}
+		all = append(all, items...)
+
+		if c.APIListLimit > 0 && len(all) >= c.APIListLimit {
+			ui.Error(fmt.Sprintf("Warning: stopped listing %s after %d results, the results may be incomplete. "+
+				"Increase `api_list_limit` or clean up the project.", resource, c.APIListLimit))
+			return all[:c.APIListLimit], nil
+		}
+
+		if resp == nil || resp.Meta.Pagination == nil || resp.Meta.Pagination.NextPage == 0 {
+			return all, nil
+		}
+		opts.Page = resp.Meta.Pagination.NextPage
+	}
+}
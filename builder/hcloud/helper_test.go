@@ -0,0 +1,180 @@
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestAPIErrorHint(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{
+			name: "not an api error",
+			err:  errors.New("boom"),
+			want: "",
+		},
+		{
+			name: "unknown code",
+			err:  hcloud.Error{Code: hcloud.ErrorCodeNotFound, Message: "not found"},
+			want: "",
+		},
+		{
+			name: "rate limit",
+			err:  hcloud.Error{Code: hcloud.ErrorCodeRateLimitExceeded, Message: "limit reached"},
+			want: "The API rate limit was exceeded, increase `poll_interval` or reduce the number of parallel builds in this project.",
+		},
+		{
+			name: "invalid input",
+			err: hcloud.Error{
+				Code:    hcloud.ErrorCodeInvalidInput,
+				Message: "invalid input in field 'name'",
+				Details: hcloud.ErrorDetailsInvalidInput{
+					Fields: []hcloud.ErrorDetailsInvalidInputField{
+						{Name: "name", Messages: []string{"name is not a valid hostname"}},
+					},
+				},
+			},
+			want: "The API rejected the value of `name` (name is not a valid hostname), check the configuration of the build.",
+		},
+		{
+			name: "invalid input with config fields",
+			err: withConfigFields(hcloud.Error{
+				Code:    hcloud.ErrorCodeInvalidInput,
+				Message: "invalid input in field 'name'",
+				Details: hcloud.ErrorDetailsInvalidInput{
+					Fields: []hcloud.ErrorDetailsInvalidInputField{
+						{Name: "name", Messages: []string{"name is not a valid hostname"}},
+					},
+				},
+			}, serverCreateConfigFields),
+			want: "The API rejected the value of `server_name` (name is not a valid hostname), check the configuration of the build.",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, apiErrorHint(tc.err))
+		})
+	}
+}
+
+func TestActionErrorDetails(t *testing.T) {
+	action := &hcloud.Action{
+		ID:           13,
+		Command:      "create_image",
+		Status:       hcloud.ActionStatusError,
+		ErrorCode:    "image_create_failed",
+		ErrorMessage: "could not create image",
+		Resources: []*hcloud.ActionResource{
+			{ID: 42, Type: hcloud.ActionResourceTypeServer},
+			{ID: 7, Type: hcloud.ActionResourceTypeImage},
+		},
+	}
+
+	assert.Equal(t, "", actionErrorDetails(errors.New("boom")))
+	assert.Equal(t,
+		"action 'create_image' (id 13) failed with 'image_create_failed': could not create image, resources: server 42, image 7",
+		actionErrorDetails(fmt.Errorf("wrapped: %w", action.Error())))
+
+	state := &multistep.BasicStateBag{}
+	errorHandler(state, &packersdk.MockUi{}, "Could not create snapshot", action.Error())
+	assert.EqualError(t, state.Get(StateError).(error),
+		"Could not create snapshot: could not create image (image_create_failed, 13)\n"+
+			"Details: action 'create_image' (id 13) failed with 'image_create_failed': could not create image, resources: server 42, image 7")
+}
+
+func TestWaitForActions(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/actions?id=1&id=2&page=1&sort=status&sort=id",
+			Status: 200,
+			JSONRaw: `{
+				"actions": [
+					{ "id": 1, "status": "success", "progress": 100 },
+					{ "id": 2, "status": "running", "progress": 50 }
+				]
+			}`,
+		},
+		{Method: "GET", Path: "/actions?id=2&page=1&sort=status&sort=id",
+			Status: 200,
+			JSONRaw: `{
+				"actions": [
+					{ "id": 2, "status": "success", "progress": 100 }
+				]
+			}`,
+		},
+	}))
+	defer server.Close()
+	client := hcloud.NewClient(
+		hcloud.WithEndpoint(server.URL),
+		hcloud.WithPollOpts(hcloud.PollOpts{BackoffFunc: hcloud.ConstantBackoff(0)}),
+	)
+
+	var out bytes.Buffer
+	ui := &packersdk.BasicUi{Writer: &out, ErrorWriter: &out}
+	err := waitForActions(context.Background(), client, ui,
+		&hcloud.Action{ID: 1, Status: hcloud.ActionStatusRunning},
+		&hcloud.Action{ID: 2, Status: hcloud.ActionStatusRunning},
+	)
+	assert.NoError(t, err)
+
+	assert.Contains(t, out.String(), "1/2 actions completed (75%)")
+	assert.Contains(t, out.String(), "2/2 actions completed (100%)")
+}
+
+func TestIsTransientError(t *testing.T) {
+	assert.True(t, isTransientError(hcloud.Error{Code: hcloud.ErrorCodeLocked}))
+	assert.True(t, isTransientError(fmt.Errorf("wrapped: %w", hcloud.Error{Code: hcloud.ErrorCodeRateLimitExceeded})))
+	assert.True(t, isTransientError(hcloud.ActionError{Code: "action_failed"}))
+	assert.False(t, isTransientError(hcloud.Error{Code: hcloud.ErrorCodeInvalidInput}))
+	assert.False(t, isTransientError(context.Canceled))
+	assert.False(t, isTransientError(hcloud.ActionError{Code: "image_not_found"}))
+	assert.False(t, isTransientError(fmt.Errorf("network %d not found", 4)))
+	assert.True(t, isTransientError(&net.OpError{Op: "dial", Err: errors.New("connection refused")}))
+}
+
+func TestListAll(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/images?page=1&per_page=2&type=snapshot",
+			Status: 200,
+			JSONRaw: `{
+				"images": [{ "id": 1 }, { "id": 2 }],
+				"meta": { "pagination": { "page": 1, "next_page": 2 }}
+			}`,
+		},
+		{Method: "GET", Path: "/images?page=2&per_page=2&type=snapshot",
+			Status: 200,
+			JSONRaw: `{
+				"images": [{ "id": 3 }, { "id": 4 }],
+				"meta": { "pagination": { "page": 2, "next_page": 3 }}
+			}`,
+		},
+	}))
+	defer server.Close()
+	client := hcloud.NewClient(hcloud.WithEndpoint(server.URL))
+
+	var out bytes.Buffer
+	ui := &packersdk.BasicUi{Writer: &out, ErrorWriter: &out}
+	c := &Config{APIPageSize: 2, APIListLimit: 3}
+
+	opts := hcloud.ImageListOpts{Type: []hcloud.ImageType{hcloud.ImageTypeSnapshot}}
+	images, err := listAll(context.Background(), c, ui, "snapshots", &opts.ListOpts, func(ctx context.Context) ([]*hcloud.Image, *hcloud.Response, error) {
+		return client.Image.List(ctx, opts)
+	})
+	assert.NoError(t, err)
+	assert.Len(t, images, 3)
+	assert.Contains(t, out.String(), "stopped listing snapshots after 3 results")
+}
@@ -0,0 +1,97 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// matchesLabelSelector reports whether the labels match the label selector, as
+// evaluated by the API: all the comma separated requirements must be met.
+func matchesLabelSelector(labels map[string]string, selector string) (bool, error) {
+	for _, requirement := range splitLabelSelector(selector) {
+		requirement = strings.TrimSpace(requirement)
+		if requirement == "" {
+			continue
+		}
+		matched, err := matchesLabelRequirement(labels, requirement)
+		if err != nil {
+			return false, err
+		}
+		if !matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+// splitLabelSelector splits the selector in its requirements, at the commas
+// which are not part of a set of values.
+func splitLabelSelector(selector string) []string {
+	var (
+		requirements []string
+		depth        int
+		start        int
+	)
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				requirements = append(requirements, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(requirements, selector[start:])
+}
+
+func matchesLabelRequirement(labels map[string]string, requirement string) (bool, error) {
+	if key, ok := strings.CutPrefix(requirement, "!"); ok {
+		_, found := labels[strings.TrimSpace(key)]
+		return !found, nil
+	}
+
+	for _, op := range []string{" notin ", " in "} {
+		key, values, ok := strings.Cut(requirement, op)
+		if !ok {
+			continue
+		}
+		values = strings.TrimSpace(values)
+		if !strings.HasPrefix(values, "(") || !strings.HasSuffix(values, ")") {
+			return false, fmt.Errorf("invalid label selector requirement '%s'", requirement)
+		}
+		set := strings.Split(values[1:len(values)-1], ",")
+		for i := range set {
+			set[i] = strings.TrimSpace(set[i])
+		}
+		value, found := labels[strings.TrimSpace(key)]
+		in := found && slices.Contains(set, value)
+		if op == " in " {
+			return in, nil
+		}
+		return !in, nil
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		key, want, ok := strings.Cut(requirement, op)
+		if !ok {
+			continue
+		}
+		value, found := labels[strings.TrimSpace(key)]
+		equal := found && value == strings.TrimSpace(want)
+		if op == "!=" {
+			return !equal, nil
+		}
+		return equal, nil
+	}
+
+	_, found := labels[requirement]
+	return found, nil
+}
@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMatchesLabelSelector(t *testing.T) {
+	labels := map[string]string{"env": "prod", "keep": "", "team": "infra"}
+
+	testCases := []struct {
+		selector string
+		want     bool
+	}{
+		{selector: "keep", want: true},
+		{selector: "missing", want: false},
+		{selector: "!missing", want: true},
+		{selector: "!keep", want: false},
+		{selector: "env=prod", want: true},
+		{selector: "env==prod", want: true},
+		{selector: "env!=prod", want: false},
+		{selector: "env!=dev", want: true},
+		{selector: "env in (dev, prod)", want: true},
+		{selector: "env notin (dev, prod)", want: false},
+		{selector: "env=prod,team in (infra,web),!missing", want: true},
+		{selector: "env=prod,team=web", want: false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.selector, func(t *testing.T) {
+			matched, err := matchesLabelSelector(labels, tc.selector)
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, matched)
+		})
+	}
+
+	_, err := matchesLabelSelector(labels, "env in dev")
+	assert.EqualError(t, err, "invalid label selector requirement 'env in dev'")
+}
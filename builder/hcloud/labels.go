@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// labelValueMaxLength is the maximum length of a label value.
+const labelValueMaxLength = 63
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// The label rules of the API: an optional DNS subdomain prefix, and a name
+// and value of at most 63 alphanumeric, '-', '_' or '.' characters, starting
+// and ending with an alphanumeric character.
+var (
+	validLabelPrefix = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?(\.[a-z0-9]([a-z0-9-]*[a-z0-9])?)*$`)
+	validLabelName   = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9_.-]{0,61}[a-zA-Z0-9])?$`)
+)
+
+// labelPrefixMaxLength is the maximum length of the prefix of a label key.
+const labelPrefixMaxLength = 253
+
+// reservedLabelPrefix is the prefix of the label keys reserved for the API.
+const reservedLabelPrefix = "hetzner.cloud/"
+
+// sanitizeLabelValue converts the text to a valid label value, replacing the
+// invalid characters with underscores and truncating it to the maximum length.
+func sanitizeLabelValue(text string) string {
+	value := invalidLabelValueChars.ReplaceAllString(text, "_")
+	if len(value) > labelValueMaxLength {
+		value = value[:labelValueMaxLength]
+	}
+	// Label values must start and end with an alphanumeric character.
+	return strings.Trim(value, "_.-")
+}
+
+// withLabel sets the label in the labels, allocating the map if needed.
+func withLabel(labels map[string]string, key, value string) map[string]string {
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[key] = value
+	return labels
+}
+
+// inheritLabels copies the inherited labels into the labels, without
+// overriding them. When keys are given, only the labels with these keys are
+// copied.
+func inheritLabels(labels, inherited map[string]string, keys []string) map[string]string {
+	for key, value := range inherited {
+		if len(keys) > 0 && !slices.Contains(keys, key) {
+			continue
+		}
+		if _, ok := labels[key]; ok {
+			continue
+		}
+		labels = withLabel(labels, key, value)
+	}
+	return labels
+}
+
+// validateLabels checks the labels of the option against the label rules of
+// the API, so invalid labels fail before any resource is created.
+func validateLabels(option string, labels map[string]string) []error {
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+
+	var errs []error
+	for _, key := range keys {
+		name := key
+		if prefix, rest, ok := strings.Cut(key, "/"); ok {
+			name = rest
+			switch {
+			case strings.HasPrefix(key, reservedLabelPrefix):
+				errs = append(errs, fmt.Errorf("%s: the label key prefix of '%s' is reserved", option, key))
+			case len(prefix) > labelPrefixMaxLength || !validLabelPrefix.MatchString(prefix):
+				errs = append(errs, fmt.Errorf("%s: the label key prefix of '%s' must be a DNS subdomain", option, key))
+			}
+		}
+		if !validLabelName.MatchString(name) {
+			errs = append(errs, fmt.Errorf(
+				"%s: the label key '%s' must be at most 63 alphanumeric, '-', '_' or '.' characters, starting and ending with an alphanumeric character", option, key))
+		}
+		if value := labels[key]; value != "" && !validLabelName.MatchString(value) {
+			errs = append(errs, fmt.Errorf(
+				"%s: the value '%s' of label '%s' must be at most 63 alphanumeric, '-', '_' or '.' characters, starting and ending with an alphanumeric character", option, value, key))
+		}
+	}
+	return errs
+}
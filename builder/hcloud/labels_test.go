@@ -0,0 +1,67 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeLabelValue(t *testing.T) {
+	assert.Equal(t, "release-1.2", sanitizeLabelValue("release-1.2"))
+	assert.Equal(t, "Fixes_CVE-2024-1234_and_bumps_nginx", sanitizeLabelValue("Fixes CVE-2024-1234 and bumps nginx!"))
+	assert.Equal(t, strings.Repeat("a", 63), sanitizeLabelValue(strings.Repeat("a", 70)))
+}
+
+func TestWithLabel(t *testing.T) {
+	assert.Equal(t, map[string]string{"packer.build_id": "abc"}, withLabel(nil, "packer.build_id", "abc"))
+	assert.Equal(t,
+		map[string]string{"env": "prod", "packer.build_id": "abc"},
+		withLabel(map[string]string{"env": "prod"}, "packer.build_id", "abc"),
+	)
+}
+
+func TestInheritLabels(t *testing.T) {
+	server := map[string]string{"env": "prod", "team": "builds"}
+
+	assert.Nil(t, inheritLabels(nil, nil, nil))
+	assert.Equal(t,
+		map[string]string{"env": "prod", "team": "builds"},
+		inheritLabels(nil, server, nil),
+	)
+	assert.Equal(t,
+		map[string]string{"env": "staging", "team": "builds"},
+		inheritLabels(map[string]string{"env": "staging"}, server, nil),
+	)
+	assert.Equal(t,
+		map[string]string{"team": "builds"},
+		inheritLabels(nil, server, []string{"team"}),
+	)
+}
+
+func TestValidateLabels(t *testing.T) {
+	assert.Empty(t, validateLabels("server_labels", map[string]string{
+		"env":                   "prod",
+		"packer.build_id":       "0190c0de-1234-7abc-8def-0123456789ab",
+		"example.com/team":      "builds",
+		"empty":                 "",
+		strings.Repeat("a", 63): strings.Repeat("b", 63),
+	}))
+
+	errs := validateLabels("server_labels", map[string]string{
+		"-env":                  "prod",
+		"hetzner.cloud/team":    "builds",
+		"Example.com/team":      "builds",
+		"owner":                 "Jane Doe",
+		strings.Repeat("a", 64): "x",
+	})
+	assert.Len(t, errs, 5)
+	assert.ErrorContains(t, errs[0], "server_labels: the label key '-env' must be at most 63")
+	assert.ErrorContains(t, errs[1], "server_labels: the label key prefix of 'Example.com/team' must be a DNS subdomain")
+	assert.ErrorContains(t, errs[2], "server_labels: the label key '"+strings.Repeat("a", 64)+"' must be at most 63")
+	assert.ErrorContains(t, errs[3], "server_labels: the label key prefix of 'hetzner.cloud/team' is reserved")
+	assert.ErrorContains(t, errs[4], "server_labels: the value 'Jane Doe' of label 'owner' must be at most 63")
+}
@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+)
+
+const (
+	namingStyleUUID      = "uuid"
+	namingStyleTimestamp = "timestamp"
+	namingStyleSequence  = "sequence"
+)
+
+// The policies when a server with the server_name already exists.
+const (
+	serverNameConflictError  = "error"
+	serverNameConflictSuffix = "suffix"
+)
+
+// maxServerNameLength is the maximum length of a server name, a hostname.
+const maxServerNameLength = 63
+
+var validNamingPrefix = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9-]{0,29}$`)
+
+// naming configures the names of the transient resources created by the
+// build, so they can be recognized by convention.
+type naming struct {
+	Prefix string `mapstructure:"prefix"`
+	Style  string `mapstructure:"style"`
+
+	sequence int
+}
+
+// resourceName returns a new name for a transient resource of the build:
+//   - uuid: <prefix>-<time ordered uuid>
+//   - timestamp: <prefix>-<yyyymmddhhmmss>-<random>
+//   - sequence: <prefix>-<build>-<n>
+func (c *Config) resourceName() string {
+	if c.Naming == nil {
+		c.Naming = &naming{}
+	}
+	prefix := c.Naming.Prefix
+	if prefix == "" {
+		prefix = "packer"
+	}
+
+	id := uuid.TimeOrderedUUID()
+	switch c.Naming.Style {
+	case namingStyleTimestamp:
+		return fmt.Sprintf("%s-%s-%s", prefix, time.Now().UTC().Format("20060102150405"), id[len(id)-4:])
+	case namingStyleSequence:
+		buildID := c.buildID
+		if buildID == "" {
+			buildID = id
+		}
+		c.Naming.sequence++
+		return fmt.Sprintf("%s-%s-%d", prefix, buildID[len(buildID)-8:], c.Naming.sequence)
+	default:
+		return fmt.Sprintf("%s-%s", prefix, id)
+	}
+}
+
+// shortUUID returns the 8 random characters ending a time ordered uuid.
+func shortUUID() string {
+	id := uuid.TimeOrderedUUID()
+	return id[len(id)-8:]
+}
+
+// suffixedServerName returns the name with a random suffix, truncating the name
+// so the result is a valid hostname.
+func suffixedServerName(name string) string {
+	suffix := "-" + shortUUID()
+	if len(name)+len(suffix) > maxServerNameLength {
+		name = name[:maxServerNameLength-len(suffix)]
+	}
+	return name + suffix
+}
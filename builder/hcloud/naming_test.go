@@ -0,0 +1,47 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResourceName(t *testing.T) {
+	c := &Config{}
+	assert.Regexp(t, `^packer-[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`, c.resourceName())
+
+	c = &Config{Naming: &naming{Prefix: "ci-image", Style: namingStyleTimestamp}}
+	assert.Regexp(t, `^ci-image-\d{14}-[0-9a-f]{4}$`, c.resourceName())
+
+	c = &Config{Naming: &naming{Style: namingStyleSequence}, buildID: "65f1a2b3-1234-5678-9abc-def012345678"}
+	assert.Equal(t, "packer-12345678-1", c.resourceName())
+	assert.Equal(t, "packer-12345678-2", c.resourceName())
+}
+
+func TestDefaultSnapshotName(t *testing.T) {
+	c := &Config{}
+	name, err := c.defaultSnapshotName()
+	assert.NoError(t, err)
+	assert.Regexp(t, `^packer-\d+$`, name)
+
+	t.Setenv("HCLOUD_SNAPSHOT_NAME_TEMPLATE", "ci-{{timestamp}}")
+	name, err = c.defaultSnapshotName()
+	assert.NoError(t, err)
+	assert.Regexp(t, `^ci-\d+$`, name)
+
+	t.Setenv("HCLOUD_SNAPSHOT_NAME_TEMPLATE", "ci-{{unknown}}")
+	_, err = c.defaultSnapshotName()
+	assert.ErrorContains(t, err, "could not render the default snapshot name 'ci-{{unknown}}'")
+}
+
+func TestSuffixedServerName(t *testing.T) {
+	assert.Regexp(t, `^build-[0-9a-f]{8}$`, suffixedServerName("build"))
+
+	name := suffixedServerName(strings.Repeat("a", 63))
+	assert.Len(t, name, maxServerNameLength)
+	assert.Regexp(t, `^a{54}-[0-9a-f]{8}$`, name)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/mitchellh/mapstructure"
+)
+
+// policy enforces the standards of a platform team on the templates: the
+// required labels, the forbidden locations and server types, and the maximum
+// cost of a build.
+type policy struct {
+	RequiredLabels       []string `mapstructure:"required_labels"`
+	ForbiddenLocations   []string `mapstructure:"forbidden_locations"`
+	ForbiddenServerTypes []string `mapstructure:"forbidden_server_types"`
+	MaxBuildCost         float64  `mapstructure:"max_build_cost"`
+
+	// source is where the policy is defined, to be reported in the errors.
+	source string
+}
+
+// loadPolicyFile reads a policy from a JSON file with the options of the
+// policy block.
+func loadPolicyFile(path string) (*policy, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var raw map[string]any
+	if err := json.Unmarshal(content, &raw); err != nil {
+		return nil, fmt.Errorf("could not parse policy file %s: %w", path, err)
+	}
+
+	p := &policy{source: path}
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:      p,
+		ErrorUnused: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := decoder.Decode(raw); err != nil {
+		return nil, fmt.Errorf("invalid policy file %s: %w", path, err)
+	}
+	return p, nil
+}
+
+// check returns the violations of the policy by the configuration which can be
+// verified before the build. The cost is verified once it was estimated.
+func (p *policy) check(c *Config) []error {
+	var errs []error
+	for _, label := range p.RequiredLabels {
+		if _, ok := c.ServerLabels[label]; !ok {
+			errs = append(errs, fmt.Errorf("policy %s: server_labels must contain the label '%s'", p.source, label))
+		}
+		if _, ok := c.SnapshotLabels[label]; !ok {
+			errs = append(errs, fmt.Errorf("policy %s: snapshot_labels must contain the label '%s'", p.source, label))
+		}
+	}
+	return append(errs, p.checkPlacement(c)...)
+}
+
+// checkPlacement returns the violations of the forbidden locations and server
+// types. It runs again once the location of the datacenter and the server type
+// of server_type_spec or server_type_class are resolved, as they are not known
+// before.
+func (p *policy) checkPlacement(c *Config) []error {
+	var errs []error
+	for _, location := range []string{c.Location, c.Datacenter} {
+		if location != "" && slices.Contains(p.ForbiddenLocations, location) {
+			errs = append(errs, fmt.Errorf("policy %s: location '%s' is forbidden", p.source, location))
+		}
+	}
+	for _, serverType := range []string{c.ServerType, c.UpgradeServerType} {
+		if serverType != "" && slices.Contains(p.ForbiddenServerTypes, serverType) {
+			errs = append(errs, fmt.Errorf("policy %s: server type '%s' is forbidden", p.source, serverType))
+		}
+	}
+	return errs
+}
+
+// checkCost returns an error when the estimated cost exceeds the maximum cost
+// of a build.
+func (p *policy) checkCost(estimate *costEstimate) error {
+	if p.MaxBuildCost > 0 && estimate.Cost > p.MaxBuildCost {
+		return fmt.Errorf("policy %s: the estimated cost %.4f %s exceeds the maximum cost of a build %.4f %s",
+			p.source, estimate.Cost, estimate.Currency, p.MaxBuildCost, estimate.Currency)
+	}
+	return nil
+}
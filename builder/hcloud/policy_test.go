@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	p := &policy{
+		RequiredLabels:       []string{"team"},
+		ForbiddenLocations:   []string{"ash"},
+		ForbiddenServerTypes: []string{"ccx63"},
+		source:               "block",
+	}
+
+	c := &Config{
+		Location:       "nbg1",
+		ServerType:     "cpx11",
+		ServerLabels:   map[string]string{"team": "builds"},
+		SnapshotLabels: map[string]string{"team": "builds"},
+	}
+	assert.Empty(t, p.check(c))
+
+	c = &Config{
+		Location:          "ash",
+		ServerType:        "cpx11",
+		UpgradeServerType: "ccx63",
+		ServerLabels:      map[string]string{"team": "builds"},
+	}
+	assert.Equal(t, []string{
+		"policy block: snapshot_labels must contain the label 'team'",
+		"policy block: location 'ash' is forbidden",
+		"policy block: server type 'ccx63' is forbidden",
+	}, errorMessages(p.check(c)))
+}
+
+func TestLoadPolicyFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"required_labels": ["team"], "max_build_cost": 0.5}`), 0o644))
+
+	p, err := loadPolicyFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"team"}, p.RequiredLabels)
+	assert.Equal(t, 0.5, p.MaxBuildCost)
+	assert.Equal(t, path, p.source)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"max_cost": 0.5}`), 0o644))
+	_, err = loadPolicyFile(path)
+	assert.ErrorContains(t, err, "invalid policy file")
+}
+
+func errorMessages(errs []error) []string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Error())
+	}
+	return messages
+}
@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// getLocationDatacenter returns the datacenter of the location, as the
+// Primary IPs are bound to a datacenter.
+func getLocationDatacenter(ctx context.Context, client *hcloud.Client, location string) (*hcloud.Datacenter, error) {
+	datacenters, err := client.Datacenter.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, datacenter := range datacenters {
+		if datacenter.Location != nil && datacenter.Location.Name == location {
+			return datacenter, nil
+		}
+	}
+	return nil, fmt.Errorf("no datacenter found in location '%s'", location)
+}
+
+// createPrimaryIP creates a Primary IP for the build server, labeled with the
+// primary_ip_labels and the build ID. Unless primary_ip_auto_delete is false,
+// it is deleted with the server.
+func createPrimaryIP(ctx context.Context, client *hcloud.Client, c *Config, ipType hcloud.PrimaryIPType, datacenter *hcloud.Datacenter) (*hcloud.PrimaryIP, error) {
+	labels := c.buildLabels()
+	for key, value := range c.PrimaryIPLabels {
+		if key != buildIDLabel {
+			labels[key] = value
+		}
+	}
+
+	result, _, err := client.PrimaryIP.Create(ctx, hcloud.PrimaryIPCreateOpts{
+		Name:         c.resourceName(),
+		Type:         ipType,
+		AssigneeType: "server",
+		Datacenter:   datacenter.Name,
+		AutoDelete:   hcloud.Ptr(c.PrimaryIPAutoDelete == nil || *c.PrimaryIPAutoDelete),
+		Labels:       labels,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Action != nil {
+		if err := client.Action.WaitFor(ctx, result.Action); err != nil {
+			return nil, err
+		}
+	}
+	return result.PrimaryIP, nil
+}
@@ -0,0 +1,80 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/hcl/v2/hclsimple"
+)
+
+// profile holds the environment specific settings of a named profile of the
+// profiles file, so the templates stay free of them.
+type profile struct {
+	Name       string            `hcl:"name,label"`
+	Token      string            `hcl:"token,optional"`
+	TokenEnv   string            `hcl:"token_env,optional"`
+	Endpoint   string            `hcl:"endpoint,optional"`
+	Location   string            `hcl:"location,optional"`
+	ServerType string            `hcl:"server_type,optional"`
+	Labels     map[string]string `hcl:"labels,optional"`
+}
+
+type profilesFile struct {
+	Profiles []profile `hcl:"profile,block"`
+}
+
+// defaultProfilesFile returns the path of the profiles file in the
+// configuration directory of the user.
+func defaultProfilesFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".config", "packer-hcloud.hcl")
+}
+
+// loadProfile reads the profile with the name from the profiles file.
+func loadProfile(path, name string) (*profile, error) {
+	var file profilesFile
+	if err := hclsimple.DecodeFile(path, nil, &file); err != nil {
+		return nil, fmt.Errorf("could not read profiles file %s: %w", path, err)
+	}
+	for i := range file.Profiles {
+		if file.Profiles[i].Name == name {
+			return &file.Profiles[i], nil
+		}
+	}
+	return nil, fmt.Errorf("profile '%s' is not defined in profiles file %s", name, path)
+}
+
+// applyProfile sets the options of the configuration from the profile. The
+// options and labels set in the template take precedence.
+func (c *Config) applyProfile(p *profile) {
+	if c.HCloudToken == "" {
+		c.HCloudToken = p.Token
+		if p.TokenEnv != "" {
+			c.HCloudToken = os.Getenv(p.TokenEnv)
+		}
+	}
+	if c.Endpoint == "" {
+		c.Endpoint = p.Endpoint
+	}
+	if c.Location == "" && c.Datacenter == "" {
+		c.Location = p.Location
+	}
+	if c.ServerType == "" {
+		c.ServerType = p.ServerType
+	}
+	for key, value := range p.Labels {
+		if _, ok := c.ServerLabels[key]; !ok {
+			c.ServerLabels = withLabel(c.ServerLabels, key, value)
+		}
+		if _, ok := c.SnapshotLabels[key]; !ok {
+			c.SnapshotLabels = withLabel(c.SnapshotLabels, key, value)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadProfile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "packer-hcloud.hcl")
+	require.NoError(t, os.WriteFile(path, []byte(`
+profile "staging" {
+  token_env   = "HCLOUD_TOKEN_STAGING"
+  location    = "fsn1"
+  server_type = "cpx11"
+  labels      = { env = "staging" }
+}
+
+profile "production" {
+  endpoint = "https://api.example.com/v1"
+}
+`), 0o644))
+
+	p, err := loadProfile(path, "staging")
+	require.NoError(t, err)
+	assert.Equal(t, "HCLOUD_TOKEN_STAGING", p.TokenEnv)
+	assert.Equal(t, "fsn1", p.Location)
+	assert.Equal(t, "cpx11", p.ServerType)
+	assert.Equal(t, map[string]string{"env": "staging"}, p.Labels)
+
+	_, err = loadProfile(path, "testing")
+	assert.ErrorContains(t, err, "profile 'testing' is not defined")
+
+	require.NoError(t, os.WriteFile(path, []byte(`profile "staging" { region = "eu" }`), 0o644))
+	_, err = loadProfile(path, "staging")
+	assert.ErrorContains(t, err, "could not read profiles file")
+}
+
+func TestApplyProfile(t *testing.T) {
+	t.Setenv("HCLOUD_TOKEN_STAGING", "staging-token")
+
+	p := &profile{
+		TokenEnv:   "HCLOUD_TOKEN_STAGING",
+		Location:   "fsn1",
+		ServerType: "cpx11",
+		Labels:     map[string]string{"env": "staging", "team": "platform"},
+	}
+
+	c := &Config{ServerType: "cx22", ServerLabels: map[string]string{"team": "builds"}}
+	c.applyProfile(p)
+	assert.Equal(t, "staging-token", c.HCloudToken)
+	assert.Equal(t, "fsn1", c.Location)
+	assert.Equal(t, "cx22", c.ServerType)
+	assert.Equal(t, map[string]string{"env": "staging", "team": "builds"}, c.ServerLabels)
+	assert.Equal(t, map[string]string{"env": "staging", "team": "platform"}, c.SnapshotLabels)
+
+	c = &Config{Datacenter: "nbg1-dc3"}
+	c.applyProfile(p)
+	assert.Empty(t, c.Location)
+}
@@ -0,0 +1,181 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/sdk-internals/communicator/ssh"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// rescueUsername is the user of the rescue system.
+const rescueUsername = "root"
+
+// rescueSSHPort is the port the SSH server of the rescue system listens on.
+const rescueSSHPort = 22
+
+// serverShutdownTimeout is how long to wait for a server to shut down.
+const serverShutdownTimeout = 5 * time.Minute
+
+// rebootIntoRescue enables the rescue system on the server and resets it. It is
+// only meant for a freshly created server, which has no data to lose.
+func rebootIntoRescue(ctx context.Context, client *hcloud.Client, server *hcloud.Server, rescue string, sshKeys []*hcloud.SSHKey) error {
+	if _, err := setRescue(ctx, client, server, rescue, sshKeys); err != nil {
+		return fmt.Errorf("could not enable rescue mode: %w", err)
+	}
+
+	action, _, err := client.Server.Reset(ctx, server)
+	if err != nil {
+		return fmt.Errorf("could not reboot server: %w", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return fmt.Errorf("could not reboot server: %w", err)
+	}
+	return nil
+}
+
+// restartIntoRescue enables the rescue system on the provisioned server, shuts
+// it down cleanly and powers it on again. Unlike a reset, the writes of the
+// provisioning are flushed and the filesystems unmounted before the rescue
+// system works on the disk.
+func restartIntoRescue(ctx context.Context, client *hcloud.Client, server *hcloud.Server, rescue string, sshKeys []*hcloud.SSHKey, interval time.Duration) error {
+	if _, err := setRescue(ctx, client, server, rescue, sshKeys); err != nil {
+		return fmt.Errorf("could not enable rescue mode: %w", err)
+	}
+
+	action, _, err := client.Server.Shutdown(ctx, server)
+	if err != nil {
+		return fmt.Errorf("could not shut down server: %w", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return fmt.Errorf("could not shut down server: %w", err)
+	}
+	// The shutdown action completes once the ACPI signal was sent, not once
+	// the server is off
+	if err := waitForServerOff(ctx, client, server.ID, interval); err != nil {
+		return fmt.Errorf("could not shut down server: %w", err)
+	}
+
+	action, _, err = client.Server.Poweron(ctx, server)
+	if err != nil {
+		return fmt.Errorf("could not power on server: %w", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return fmt.Errorf("could not power on server: %w", err)
+	}
+	return nil
+}
+
+// waitForServerOff waits until the server is off, for at most serverShutdownTimeout.
+func waitForServerOff(ctx context.Context, client *hcloud.Client, serverID int64, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, serverShutdownTimeout)
+	defer cancel()
+
+	for {
+		server, _, err := client.Server.GetByID(ctx, serverID)
+		if err != nil {
+			return err
+		}
+		if server == nil {
+			return fmt.Errorf("server %d not found", serverID)
+		}
+		if server.Status == hcloud.ServerStatusOff {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for server %d to be off: %w", serverID, ctx.Err())
+		case <-time.After(interval):
+		}
+	}
+}
+
+// rescueComm is the communicator of the rescue system. It must be closed once
+// the commands have run, as the SSH communicator of the SDK cannot be closed.
+type rescueComm struct {
+	packersdk.Communicator
+	conn net.Conn
+}
+
+// Close closes the connection to the rescue system.
+func (c *rescueComm) Close() error {
+	if c.conn == nil {
+		return nil
+	}
+	return c.conn.Close()
+}
+
+// connectSSH connects to the rescue system of the host with the temporary SSH
+// key, retrying until the SSH timeout of the communicator is reached. The
+// rescue system always listens on port 22, regardless of ssh_port.
+func connectSSH(ctx context.Context, c *Config, host string, username string) (*rescueComm, error) {
+	signer, err := gossh.ParsePrivateKey(c.Comm.SSHPrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("could not parse SSH private key: %w", err)
+	}
+
+	address := net.JoinHostPort(host, strconv.Itoa(rescueSSHPort))
+	rc := &rescueComm{}
+	connect := ssh.ConnectFunc("tcp", address)
+	config := &ssh.Config{
+		Connection: func() (net.Conn, error) {
+			conn, err := connect()
+			rc.conn = conn
+			return conn, err
+		},
+		SSHConfig: &gossh.ClientConfig{
+			User:            username,
+			Auth:            []gossh.AuthMethod{gossh.PublicKeys(signer)},
+			HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+		},
+	}
+
+	timeout := c.Comm.SSHTimeout
+	if timeout == 0 {
+		timeout = 5 * time.Minute
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		comm, err := ssh.New(address, config)
+		if err == nil {
+			rc.Communicator = comm
+			return rc, nil
+		}
+		log.Printf("[DEBUG] SSH connection to %s failed: %s", address, err)
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("timeout waiting for SSH on %s: %w", address, err)
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// runCommands runs the commands one after the other on the communicator, and
+// stops at the first failure.
+func runCommands(ctx context.Context, ui packersdk.Ui, comm packersdk.Communicator, commands []string) error {
+	for _, command := range commands {
+		ui.Message(fmt.Sprintf("Executing: %s", command))
+		cmd := &packersdk.RemoteCmd{Command: command}
+		if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+			return fmt.Errorf("could not execute command '%s': %w", command, err)
+		}
+		if cmd.ExitStatus() != 0 {
+			return fmt.Errorf("command '%s' exited with status %d", command, cmd.ExitStatus())
+		}
+	}
+	return nil
+}
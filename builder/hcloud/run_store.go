@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// runStore persists data shared by the sources of a Packer run on the local
+// disk, as each source of a build runs in a separate plugin process.
+type runStore struct {
+	dir string
+}
+
+// newRunStore returns the store of the current Packer run.
+func newRunStore() *runStore {
+	runID := os.Getenv("PACKER_RUN_UUID")
+	if runID == "" {
+		// All the plugin processes are started by the same Packer process
+		runID = fmt.Sprintf("ppid-%d", os.Getppid())
+	}
+	return &runStore{dir: filepath.Join(os.TempDir(), "packer-hcloud-"+runID)}
+}
+
+// update decodes the named entry into v and applies fn while holding the lock
+// of the store. The entry is written back when fn returns true, and removed
+// otherwise. The directory of the store is removed with its last entry.
+func (s *runStore) update(ctx context.Context, name string, v interface{}, fn func() (bool, error)) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return err
+	}
+
+	unlock, err := s.lock(ctx)
+	if err != nil {
+		return err
+	}
+	// Removing the directory fails as long as another entry or lock exists
+	defer os.Remove(s.dir)
+	defer unlock()
+
+	path := filepath.Join(s.dir, name+".json")
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			return fmt.Errorf("could not read %s: %w", path, err)
+		}
+	}
+
+	keep, err := fn()
+	if err != nil {
+		return err
+	}
+
+	if !keep {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	data, err = json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o600)
+}
+
+// lock creates the lock file of the store, waiting until it is released by
+// other processes.
+func (s *runStore) lock(ctx context.Context) (func(), error) {
+	path := filepath.Join(s.dir, "lock")
+
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	for {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(path) }, nil
+		}
+		if errors.Is(err, os.ErrNotExist) {
+			// The directory was removed along with the last entry of another process
+			if err := os.MkdirAll(s.dir, 0o700); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("could not lock %s: %w", s.dir, ctx.Err())
+		case <-time.After(100 * time.Millisecond):
+		}
+	}
+}
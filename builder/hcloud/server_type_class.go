@@ -0,0 +1,83 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// serverTypeSpec describes the requirements of the server type resolved at
+// build time, set with server_type_spec or server_type_class.
+type serverTypeSpec struct {
+	MinCores     int     `mapstructure:"min_cores"`
+	MinMemory    float32 `mapstructure:"min_memory"`
+	MinDisk      int     `mapstructure:"min_disk"`
+	Architecture string  `mapstructure:"architecture"`
+	CPUType      string  `mapstructure:"cpu_type"`
+}
+
+// String describes the spec in error messages.
+func (s *serverTypeSpec) String() string {
+	description := "server type"
+	if s.CPUType != "" {
+		description = s.CPUType + " " + description
+	}
+	return fmt.Sprintf("%s with at least %d cores, %gGB memory and %dGB disk", description, s.MinCores, s.MinMemory, s.MinDisk)
+}
+
+// resolveServerType returns the cheapest server type matching the spec which
+// is available in one of the datacenters of the location, leaving out the
+// forbidden server types and the ones above the max hourly price, if set.
+func resolveServerType(
+	serverTypes []*hcloud.ServerType, spec *serverTypeSpec, location string,
+	datacenters []*hcloud.Datacenter, forbidden []string, maxHourlyPrice float64,
+) (*hcloud.ServerType, error) {
+	architecture := hcloud.Architecture(spec.Architecture)
+	if architecture == "" {
+		architecture = hcloud.ArchitectureX86
+	}
+
+	var (
+		resolved *hcloud.ServerType
+		cheapest float64
+	)
+	for _, serverType := range serverTypes {
+		if (spec.CPUType != "" && serverType.CPUType != hcloud.CPUType(spec.CPUType)) ||
+			serverType.Architecture != architecture ||
+			serverType.Cores < spec.MinCores ||
+			serverType.Memory < spec.MinMemory ||
+			serverType.Disk < spec.MinDisk ||
+			serverType.IsDeprecated() ||
+			slices.Contains(forbidden, serverType.Name) ||
+			checkServerTypeAvailable(datacenters, serverType, location) != nil {
+			continue
+		}
+
+		for _, pricing := range serverType.Pricings {
+			if pricing.Location == nil || pricing.Location.Name != location {
+				continue
+			}
+			hourly, err := strconv.ParseFloat(pricing.Hourly.Gross, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid hourly price '%s': %w", pricing.Hourly.Gross, err)
+			}
+			if maxHourlyPrice > 0 && hourly > maxHourlyPrice {
+				continue
+			}
+			if resolved == nil || hourly < cheapest {
+				resolved, cheapest = serverType, hourly
+			}
+		}
+	}
+	if resolved == nil {
+		return nil, fmt.Errorf(
+			"no %s is available for the architecture '%s' in location '%s'",
+			spec, architecture, location)
+	}
+	return resolved, nil
+}
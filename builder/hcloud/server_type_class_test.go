@@ -0,0 +1,120 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+func TestResolveServerType(t *testing.T) {
+	pricing := func(location, hourly string) hcloud.ServerTypeLocationPricing {
+		return hcloud.ServerTypeLocationPricing{
+			Location: &hcloud.Location{Name: location},
+			Hourly:   hcloud.Price{Gross: hourly},
+		}
+	}
+	serverTypes := []*hcloud.ServerType{
+		{ID: 1, Name: "cx22", CPUType: hcloud.CPUTypeShared, Architecture: hcloud.ArchitectureX86, Cores: 2, Memory: 4, Disk: 40,
+			Pricings: []hcloud.ServerTypeLocationPricing{pricing("nbg1", "0.0060")}},
+		{ID: 2, Name: "cpx21", CPUType: hcloud.CPUTypeShared, Architecture: hcloud.ArchitectureX86, Cores: 3, Memory: 4, Disk: 80,
+			Pricings: []hcloud.ServerTypeLocationPricing{pricing("nbg1", "0.0130")}},
+		{ID: 3, Name: "cx32", CPUType: hcloud.CPUTypeShared, Architecture: hcloud.ArchitectureX86, Cores: 4, Memory: 8, Disk: 80,
+			Pricings: []hcloud.ServerTypeLocationPricing{pricing("fsn1", "0.0110")}},
+		{ID: 4, Name: "cax21", CPUType: hcloud.CPUTypeShared, Architecture: hcloud.ArchitectureARM, Cores: 4, Memory: 8, Disk: 40,
+			Pricings: []hcloud.ServerTypeLocationPricing{pricing("nbg1", "0.0110")}},
+		{ID: 5, Name: "ccx13", CPUType: hcloud.CPUTypeDedicated, Architecture: hcloud.ArchitectureX86, Cores: 2, Memory: 8, Disk: 80,
+			Pricings: []hcloud.ServerTypeLocationPricing{pricing("nbg1", "0.0240")}},
+	}
+
+	datacenter := &hcloud.Datacenter{
+		Name:     "nbg1-dc3",
+		Location: &hcloud.Location{Name: "nbg1"},
+	}
+	datacenter.ServerTypes.Supported = serverTypes
+
+	testCases := []struct {
+		name           string
+		spec           serverTypeSpec
+		forbidden      []string
+		unavailable    string
+		maxHourlyPrice float64
+		want           string
+		err            string
+	}{
+		{
+			name: "cheapest shared",
+			spec: serverTypeSpec{CPUType: "shared"},
+			want: "cx22",
+		},
+		{
+			name: "min cores",
+			spec: serverTypeSpec{CPUType: "shared", MinCores: 3},
+			want: "cpx21",
+		},
+		{
+			name: "arm",
+			spec: serverTypeSpec{CPUType: "shared", Architecture: "arm"},
+			want: "cax21",
+		},
+		{
+			name: "dedicated",
+			spec: serverTypeSpec{CPUType: "dedicated"},
+			want: "ccx13",
+		},
+		{
+			name: "none available",
+			spec: serverTypeSpec{CPUType: "shared", MinMemory: 16},
+			err:  "no shared server type with at least 0 cores, 16GB memory and 0GB disk is available for the architecture 'x86' in location 'nbg1'",
+		},
+		{
+			name: "min disk",
+			spec: serverTypeSpec{CPUType: "shared", MinDisk: 60},
+			want: "cpx21",
+		},
+		{
+			name: "any cpu type",
+			spec: serverTypeSpec{MinMemory: 8},
+			want: "ccx13",
+		},
+		{
+			name:      "forbidden",
+			spec:      serverTypeSpec{CPUType: "shared"},
+			forbidden: []string{"cx22"},
+			want:      "cpx21",
+		},
+		{
+			name:        "unavailable",
+			spec:        serverTypeSpec{CPUType: "shared"},
+			unavailable: "cx22",
+			want:        "cpx21",
+		},
+		{
+			name:           "max hourly price",
+			spec:           serverTypeSpec{MinMemory: 8},
+			maxHourlyPrice: 0.02,
+			err:            "no server type with at least 0 cores, 8GB memory and 0GB disk is available for the architecture 'x86' in location 'nbg1'",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			dc := *datacenter
+			dc.ServerTypes.Available = slices.DeleteFunc(slices.Clone(serverTypes), func(serverType *hcloud.ServerType) bool {
+				return serverType.Name == tc.unavailable
+			})
+			serverType, err := resolveServerType(serverTypes, &tc.spec, "nbg1", []*hcloud.Datacenter{&dc}, tc.forbidden, tc.maxHourlyPrice)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.want, serverType.Name)
+		})
+	}
+}
@@ -15,16 +15,33 @@ const (
 	StateError  = "error"
 
 	StateHCloudClient = "hcloud_client"
-
-	StateGeneratedData = "generated_data"
-	StateInstanceID    = "instance_id"
-	StateServerID      = "server_id"
-	StateServerIP      = "server_ip"
-	StateServerType    = "server_type"
-	StateSnapshotID    = "snapshot_id"
-	StateSnapshotIDOld = "snapshot_id_old"
-	StateSnapshotName  = "snapshot_name"
-	StateSSHKeyID      = "ssh_key_id"
+	StateCommunicator = "communicator"
+
+	StateGeneratedData  = "generated_data"
+	StateInstanceID     = "instance_id"
+	StateServerCreated  = "server_created"
+	StateServerID       = "server_id"
+	StateServerIP       = "server_ip"
+	StateServerMetadata = "server_metadata"
+	StateServerMetrics  = "server_metrics"
+	StateCostEstimate   = "cost_estimate"
+	StateServerType     = "server_type"
+	StateSnapshotID     = "snapshot_id"
+	StateSnapshotIDOld  = "snapshot_id_old"
+	StateSnapshotName   = "snapshot_name"
+	StateSSHKeyID       = "ssh_key_id"
+	StateSSHKeysPublic  = "ssh_keys_public"
+	StateRescueActive   = "rescue_active"
+
+	StateAuthorizedKeys = "authorized_keys"
+
+	StateTemporaryNetworkID = "temporary_network_id"
+	StatePlacementGroupID   = "placement_group_id"
+	StateVolumeIDs          = "volume_ids"
+	StateScratchVolume      = "scratch_volume"
+	StatePrimaryIPIDs       = "primary_ip_ids"
+
+	StateTemporaryFirewallID = "temporary_firewall_id"
 
 	StateSourceImageID = "source_image_id"
 )
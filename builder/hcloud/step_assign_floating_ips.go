@@ -0,0 +1,121 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepAssignFloatingIPs assigns existing Floating IPs to the build server for
+// the duration of the build, so the provisioners can validate the services
+// bound to them.
+type stepAssignFloatingIPs struct {
+	// floatingIPs are the Floating IPs assigned to the server, unassigned in
+	// cleanup.
+	floatingIPs []*hcloud.FloatingIP
+}
+
+func (s *stepAssignFloatingIPs) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+	generatedData.Put("FloatingIPs", []string{})
+
+	if len(c.FloatingIPs) == 0 {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	floatingIPs := make([]*hcloud.FloatingIP, 0, len(c.FloatingIPs))
+	for _, floatingIP := range c.FloatingIPs {
+		matches, msg, err := getFloatingIPs(ctx, client, floatingIP)
+		if err != nil {
+			return errorHandler(state, ui, msg, err)
+		}
+		floatingIPs = append(floatingIPs, matches...)
+	}
+
+	addresses := make([]string, 0, len(floatingIPs))
+	for _, floatingIP := range floatingIPs {
+		// Assigning an assigned Floating IP would take it away from its server
+		if floatingIP.Server != nil && floatingIP.Server.ID != serverID {
+			return errorHandler(state, ui, "", fmt.Errorf(
+				"Floating ip %s (ID: %d) is assigned to server %d", floatingIP.IP, floatingIP.ID, floatingIP.Server.ID))
+		}
+
+		ui.Say(fmt.Sprintf("Assigning floating ip %s...", floatingIP.IP))
+		action, _, err := client.FloatingIP.Assign(ctx, floatingIP, &hcloud.Server{ID: serverID})
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not assign floating ip %s", floatingIP.IP), err)
+		}
+
+		// We use this in cleanup
+		s.floatingIPs = append(s.floatingIPs, floatingIP)
+
+		if err := client.Action.WaitFor(ctx, action); err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not assign floating ip %s", floatingIP.IP), err)
+		}
+		addresses = append(addresses, floatingIP.IP.String())
+	}
+	generatedData.Put("FloatingIPs", addresses)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepAssignFloatingIPs) Cleanup(state multistep.StateBag) {
+	if len(s.floatingIPs) == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if c.KeepServer {
+		ui.Say("Keeping floating ips assigned to the kept server")
+		return
+	}
+
+	for _, floatingIP := range s.floatingIPs {
+		ui.Say(fmt.Sprintf("Unassigning floating ip %s...", floatingIP.IP))
+		action, _, err := client.FloatingIP.Unassign(context.TODO(), floatingIP)
+		if err == nil {
+			err = client.Action.WaitFor(context.TODO(), action)
+		}
+		if err != nil {
+			errorHandler(state, ui, fmt.Sprintf("Could not unassign floating ip %s (please unassign it manually)", floatingIP.IP), err)
+		}
+	}
+}
+
+// getFloatingIPs returns the Floating IP with the ID or the name, or all the
+// Floating IPs matched by the label selector.
+func getFloatingIPs(ctx context.Context, client *hcloud.Client, floatingIP string) ([]*hcloud.FloatingIP, string, error) {
+	if !isLabelSelector(floatingIP) {
+		hcloudFloatingIP, _, err := client.FloatingIP.Get(ctx, floatingIP)
+		if err != nil {
+			return nil, fmt.Sprintf("Could not fetch floating ip '%s'", floatingIP), err
+		}
+		if hcloudFloatingIP == nil {
+			return nil, "", fmt.Errorf("Could not find floating ip '%s'", floatingIP)
+		}
+		return []*hcloud.FloatingIP{hcloudFloatingIP}, "", nil
+	}
+
+	matches, err := client.FloatingIP.AllWithOpts(ctx, hcloud.FloatingIPListOpts{
+		ListOpts: hcloud.ListOpts{LabelSelector: floatingIP},
+	})
+	if err != nil {
+		return nil, fmt.Sprintf("Could not fetch floating ips matching '%s'", floatingIP), err
+	}
+	if len(matches) == 0 {
+		return nil, "", fmt.Errorf("Could not find floating ips matching '%s'", floatingIP)
+	}
+	return matches, "", nil
+}
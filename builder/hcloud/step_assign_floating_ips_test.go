@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepAssignFloatingIPs(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepAssignFloatingIPs{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				generatedData := state.Get(StateGeneratedData).(map[string]interface{})
+				assert.Equal(t, []string{}, generatedData["FloatingIPs"])
+			},
+		},
+		{
+			Name: "happy with id and label selector",
+			Step: &stepAssignFloatingIPs{},
+			SetupConfigFunc: func(c *Config) {
+				c.FloatingIPs = []string{"5", "role=ingress"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/floating_ips/5",
+					Status: 200,
+					JSONRaw: `{
+						"floating_ip": { "id": 5, "ip": "203.0.113.5", "type": "ipv4" }
+					}`,
+				},
+				{Method: "GET", Path: "/floating_ips?label_selector=role%3Dingress&page=1",
+					Status: 200,
+					JSONRaw: `{
+						"floating_ips": [{ "id": 6, "ip": "203.0.113.6", "type": "ipv4" }],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "POST", Path: "/floating_ips/5/actions/assign",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.FloatingIPActionAssignRequest{})
+						assert.Equal(t, int64(8), payload.Server)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "POST", Path: "/floating_ips/6/actions/assign",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "success" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				generatedData := state.Get(StateGeneratedData).(map[string]interface{})
+				assert.Equal(t, []string{"203.0.113.5", "203.0.113.6"}, generatedData["FloatingIPs"])
+			},
+		},
+		{
+			Name: "fail with floating ip assigned to another server",
+			Step: &stepAssignFloatingIPs{},
+			SetupConfigFunc: func(c *Config) {
+				c.FloatingIPs = []string{"ingress"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/floating_ips?name=ingress",
+					Status: 200,
+					JSONRaw: `{
+						"floating_ips": [{ "id": 5, "ip": "203.0.113.5", "type": "ipv4", "server": 42 }]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "Floating ip 203.0.113.5 (ID: 5) is assigned to server 42")
+			},
+		},
+	})
+}
+
+func TestStepCleanupFloatingIPs(t *testing.T) {
+	floatingIPs := []*hcloud.FloatingIP{{ID: 5}}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepAssignFloatingIPs{floatingIPs: floatingIPs},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/floating_ips/5/actions/unassign",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+			},
+		},
+		{
+			Name:         "kept server",
+			Step:         &stepAssignFloatingIPs{floatingIPs: floatingIPs},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
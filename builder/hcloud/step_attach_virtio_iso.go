@@ -0,0 +1,90 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// virtioISOPrefix is the name prefix of the public VirtIO driver ISOs.
+const virtioISOPrefix = "virtio-win"
+
+// stepAttachVirtIOISO attaches the latest public VirtIO driver ISO to the
+// server, so an unattended Windows installation can load the drivers for the
+// KVM disk and network devices.
+type stepAttachVirtIOISO struct {
+	attached bool
+}
+
+func (s *stepAttachVirtIOISO) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.VirtIOISO {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+	serverType := state.Get(StateServerType).(*hcloud.ServerType)
+
+	isos, err := client.ISO.AllWithOpts(ctx, hcloud.ISOListOpts{
+		Architecture: []hcloud.Architecture{serverType.Architecture},
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not list ISOs", err)
+	}
+
+	// The latest ISO has the highest id, the version in the name does not sort
+	var iso *hcloud.ISO
+	for _, candidate := range isos {
+		if candidate.Type != hcloud.ISOTypePublic ||
+			!strings.HasPrefix(candidate.Name, virtioISOPrefix) ||
+			candidate.IsDeprecated() {
+			continue
+		}
+		if iso == nil || candidate.ID > iso.ID {
+			iso = candidate
+		}
+	}
+	if iso == nil {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not find a VirtIO driver ISO for the architecture '%s'", serverType.Architecture))
+	}
+
+	ui.Say(fmt.Sprintf("Attaching VirtIO driver ISO '%s'...", iso.Name))
+	action, _, err := client.Server.AttachISO(ctx, &hcloud.Server{ID: serverID}, iso)
+	if err != nil {
+		return errorHandler(state, ui, "Could not attach ISO", err)
+	}
+	s.attached = true
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not attach ISO", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepAttachVirtIOISO) Cleanup(state multistep.StateBag) {
+	c, ui, client := UnpackState(state)
+
+	// The ISO is gone with the deleted server
+	if !s.attached || !c.KeepServer {
+		return
+	}
+
+	ctx := context.TODO()
+	serverID := state.Get(StateServerID).(int64)
+
+	action, _, err := client.Server.DetachISO(ctx, &hcloud.Server{ID: serverID})
+	if err == nil {
+		err = client.Action.WaitFor(ctx, action)
+	}
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not detach ISO: %s", err))
+	}
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepAttachVirtIOISO(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepAttachVirtIOISO{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepAttachVirtIOISO{},
+			SetupConfigFunc: func(c *Config) {
+				c.VirtIOISO = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/isos?architecture=x86&page=1",
+					Status: 200,
+					JSONRaw: `{
+						"isos": [
+							{ "id": 1, "name": "virtio-win-0.1.96", "type": "public" },
+							{ "id": 5, "name": "virtio-win-0.1.248", "type": "public" },
+							{ "id": 6, "name": "virtio-win-custom", "type": "private" },
+							{ "id": 7, "name": "debian-12.iso", "type": "public" }
+						]
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/attach_iso",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionAttachISORequest{})
+						assert.Equal(t, int64(5), payload.ISO.ID)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail no iso",
+			Step: &stepAttachVirtIOISO{},
+			SetupConfigFunc: func(c *Config) {
+				c.VirtIOISO = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cax11", Architecture: "arm"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/isos?architecture=arm&page=1",
+					Status:  200,
+					JSONRaw: `{ "isos": [] }`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "Could not find a VirtIO driver ISO for the architecture 'arm'")
+			},
+		},
+	})
+}
@@ -0,0 +1,81 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepCaptureServerMetadata captures the description of the server right before
+// the snapshot is taken, so audits can reconstruct which machine produced the
+// image.
+type stepCaptureServerMetadata struct{}
+
+func (s *stepCaptureServerMetadata) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if c.SkipSnapshot {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	server, _, err := client.Server.GetByID(ctx, serverID)
+	if err != nil {
+		return errorHandler(state, ui, "Could not fetch server", err)
+	}
+	if server == nil {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not find server '%d'", serverID))
+	}
+
+	state.Put(StateServerMetadata, serverMetadata(server))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCaptureServerMetadata) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func serverMetadata(server *hcloud.Server) map[string]interface{} {
+	metadata := map[string]interface{}{
+		"id":      server.ID,
+		"name":    server.Name,
+		"created": server.Created,
+		"labels":  server.Labels,
+	}
+	if server.ServerType != nil {
+		metadata["server_type"] = server.ServerType.Name
+		metadata["architecture"] = string(server.ServerType.Architecture)
+	}
+	if server.Datacenter != nil {
+		metadata["datacenter"] = server.Datacenter.Name
+		if server.Datacenter.Location != nil {
+			metadata["location"] = server.Datacenter.Location.Name
+		}
+	}
+	if server.Image != nil {
+		metadata["image_id"] = server.Image.ID
+		metadata["image_name"] = server.Image.Name
+	}
+
+	networks := make([]map[string]interface{}, 0, len(server.PrivateNet))
+	for _, privateNet := range server.PrivateNet {
+		network := map[string]interface{}{
+			"ip": privateNet.IP.String(),
+		}
+		if privateNet.Network != nil {
+			network["id"] = privateNet.Network.ID
+		}
+		networks = append(networks, network)
+	}
+	metadata["networks"] = networks
+
+	return metadata
+}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepCaptureServerMetadata(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "happy",
+			Step: &stepCaptureServerMetadata{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": {
+							"id": 8,
+							"name": "dummy-server",
+							"labels": { "key": "value" },
+							"server_type": { "id": 9, "name": "cpx11", "architecture": "x86" },
+							"datacenter": { "id": 2, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" } },
+							"image": { "id": 114690387, "name": "debian-12" },
+							"private_net": [{ "network": 12, "ip": "10.0.0.2" }]
+						}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				metadata, ok := state.Get(StateServerMetadata).(map[string]interface{})
+				assert.True(t, ok)
+				assert.Equal(t, "cpx11", metadata["server_type"])
+				assert.Equal(t, "x86", metadata["architecture"])
+				assert.Equal(t, "nbg1-dc3", metadata["datacenter"])
+				assert.Equal(t, "nbg1", metadata["location"])
+				assert.Equal(t, int64(114690387), metadata["image_id"])
+				assert.Equal(t, map[string]string{"key": "value"}, metadata["labels"])
+				assert.Equal(t, []map[string]interface{}{{"id": int64(12), "ip": "10.0.0.2"}}, metadata["networks"])
+			},
+		},
+		{
+			Name: "skip snapshot",
+			Step: &stepCaptureServerMetadata{},
+			SetupConfigFunc: func(c *Config) {
+				c.SkipSnapshot = true
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
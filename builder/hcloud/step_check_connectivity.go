@@ -0,0 +1,76 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const (
+	ipFamilyIPv4 = "ipv4"
+	ipFamilyIPv6 = "ipv6"
+)
+
+// stepCheckConnectivity probes the outbound connectivity of the server before
+// provisioning, so builds without a route to the internet, e.g. IPv6 only
+// servers fetching from IPv4 only hosts, fail early with a clear error.
+type stepCheckConnectivity struct{}
+
+func (s *stepCheckConnectivity) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.ConnectivityCheckURL == "" {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	family := c.ConnectivityCheckIPFamily
+	if family == "" {
+		family = "any IP family"
+	}
+	ui.Say(fmt.Sprintf("Checking outbound connectivity to %s over %s...", c.ConnectivityCheckURL, family))
+
+	var output strings.Builder
+	cmd := &packersdk.RemoteCmd{
+		Command: connectivityCheckCommand(c.ConnectivityCheckURL, c.ConnectivityCheckIPFamily),
+		Stdout:  &output,
+		Stderr:  &output,
+	}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return errorHandler(state, ui, "Could not check outbound connectivity", err)
+	}
+	if cmd.Wait() != 0 {
+		return errorHandler(state, ui, "", fmt.Errorf(
+			"No outbound connectivity to %s over %s from the server: %s",
+			c.ConnectivityCheckURL, family, strings.TrimSpace(output.String())))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckConnectivity) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// connectivityCheckCommand returns a shell command fetching the url with curl,
+// or wget when curl is not installed.
+func connectivityCheckCommand(url, family string) string {
+	flag := ""
+	switch family {
+	case ipFamilyIPv4:
+		flag = " -4"
+	case ipFamilyIPv6:
+		flag = " -6"
+	}
+	return fmt.Sprintf(
+		"if command -v curl >/dev/null; then curl -fsS -o /dev/null --max-time 10%[1]s '%[2]s'; "+
+			"else wget -q -O /dev/null -T 10%[1]s '%[2]s'; fi",
+		flag, url)
+}
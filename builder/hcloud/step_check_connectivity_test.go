@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepCheckConnectivity(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCheckConnectivity{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepCheckConnectivity{},
+			SetupConfigFunc: func(c *Config) {
+				c.ConnectivityCheckURL = "https://example.com"
+				c.ConnectivityCheckIPFamily = "ipv6"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				comm := state.Get(StateCommunicator).(*packersdk.MockCommunicator)
+				assert.Equal(t,
+					"if command -v curl >/dev/null; then curl -fsS -o /dev/null --max-time 10 -6 'https://example.com'; "+
+						"else wget -q -O /dev/null -T 10 -6 'https://example.com'; fi",
+					comm.StartCmd.Command)
+			},
+		},
+		{
+			Name: "fail",
+			Step: &stepCheckConnectivity{},
+			SetupConfigFunc: func(c *Config) {
+				c.ConnectivityCheckURL = "https://example.com"
+				c.ConnectivityCheckIPFamily = "ipv4"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{
+					StartExitStatus: 7,
+					StartStderr:     "curl: (7) Couldn't connect to server\n",
+				})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "No outbound connectivity to https://example.com over ipv4 from the server: curl: (7) Couldn't connect to server")
+			},
+		},
+	})
+}
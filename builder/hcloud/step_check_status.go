@@ -0,0 +1,118 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// The policies for the ongoing incidents affecting the location.
+const (
+	statusCheckWarn = "warn"
+	statusCheckFail = "fail"
+)
+
+// statusPageMaxSize is the maximum size of the status page response.
+const statusPageMaxSize = 1 << 20
+
+// statusIncident is an unresolved incident, as listed by the
+// incidents/unresolved.json endpoint of a Statuspage compatible status page.
+type statusIncident struct {
+	Name       string `json:"name"`
+	Status     string `json:"status"`
+	Impact     string `json:"impact"`
+	Components []struct {
+		Name string `json:"name"`
+	} `json:"components"`
+}
+
+// stepCheckStatus checks the status page for ongoing incidents affecting the
+// location of the build, before the build and before the snapshot, to warn or
+// stop early instead of running a long build doomed by an outage.
+type stepCheckStatus struct {
+	// Stage is the stage of the build, for the messages.
+	Stage string
+}
+
+func (s *stepCheckStatus) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.StatusCheck == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Checking the status page for incidents in %s %s...", c.Location, s.Stage))
+	incidents, err := fetchIncidents(ctx, c.StatusURL)
+	if err != nil {
+		// An unavailable status page must not fail the build
+		ui.Errorf("Could not check the status page: %s", err)
+		return multistep.ActionContinue
+	}
+
+	affecting := incidentsAffecting(incidents, c.Location)
+	if len(affecting) == 0 {
+		return multistep.ActionContinue
+	}
+	for _, incident := range affecting {
+		ui.Errorf("Ongoing incident in %s: %s (%s, impact %s)", c.Location, incident.Name, incident.Status, incident.Impact)
+	}
+	if c.StatusCheck == statusCheckFail {
+		return errorHandler(state, ui, "", fmt.Errorf("%d ongoing incidents affect location %s", len(affecting), c.Location))
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepCheckStatus) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// fetchIncidents returns the unresolved incidents listed by the status page.
+func fetchIncidents(ctx context.Context, url string) ([]statusIncident, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	var page struct {
+		Incidents []statusIncident `json:"incidents"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, statusPageMaxSize)).Decode(&page); err != nil {
+		return nil, fmt.Errorf("%s returned an invalid response: %w", url, err)
+	}
+	return page.Incidents, nil
+}
+
+// incidentsAffecting returns the incidents naming the location in their name
+// or in one of their components.
+func incidentsAffecting(incidents []statusIncident, location string) []statusIncident {
+	var affecting []statusIncident
+	for _, incident := range incidents {
+		names := []string{incident.Name}
+		for _, component := range incident.Components {
+			names = append(names, component.Name)
+		}
+		for _, name := range names {
+			if strings.Contains(strings.ToLower(name), strings.ToLower(location)) {
+				affecting = append(affecting, incident)
+				break
+			}
+		}
+	}
+	return affecting
+}
@@ -0,0 +1,78 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepCheckStatus(t *testing.T) {
+	status := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprint(w, `{
+			"incidents": [
+				{ "name": "Network degradation", "status": "investigating", "impact": "major",
+				  "components": [{ "name": "Cloud NBG1" }] },
+				{ "name": "Console unavailable in hel1", "status": "monitoring", "impact": "minor" }
+			]
+		}`)
+	}))
+	defer status.Close()
+
+	unavailable := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer unavailable.Close()
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCheckStatus{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "warn",
+			Step: &stepCheckStatus{},
+			SetupConfigFunc: func(c *Config) {
+				c.StatusCheck = statusCheckWarn
+				c.StatusURL = status.URL
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail",
+			Step: &stepCheckStatus{},
+			SetupConfigFunc: func(c *Config) {
+				c.StatusCheck = statusCheckFail
+				c.StatusURL = status.URL
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "1 ongoing incidents affect location nbg1")
+			},
+		},
+		{
+			Name: "status page unavailable",
+			Step: &stepCheckStatus{},
+			SetupConfigFunc: func(c *Config) {
+				c.StatusCheck = statusCheckFail
+				c.StatusURL = unavailable.URL
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
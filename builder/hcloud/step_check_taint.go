@@ -0,0 +1,61 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// taintExitStatus is the exit status of the taint check when the taint file
+// exists.
+const taintExitStatus = 3
+
+// stepCheckTaint fails the build before the snapshot when the provisioners
+// left the taint_file, giving the validation scripts run on the server a way
+// to veto the publication of the image. The content of the file is the reason.
+type stepCheckTaint struct{}
+
+func (s *stepCheckTaint) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.TaintFile == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Checking the taint file %s...", c.TaintFile))
+
+	command := fmt.Sprintf("if [ -e %[1]s ]; then cat %[1]s; exit %[2]d; fi", c.TaintFile, taintExitStatus)
+	if c.Comm.SSHUsername != "root" {
+		command = fmt.Sprintf("sudo -n sh -c '%s'", command)
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+	var stdout bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &stdout}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return errorHandler(state, ui, "Could not check the taint file", err)
+	}
+	switch status := cmd.Wait(); status {
+	case 0:
+		return multistep.ActionContinue
+	case taintExitStatus:
+		reason := strings.TrimSpace(stdout.String())
+		if reason == "" {
+			reason = "no reason given"
+		}
+		return errorHandler(state, ui, "", fmt.Errorf("The server was tainted by the provisioning, skipping the snapshot: %s", reason))
+	default:
+		return errorHandler(state, ui, "", fmt.Errorf("Could not check the taint file: exit status %d", status))
+	}
+}
+
+func (s *stepCheckTaint) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
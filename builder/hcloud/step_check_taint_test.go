@@ -0,0 +1,65 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepCheckTaint(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCheckTaint{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "not tainted",
+			Step: &stepCheckTaint{},
+			SetupConfigFunc: func(c *Config) {
+				c.TaintFile = "/run/packer-taint"
+				c.Comm.SSHUsername = "admin"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				comm := state.Get(StateCommunicator).(*packersdk.MockCommunicator)
+				assert.Equal(t,
+					"sudo -n sh -c 'if [ -e /run/packer-taint ]; then cat /run/packer-taint; exit 3; fi'",
+					comm.StartCmd.Command)
+			},
+		},
+		{
+			Name: "tainted",
+			Step: &stepCheckTaint{},
+			SetupConfigFunc: func(c *Config) {
+				c.TaintFile = "/run/packer-taint"
+				c.Comm.SSHUsername = "root"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{
+					StartExitStatus: taintExitStatus,
+					StartStdout:     "CIS benchmark failed\n",
+				})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "The server was tainted by the provisioning, skipping the snapshot: CIS benchmark failed")
+			},
+		},
+	})
+}
@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// metricSummary summarizes a single time series of the server metrics.
+type metricSummary struct {
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
+
+// stepCollectMetrics fetches the resource metrics of the build server, from
+// its creation until the end of the provisioning, to help right-sizing the
+// build server type.
+type stepCollectMetrics struct{}
+
+func (s *stepCollectMetrics) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.CollectMetrics {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+	serverCreated := state.Get(StateServerCreated).(time.Time)
+
+	ui.Say("Collecting server metrics...")
+	metrics, _, err := client.Server.GetMetrics(ctx, &hcloud.Server{ID: serverID}, hcloud.ServerGetMetricsOpts{
+		Types: []hcloud.ServerMetricType{
+			hcloud.ServerMetricCPU,
+			hcloud.ServerMetricDisk,
+			hcloud.ServerMetricNetwork,
+		},
+		Start: serverCreated,
+		End:   time.Now(),
+	})
+	if err != nil {
+		// The metrics are informative only, do not fail the build
+		ui.Error(fmt.Sprintf("Could not fetch server metrics: %s", err))
+		return multistep.ActionContinue
+	}
+
+	summary := summarizeMetrics(metrics)
+	state.Put(StateServerMetrics, summary)
+
+	names := make([]string, 0, len(summary))
+	for name := range summary {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	for _, name := range names {
+		ui.Message(fmt.Sprintf("%s: avg=%.2f max=%.2f", name, summary[name].Avg, summary[name].Max))
+	}
+
+	if c.MetricsFile != "" {
+		content, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return errorHandler(state, ui, "Could not encode server metrics", err)
+		}
+		if err := os.WriteFile(c.MetricsFile, content, 0o644); err != nil {
+			return errorHandler(state, ui, "Could not write metrics file", err)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCollectMetrics) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+func summarizeMetrics(metrics *hcloud.ServerMetrics) map[string]metricSummary {
+	summary := make(map[string]metricSummary, len(metrics.TimeSeries))
+	for name, values := range metrics.TimeSeries {
+		var result metricSummary
+		var count int
+		for _, value := range values {
+			v, err := strconv.ParseFloat(value.Value, 64)
+			if err != nil {
+				continue
+			}
+			result.Avg += v
+			result.Max = max(result.Max, v)
+			count++
+		}
+		if count == 0 {
+			continue
+		}
+		result.Avg /= float64(count)
+		summary[name] = result
+	}
+	return summary
+}
@@ -0,0 +1,62 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepCollectMetrics(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCollectMetrics{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepCollectMetrics{},
+			SetupConfigFunc: func(c *Config) {
+				c.CollectMetrics = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerCreated, time.Now().Add(-time.Hour))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET",
+					Want: func(t *testing.T, req *http.Request) {
+						assert.Equal(t, "/servers/8/metrics", req.URL.Path)
+						assert.Equal(t, []string{"cpu", "disk", "network"}, req.URL.Query()["type"])
+					},
+					Status: 200,
+					JSONRaw: `{
+						"metrics": {
+							"start": "2024-01-01T10:00:00Z",
+							"end": "2024-01-01T11:00:00Z",
+							"step": 60,
+							"time_series": {
+								"cpu": { "values": [[1704103200, "10"], [1704103260, "50"]] }
+							}
+						}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				metrics, ok := state.Get(StateServerMetrics).(map[string]metricSummary)
+				assert.True(t, ok)
+				assert.Equal(t, metricSummary{Avg: 30, Max: 50}, metrics["cpu"])
+			},
+		},
+	})
+}
@@ -0,0 +1,134 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepConnectDiagnostics connects to the server, and when the communicator is
+// still not available after half of its timeout, diagnoses why. The findings
+// are included in the error when the connection times out.
+type stepConnectDiagnostics struct {
+	communicator.StepConnect
+}
+
+func (s *stepConnectDiagnostics) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	timeout := s.Config.SSHTimeout
+	if s.Config.Type == "winrm" {
+		timeout = s.Config.WinRMTimeout
+	}
+	if timeout <= 0 || s.Config.Type == "none" {
+		return s.StepConnect.Run(ctx, state)
+	}
+
+	diagnosticsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	findings := make(chan []string, 1)
+	timer := time.AfterFunc(timeout/2, func() {
+		c, ui, _ := UnpackState(state)
+		ui.Say(fmt.Sprintf("The server is not reachable after %s, running diagnostics...", timeout/2))
+		result := connectDiagnostics(diagnosticsCtx, state, s.Config.Port())
+		for _, finding := range result {
+			ui.Message(finding)
+		}
+		if c.ConsoleFile != "" {
+			if err := writeConsoleFile(diagnosticsCtx, state, c.ConsoleFile); err != nil {
+				ui.Error(fmt.Sprintf("Could not write the console access to %s: %s", c.ConsoleFile, err))
+			} else {
+				ui.Message(fmt.Sprintf("The console access of the server was written to %s", c.ConsoleFile))
+			}
+		}
+		findings <- result
+	})
+
+	action := s.StepConnect.Run(ctx, state)
+	if timer.Stop() || action != multistep.ActionHalt {
+		return action
+	}
+
+	result := <-findings
+	if err, ok := state.GetOk(StateError); ok && len(result) > 0 {
+		state.Put(StateError, fmt.Errorf("%w Diagnostics: %s", err.(error), strings.Join(result, "; ")))
+	}
+	return action
+}
+
+// connectDiagnostics returns the findings about the reachability of the
+// server: whether the communicator port accepts connections, and the state of
+// the server.
+func connectDiagnostics(ctx context.Context, state multistep.StateBag, port int) []string {
+	c, _, client := UnpackState(state)
+
+	var findings []string
+	if serverIP, ok := state.Get(StateServerIP).(string); ok {
+		address := net.JoinHostPort(serverIP, strconv.Itoa(port))
+		connect, err := commConnectFunc(c, address)
+		var conn net.Conn
+		if err == nil {
+			conn, err = connect()
+		}
+		if err != nil {
+			findings = append(findings, fmt.Sprintf("port %d on %s does not accept connections: %s", port, serverIP, err))
+		} else {
+			conn.Close()
+			findings = append(findings, fmt.Sprintf("port %d on %s accepts connections", port, serverIP))
+		}
+	}
+
+	if serverID, ok := state.Get(StateServerID).(int64); ok {
+		server, _, err := client.Server.GetByID(ctx, serverID)
+		switch {
+		case err != nil:
+			findings = append(findings, fmt.Sprintf("could not fetch server %d: %s", serverID, err))
+		case server == nil:
+			findings = append(findings, fmt.Sprintf("server %d does not exist anymore", serverID))
+		default:
+			finding := fmt.Sprintf("server %d is %s", serverID, server.Status)
+			if server.Locked {
+				finding += " and locked by a running action"
+			}
+			if server.RescueEnabled {
+				finding += ", with the rescue mode enabled"
+			}
+			findings = append(findings, finding)
+		}
+	}
+
+	return findings
+}
+
+// writeConsoleFile requests a VNC console of the server, and writes its
+// WebSocket URL and password to the file, so the boot of the server can be
+// inspected with a VNC client while the build waits for the communicator. The
+// API provides no text log of the console.
+func writeConsoleFile(ctx context.Context, state multistep.StateBag, path string) error {
+	_, _, client := UnpackState(state)
+
+	serverID, ok := state.Get(StateServerID).(int64)
+	if !ok {
+		return fmt.Errorf("the server was not created")
+	}
+	result, _, err := client.Server.RequestConsole(ctx, &hcloud.Server{ID: serverID})
+	if err != nil {
+		return err
+	}
+	packersdk.LogSecretFilter.Set(result.Password)
+
+	content := fmt.Sprintf("wss_url: %s\npassword: %s\n", result.WSSURL, result.Password)
+	return os.WriteFile(path, []byte(content), 0o600)
+}
@@ -0,0 +1,96 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepConnectDiagnostics(t *testing.T) {
+	// A port which does not accept connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/servers/8",
+			Status:  200,
+			JSONRaw: `{ "server": { "id": 8, "status": "running", "locked": true }}`,
+		},
+	}))
+	defer server.Close()
+
+	comm := &communicator.Config{
+		Type: "ssh",
+		SSH:  communicator.SSH{SSHPort: port, SSHTimeout: 2 * time.Second},
+	}
+	step := &stepConnectDiagnostics{communicator.StepConnect{
+		Config: comm,
+		Host:   func(multistep.StateBag) (string, error) { return "127.0.0.1", nil },
+		SSHConfig: func(multistep.StateBag) (*gossh.ClientConfig, error) {
+			return &gossh.ClientConfig{User: "root", HostKeyCallback: gossh.InsecureIgnoreHostKey()}, nil
+		},
+	}}
+
+	ui := &packersdk.MockUi{}
+	state := &multistep.BasicStateBag{}
+	state.Put(StateConfig, &Config{})
+	state.Put(StateUI, ui)
+	state.Put(StateHCloudClient, hcloud.NewClient(hcloud.WithEndpoint(server.URL)))
+	state.Put(StateServerID, int64(8))
+	state.Put(StateServerIP, "127.0.0.1")
+
+	action := step.Run(context.Background(), state)
+	assert.Equal(t, multistep.ActionHalt, action)
+
+	err = state.Get(StateError).(error)
+	assert.Contains(t, err.Error(), "Timeout waiting for SSH. Diagnostics: port")
+	assert.Contains(t, err.Error(), "does not accept connections")
+	assert.Contains(t, err.Error(), "server 8 is running and locked by a running action")
+	assert.Contains(t, ui.SayMessages[len(ui.SayMessages)-1].Message, "running diagnostics")
+}
+
+func TestWriteConsoleFile(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "POST", Path: "/servers/8/actions/request_console",
+			Status: 201,
+			JSONRaw: `{
+				"wss_url": "wss://console.hetzner.cloud/?server_id=8&token=abc",
+				"password": "secret",
+				"action": { "id": 3, "status": "success" }
+			}`,
+		},
+	}))
+	defer server.Close()
+
+	state := &multistep.BasicStateBag{}
+	state.Put(StateConfig, &Config{})
+	state.Put(StateUI, &packersdk.MockUi{})
+	state.Put(StateHCloudClient, hcloud.NewClient(hcloud.WithEndpoint(server.URL)))
+	state.Put(StateServerID, int64(8))
+
+	path := filepath.Join(t.TempDir(), "console.txt")
+	require.NoError(t, writeConsoleFile(context.Background(), state, path))
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, "wss_url: wss://console.hetzner.cloud/?server_id=8&token=abc\npassword: secret\n", string(content))
+}
@@ -0,0 +1,124 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// defaultEgressIPURL is the service returning the public egress IP of the
+// Packer host.
+const defaultEgressIPURL = "https://api.ipify.org"
+
+// stepCreateFirewall creates a temporary firewall only allowing the
+// communicator port from the public egress IP of the Packer host, so the build
+// server is not exposed to the whole internet while it accepts the temporary
+// key or a password.
+type stepCreateFirewall struct {
+	firewallId int64
+}
+
+func (s *stepCreateFirewall) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.TemporaryFirewall {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Detecting the public IP of the Packer host...")
+	egressIP, err := detectEgressIP(ctx, c.TemporaryFirewallIPURL)
+	if err != nil {
+		return errorHandler(state, ui, "Could not detect the public IP of the Packer host", err)
+	}
+	ui.Message(fmt.Sprintf("Public IP: %s", egressIP))
+
+	ui.Say("Creating temporary firewall...")
+	source := netip.PrefixFrom(egressIP, egressIP.BitLen())
+	_, sourceNet, _ := net.ParseCIDR(source.String())
+	name := c.resourceName()
+
+	result, _, err := client.Firewall.Create(ctx, hcloud.FirewallCreateOpts{
+		Name:   name,
+		Labels: c.buildLabels(),
+		Rules: []hcloud.FirewallRule{{
+			Direction:   hcloud.FirewallRuleDirectionIn,
+			Protocol:    hcloud.FirewallRuleProtocolTCP,
+			Port:        hcloud.Ptr(strconv.Itoa(c.Comm.Port())),
+			SourceIPs:   []net.IPNet{*sourceNet},
+			Description: hcloud.Ptr("Packer communicator"),
+		}},
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not create temporary firewall", err)
+	}
+
+	// We use this in cleanup
+	s.firewallId = result.Firewall.ID
+
+	log.Printf("temporary firewall name: %s", name)
+
+	state.Put(StateTemporaryFirewallID, result.Firewall.ID)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateFirewall) Cleanup(state multistep.StateBag) {
+	// If no firewall id is set, then we never created it, so just return
+	if s.firewallId == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if c.KeepServer {
+		// The kept server is still protected by the firewall
+		ui.Say(fmt.Sprintf("Keeping temporary firewall (ID: %d) applied to the kept server", s.firewallId))
+		return
+	}
+
+	ui.Say("Deleting temporary firewall...")
+	_, err := client.Firewall.Delete(context.TODO(), &hcloud.Firewall{ID: s.firewallId})
+	if err != nil {
+		errorHandler(state, ui, "Could not cleanup temporary firewall", err)
+	}
+}
+
+// detectEgressIP returns the public IP of the Packer host, as returned in plain
+// text by the service.
+func detectEgressIP(ctx context.Context, url string) (netip.Addr, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return netip.Addr{}, fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return netip.Addr{}, err
+	}
+	ip, err := netip.ParseAddr(strings.TrimSpace(string(body)))
+	if err != nil {
+		return netip.Addr{}, fmt.Errorf("%s returned an invalid IP: %w", url, err)
+	}
+	return ip.Unmap(), nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepCreateFirewall(t *testing.T) {
+	egress := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		fmt.Fprintln(w, "203.0.113.7")
+	}))
+	defer egress.Close()
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCreateFirewall{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepCreateFirewall{},
+			SetupConfigFunc: func(c *Config) {
+				c.TemporaryFirewall = true
+				c.TemporaryFirewallIPURL = egress.URL
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = 22
+				c.buildID = "build-id"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/firewalls",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.FirewallCreateRequest{})
+						assert.Regexp(t, "packer([a-z0-9-]+)$", payload.Name)
+						assert.Equal(t, map[string]string{buildIDLabel: "build-id"}, *payload.Labels)
+						assert.Len(t, payload.Rules, 1)
+						assert.Equal(t, "in", payload.Rules[0].Direction)
+						assert.Equal(t, "tcp", payload.Rules[0].Protocol)
+						assert.Equal(t, "22", *payload.Rules[0].Port)
+						assert.Equal(t, []string{"203.0.113.7/32"}, payload.Rules[0].SourceIPs)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"firewall": { "id": 6, "name": "packer-firewall" },
+						"actions": []
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(6), state.Get(StateTemporaryFirewallID))
+			},
+		},
+	})
+}
+
+func TestStepCleanupFirewall(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepCreateFirewall{firewallId: 6},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/firewalls/6",
+					Status: 204,
+				},
+			},
+		},
+		{
+			Name:         "kept server",
+			Step:         &stepCreateFirewall{firewallId: 6},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
+
+func TestDetectEgressIP(t *testing.T) {
+	testCases := []struct {
+		name    string
+		body    string
+		status  int
+		want    string
+		wantErr string
+	}{
+		{name: "ipv4", body: "203.0.113.7\n", status: 200, want: "203.0.113.7"},
+		{name: "ipv6", body: "2001:db8::1", status: 200, want: "2001:db8::1"},
+		{name: "invalid", body: "<html>", status: 200, wantErr: "returned an invalid IP"},
+		{name: "error", body: "", status: 503, wantErr: "returned status 503"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(tc.status)
+				fmt.Fprint(w, tc.body)
+			}))
+			defer server.Close()
+
+			ip, err := detectEgressIP(context.Background(), server.URL)
+			if tc.wantErr != "" {
+				assert.ErrorContains(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.want, ip.String())
+		})
+	}
+}
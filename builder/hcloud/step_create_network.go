@@ -0,0 +1,149 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepCreateNetwork creates a temporary private network that isolates the build
+// server, and attaches the optional bastion server to it.
+type stepCreateNetwork struct {
+	networkId int64
+	bastionId int64
+}
+
+func (s *stepCreateNetwork) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if c.TemporaryNetwork == nil {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Creating temporary network...")
+
+	networkZone := hcloud.NetworkZone(c.TemporaryNetwork.NetworkZone)
+	if networkZone == "" {
+		location, _, err := client.Location.Get(ctx, c.Location)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch location '%s'", c.Location), err)
+		}
+		if location == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not find location '%s'", c.Location))
+		}
+		networkZone = location.NetworkZone
+	}
+
+	// The values were validated in the config
+	_, ipRange, _ := net.ParseCIDR(c.TemporaryNetwork.IPRange)
+	_, subnet, _ := net.ParseCIDR(c.TemporaryNetwork.Subnet)
+
+	name := c.resourceName()
+
+	network, _, err := client.Network.Create(ctx, hcloud.NetworkCreateOpts{
+		Name:    name,
+		IPRange: ipRange,
+		Labels:  c.buildLabels(),
+		Subnets: []hcloud.NetworkSubnet{{
+			Type:        hcloud.NetworkSubnetTypeCloud,
+			IPRange:     subnet,
+			NetworkZone: networkZone,
+		}},
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not create temporary network", err)
+	}
+
+	// We use this in cleanup
+	s.networkId = network.ID
+
+	log.Printf("temporary network name: %s", name)
+
+	state.Put(StateTemporaryNetworkID, network.ID)
+
+	if c.TemporaryNetwork.Bastion != "" {
+		ui.Say("Attaching bastion server to temporary network...")
+		bastion, _, err := client.Server.Get(ctx, c.TemporaryNetwork.Bastion)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch bastion server '%s'", c.TemporaryNetwork.Bastion), err)
+		}
+		if bastion == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not find bastion server '%s'", c.TemporaryNetwork.Bastion))
+		}
+
+		err = retryStep(ctx, c, ui, c.StepRetries, func(ctx context.Context, attempt int) error {
+			if attempt > 0 {
+				// A previous attempt may have attached the bastion before failing
+				bastion, _, err := client.Server.GetByID(ctx, bastion.ID)
+				if err != nil {
+					return err
+				}
+				for _, privateNet := range bastion.PrivateNet {
+					if privateNet.Network != nil && privateNet.Network.ID == network.ID {
+						s.bastionId = bastion.ID
+						return nil
+					}
+				}
+			}
+
+			action, _, err := client.Server.AttachToNetwork(ctx, bastion, hcloud.ServerAttachToNetworkOpts{Network: network})
+			if err != nil {
+				return err
+			}
+
+			// We use this in cleanup
+			s.bastionId = bastion.ID
+
+			return client.Action.WaitFor(ctx, action)
+		})
+		if err != nil {
+			return errorHandler(state, ui, "Could not attach bastion server to temporary network", err)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateNetwork) Cleanup(state multistep.StateBag) {
+	// If no network id is set, then we never created it, so just return
+	if s.networkId == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if s.bastionId != 0 {
+		ui.Say("Detaching bastion server from temporary network...")
+		action, _, err := client.Server.DetachFromNetwork(context.TODO(), &hcloud.Server{ID: s.bastionId}, hcloud.ServerDetachFromNetworkOpts{
+			Network: &hcloud.Network{ID: s.networkId},
+		})
+		if err != nil {
+			errorHandler(state, ui, "Could not detach bastion server from temporary network", err)
+			return
+		}
+		if err := client.Action.WaitFor(context.TODO(), action); err != nil {
+			errorHandler(state, ui, "Could not detach bastion server from temporary network", err)
+			return
+		}
+	}
+
+	if c.KeepServer {
+		// The kept server is still attached to the network
+		ui.Say(fmt.Sprintf("Keeping temporary network (ID: %d) attached to the kept server", s.networkId))
+		return
+	}
+
+	ui.Say("Deleting temporary network...")
+	_, err := client.Network.Delete(context.TODO(), &hcloud.Network{ID: s.networkId})
+	if err != nil {
+		errorHandler(state, ui, "Could not cleanup temporary network", err)
+	}
+}
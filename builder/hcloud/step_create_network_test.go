@@ -0,0 +1,108 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepCreateNetwork(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCreateNetwork{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StateTemporaryNetworkID)
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name: "happy",
+			Step: &stepCreateNetwork{},
+			SetupConfigFunc: func(c *Config) {
+				c.TemporaryNetwork = &temporaryNetwork{IPRange: "10.0.0.0/16", Subnet: "10.0.1.0/24"}
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/locations?name=nbg1",
+					Status: 200,
+					JSONRaw: `{
+						"locations": [{ "id": 2, "name": "nbg1", "network_zone": "eu-central" }]
+					}`,
+				},
+				{Method: "POST", Path: "/networks",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.NetworkCreateRequest{})
+						assert.Regexp(t, "packer([a-z0-9-]+)$", payload.Name)
+						assert.Equal(t, "10.0.0.0/16", payload.IPRange)
+						assert.Equal(t, "10.0.1.0/24", payload.Subnets[0].IPRange)
+						assert.Equal(t, "eu-central", payload.Subnets[0].NetworkZone)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"network": { "id": 12, "ip_range": "10.0.0.0/16" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				networkID, ok := state.Get(StateTemporaryNetworkID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(12), networkID)
+			},
+		},
+		{
+			Name: "happy with network zone",
+			Step: &stepCreateNetwork{},
+			SetupConfigFunc: func(c *Config) {
+				c.TemporaryNetwork = &temporaryNetwork{IPRange: "10.0.0.0/16", Subnet: "10.0.0.0/16", NetworkZone: "eu-central"}
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/networks",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.NetworkCreateRequest{})
+						assert.Equal(t, "eu-central", payload.Subnets[0].NetworkZone)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"network": { "id": 12, "ip_range": "10.0.0.0/16" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
+
+func TestStepCleanupNetwork(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepCreateNetwork{networkId: 12},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/networks/12",
+					Status: 204,
+				},
+			},
+		},
+		{
+			Name:         "keep server",
+			Step:         &stepCreateNetwork{networkId: 12},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// placementGroupMaxServers is the maximum number of servers in a spread
+// placement group.
+const placementGroupMaxServers = 10
+
+// stepCreatePlacementGroup resolves the placement group of the build server,
+// or creates a temporary spread placement group.
+type stepCreatePlacementGroup struct {
+	placementGroupId int64
+}
+
+func (s *stepCreatePlacementGroup) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	switch {
+	case c.PlacementGroup != "":
+		placementGroup, _, err := client.PlacementGroup.Get(ctx, c.PlacementGroup)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch placement group '%s'", c.PlacementGroup), err)
+		}
+		if placementGroup == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not find placement group '%s'", c.PlacementGroup))
+		}
+		state.Put(StatePlacementGroupID, placementGroup.ID)
+
+	case c.PlacementGroupSelector != "":
+		placementGroups, err := client.PlacementGroup.AllWithOpts(ctx, hcloud.PlacementGroupListOpts{
+			ListOpts: hcloud.ListOpts{LabelSelector: c.PlacementGroupSelector},
+		})
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch placement groups", err)
+		}
+		// Use the oldest placement group with free capacity
+		slices.SortFunc(placementGroups, func(a, b *hcloud.PlacementGroup) int {
+			return int(a.ID - b.ID)
+		})
+		i := slices.IndexFunc(placementGroups, func(placementGroup *hcloud.PlacementGroup) bool {
+			return len(placementGroup.Servers) < placementGroupMaxServers
+		})
+		if i < 0 {
+			return errorHandler(state, ui, "", fmt.Errorf(
+				"Could not find a placement group with free capacity matching '%s'", c.PlacementGroupSelector))
+		}
+		ui.Say(fmt.Sprintf("Using placement group '%s'", placementGroups[i].Name))
+		state.Put(StatePlacementGroupID, placementGroups[i].ID)
+
+	case c.TemporaryPlacementGroup:
+		ui.Say("Creating temporary placement group...")
+		name := c.resourceName()
+		result, _, err := client.PlacementGroup.Create(ctx, hcloud.PlacementGroupCreateOpts{
+			Name:   name,
+			Type:   hcloud.PlacementGroupTypeSpread,
+			Labels: c.buildLabels(),
+		})
+		if err != nil {
+			return errorHandler(state, ui, "Could not create temporary placement group", err)
+		}
+
+		// We use this in cleanup
+		s.placementGroupId = result.PlacementGroup.ID
+
+		log.Printf("temporary placement group name: %s", name)
+
+		state.Put(StatePlacementGroupID, result.PlacementGroup.ID)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreatePlacementGroup) Cleanup(state multistep.StateBag) {
+	// If no placement group id is set, then we never created it, so just return
+	if s.placementGroupId == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if c.KeepServer {
+		// The kept server is still in the placement group
+		ui.Say(fmt.Sprintf("Keeping temporary placement group (ID: %d) of the kept server", s.placementGroupId))
+		return
+	}
+
+	ui.Say("Deleting temporary placement group...")
+	_, err := client.PlacementGroup.Delete(context.TODO(), &hcloud.PlacementGroup{ID: s.placementGroupId})
+	if err != nil {
+		errorHandler(state, ui, "Could not cleanup temporary placement group", err)
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepCreatePlacementGroup(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCreatePlacementGroup{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StatePlacementGroupID)
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name: "happy by name",
+			Step: &stepCreatePlacementGroup{},
+			SetupConfigFunc: func(c *Config) {
+				c.PlacementGroup = "builds"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/placement_groups?name=builds",
+					Status: 200,
+					JSONRaw: `{
+						"placement_groups": [{ "id": 5, "name": "builds", "type": "spread", "servers": [] }]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(5), state.Get(StatePlacementGroupID))
+			},
+		},
+		{
+			Name: "happy by selector",
+			Step: &stepCreatePlacementGroup{},
+			SetupConfigFunc: func(c *Config) {
+				c.PlacementGroupSelector = "team=builds"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/placement_groups?label_selector=team%3Dbuilds&page=1",
+					Status: 200,
+					JSONRaw: `{
+						"placement_groups": [
+							{ "id": 7, "name": "builds-2", "type": "spread", "servers": [] },
+							{ "id": 5, "name": "builds-1", "type": "spread", "servers": [1, 2, 3, 4, 5, 6, 7, 8, 9, 10] }
+						]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(7), state.Get(StatePlacementGroupID))
+			},
+		},
+		{
+			Name: "fail by selector",
+			Step: &stepCreatePlacementGroup{},
+			SetupConfigFunc: func(c *Config) {
+				c.PlacementGroupSelector = "team=builds"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/placement_groups?label_selector=team%3Dbuilds&page=1",
+					Status:  200,
+					JSONRaw: `{ "placement_groups": [] }`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "Could not find a placement group with free capacity matching 'team=builds'")
+			},
+		},
+		{
+			Name: "happy temporary",
+			Step: &stepCreatePlacementGroup{},
+			SetupConfigFunc: func(c *Config) {
+				c.TemporaryPlacementGroup = true
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/placement_groups",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.PlacementGroupCreateRequest{})
+						assert.Regexp(t, "^packer-", payload.Name)
+						assert.Equal(t, "spread", payload.Type)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"placement_group": { "id": 9, "name": "packer-abc", "type": "spread", "servers": [] }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(9), state.Get(StatePlacementGroupID))
+			},
+		},
+		{
+			Name:         "cleanup temporary",
+			Step:         &stepCreatePlacementGroup{placementGroupId: 9},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/placement_groups/9",
+					Status: 204,
+				},
+			},
+		},
+		{
+			Name:         "cleanup not created",
+			Step:         &stepCreatePlacementGroup{},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
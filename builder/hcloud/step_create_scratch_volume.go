@@ -0,0 +1,140 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepCreateScratchVolume creates a temporary volume attached to the build
+// server, giving space to the provisioners without growing the snapshot. The
+// volume is deleted after the build.
+type stepCreateScratchVolume struct {
+	volumeId int64
+}
+
+func (s *stepCreateScratchVolume) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if c.ScratchVolume == nil {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Creating scratch volume...")
+
+	serverID := state.Get(StateServerID).(int64)
+	name := c.resourceName()
+
+	opts := hcloud.VolumeCreateOpts{
+		Name:      name,
+		Size:      c.ScratchVolume.Size,
+		Server:    &hcloud.Server{ID: serverID},
+		Labels:    c.buildLabels(),
+		Automount: hcloud.Ptr(c.ScratchVolume.Automount),
+	}
+	if c.ScratchVolume.Filesystem != "" {
+		opts.Format = hcloud.Ptr(c.ScratchVolume.Filesystem)
+	}
+
+	result, _, err := client.Volume.Create(ctx, opts)
+	if err != nil {
+		return errorHandler(state, ui, "Could not create scratch volume", err)
+	}
+
+	// We use this in cleanup
+	s.volumeId = result.Volume.ID
+
+	log.Printf("scratch volume name: %s", name)
+
+	if err := waitForActions(ctx, client, ui, append([]*hcloud.Action{result.Action}, result.NextActions...)...); err != nil {
+		return errorHandler(state, ui, "Could not attach scratch volume", err)
+	}
+
+	state.Put(StateScratchVolume, result.Volume)
+
+	volumeIDs, _ := state.Get(StateVolumeIDs).([]int64)
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+	generatedData.Put("VolumeIDs", formatIDs(append(slices.Clone(volumeIDs), result.Volume.ID)))
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateScratchVolume) Cleanup(state multistep.StateBag) {
+	// If no volume id is set, then we never created it, so just return
+	if s.volumeId == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if c.KeepServer {
+		// The kept server may still use the volume
+		ui.Say(fmt.Sprintf("Keeping scratch volume (ID: %d) attached to the kept server", s.volumeId))
+		return
+	}
+
+	volume := &hcloud.Volume{ID: s.volumeId}
+
+	ui.Say("Deleting scratch volume...")
+	action, _, err := client.Volume.Detach(context.TODO(), volume)
+	if err != nil && !hcloud.IsError(err, hcloud.ErrorCodeNotFound) {
+		errorHandler(state, ui, "Could not detach scratch volume", err)
+		return
+	}
+	if action != nil {
+		if err := client.Action.WaitFor(context.TODO(), action); err != nil {
+			errorHandler(state, ui, "Could not detach scratch volume", err)
+			return
+		}
+	}
+
+	if _, err := client.Volume.Delete(context.TODO(), volume); err != nil {
+		errorHandler(state, ui, "Could not cleanup scratch volume", err)
+	}
+}
+
+// stepMountScratchVolume mounts the formatted scratch volume on the build
+// server. The mount is not persisted in fstab, so the image does not depend on
+// the volume.
+type stepMountScratchVolume struct{}
+
+func (s *stepMountScratchVolume) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	volume, ok := state.Get(StateScratchVolume).(*hcloud.Volume)
+	if !ok || c.ScratchVolume.MountPath == "" {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Mounting scratch volume on %s...", c.ScratchVolume.MountPath))
+
+	command := mountVolumeCommand(volume.LinuxDevice, c.ScratchVolume.MountPath, c.ScratchVolume.Automount)
+	if c.Comm.SSHUsername != "root" {
+		command = fmt.Sprintf("sudo -n sh -c '%s'", command)
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return errorHandler(state, ui, "Could not mount scratch volume", err)
+	}
+	if cmd.ExitStatus() != 0 {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not mount scratch volume: exit status %d", cmd.ExitStatus()))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepMountScratchVolume) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
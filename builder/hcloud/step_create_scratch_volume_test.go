@@ -0,0 +1,168 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepCreateScratchVolume(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepCreateScratchVolume{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepCreateScratchVolume{},
+			SetupConfigFunc: func(c *Config) {
+				c.ScratchVolume = &scratchVolume{Size: 50, Filesystem: "ext4", MountPath: "/scratch"}
+				c.buildID = "build-id"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(1))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/volumes",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.VolumeCreateRequest{})
+						assert.Equal(t, 50, payload.Size)
+						assert.Equal(t, int64(1), *payload.Server)
+						assert.Nil(t, payload.Location)
+						assert.Equal(t, "ext4", *payload.Format)
+						assert.False(t, *payload.Automount)
+						assert.Equal(t, map[string]string{buildIDLabel: "build-id"}, *payload.Labels)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"volume": { "id": 5, "linux_device": "/dev/disk/by-id/scsi-0HC_Volume_5" },
+						"action": { "id": 3, "status": "running" },
+						"next_actions": [{ "id": 4, "status": "running" }]
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&id=4&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" },
+							{ "id": 4, "status": "success" }
+						]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				volume, ok := state.Get(StateScratchVolume).(*hcloud.Volume)
+				assert.True(t, ok)
+				assert.Equal(t, int64(5), volume.ID)
+				assert.Equal(t, "/dev/disk/by-id/scsi-0HC_Volume_5", volume.LinuxDevice)
+			},
+		},
+		{
+			Name: "fail",
+			Step: &stepCreateScratchVolume{},
+			SetupConfigFunc: func(c *Config) {
+				c.ScratchVolume = &scratchVolume{Size: 50}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(1))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/volumes",
+					Status: 403,
+					JSONRaw: `{
+						"error": { "code": "resource_limit_exceeded", "message": "volume limit exceeded" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+		},
+	})
+}
+
+func TestStepCleanupScratchVolume(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepCreateScratchVolume{volumeId: 5},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/volumes/5/actions/detach",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 3, "status": "success" }]
+					}`,
+				},
+				{Method: "DELETE", Path: "/volumes/5",
+					Status: 204,
+				},
+			},
+		},
+		{
+			Name:         "kept server",
+			Step:         &stepCreateScratchVolume{volumeId: 5},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
+
+func TestStepMountScratchVolume(t *testing.T) {
+	comm := &packersdk.MockCommunicator{}
+	volume := &hcloud.Volume{ID: 5, LinuxDevice: "/dev/disk/by-id/scsi-0HC_Volume_5"}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "no mount path",
+			Step: &stepMountScratchVolume{},
+			SetupConfigFunc: func(c *Config) {
+				c.ScratchVolume = &scratchVolume{Size: 50}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateScratchVolume, volume)
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepMountScratchVolume{},
+			SetupConfigFunc: func(c *Config) {
+				c.ScratchVolume = &scratchVolume{Size: 50, Filesystem: "xfs", MountPath: "/scratch"}
+				c.Comm.SSHUsername = "admin"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, comm)
+				state.Put(StateScratchVolume, volume)
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t,
+					"sudo -n sh -c 'mkdir -p /scratch && mount /dev/disk/by-id/scsi-0HC_Volume_5 /scratch'",
+					comm.StartCmd.Command)
+			},
+		},
+	})
+}
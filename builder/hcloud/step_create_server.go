@@ -5,26 +5,52 @@ package hcloud
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"log"
+	"maps"
+	"net"
 	"net/netip"
 	"os"
 	"slices"
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/packerbuilderdata"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
 
+// firewallSelectorPrefix marks the firewalls entries which are label selectors.
+const firewallSelectorPrefix = "label:"
+
+// firewallApplyTimeout is how long to wait for the firewalls of the server to
+// be applied.
+const firewallApplyTimeout = 5 * time.Minute
+
+// serverCreateConfigFields maps the fields of the server create request to the
+// matching configuration options, when they differ.
+var serverCreateConfigFields = map[string]string{
+	"name":       "server_name",
+	"labels":     "server_labels",
+	"public_net": "public_ipv4, public_ipv6",
+}
+
 type stepCreateServer struct {
 	serverId int64
+
+	// primaryIPIds are the Primary IPs created for the server, deleted in
+	// cleanup if the server could not be created.
+	primaryIPIds []int64
 }
 
 func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	c, ui, client := UnpackState(state)
 
-	sshKeyId := state.Get(StateSSHKeyID).(int64)
 	serverType := state.Get(StateServerType).(*hcloud.ServerType)
 
 	// Create the server based on configuration
@@ -39,8 +65,17 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 
 		userData = string(contents)
 	}
+	userData, err := buildUserData(c, userData)
+	if err != nil {
+		return errorHandler(state, ui, "Could not build user data", err)
+	}
 
-	sshKeys := []*hcloud.SSHKey{{ID: sshKeyId}}
+	// Without the temporary key, see no_ssh_keys, the server may have no key
+	sshKeys := []*hcloud.SSHKey{}
+	if sshKeyId, ok := state.Get(StateSSHKeyID).(int64); ok {
+		sshKeys = append(sshKeys, &hcloud.SSHKey{ID: sshKeyId})
+	}
+	publicKeys := []string{}
 	for _, idOrName := range c.SSHKeys {
 		sshKey, _, err := client.SSHKey.Get(ctx, idOrName)
 		if err != nil {
@@ -50,20 +85,49 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 			return errorHandler(state, ui, "", fmt.Errorf("Could not find SSH key '%s'", idOrName))
 		}
 		sshKeys = append(sshKeys, sshKey)
+		publicKeys = append(publicKeys, sshKey.PublicKey)
 	}
+	state.Put(StateSSHKeysPublic, publicKeys)
 
 	firewalls := make([]*hcloud.ServerCreateFirewall, 0, len(c.Firewalls))
 	for _, idOrName := range c.Firewalls {
+		if selector, ok := strings.CutPrefix(idOrName, firewallSelectorPrefix); ok {
+			selected, err := client.Firewall.AllWithOpts(ctx, hcloud.FirewallListOpts{
+				ListOpts: hcloud.ListOpts{LabelSelector: selector},
+			})
+			if err != nil {
+				return errorHandler(state, ui, fmt.Sprintf("Could not fetch firewalls matching '%s'", selector), err)
+			}
+			if len(selected) == 0 {
+				if !c.ignoresMissing(ignoreMissingFirewalls) {
+					return errorHandler(state, ui, "", fmt.Errorf("Could not find firewalls matching '%s'", selector))
+				}
+				ui.Errorf("Could not find firewalls matching '%s', ignoring it", selector)
+			}
+			for _, firewall := range selected {
+				firewalls = append(firewalls, &hcloud.ServerCreateFirewall{Firewall: *firewall})
+			}
+			continue
+		}
+
 		firewall, _, err := client.Firewall.Get(ctx, idOrName)
 		if err != nil {
 			return errorHandler(state, ui, fmt.Sprintf("Could not fetch firewall '%s'", idOrName), err)
 		}
 		if firewall == nil {
+			if c.ignoresMissing(ignoreMissingFirewalls) {
+				ui.Errorf("Could not find firewall '%s', ignoring it", idOrName)
+				continue
+			}
 			return errorHandler(state, ui, "", fmt.Errorf("Could not find firewall '%s'", idOrName))
 		}
 		firewalls = append(firewalls, &hcloud.ServerCreateFirewall{Firewall: *firewall})
 	}
 
+	if firewallID, ok := state.GetOk(StateTemporaryFirewallID); ok {
+		firewalls = append(firewalls, &hcloud.ServerCreateFirewall{Firewall: hcloud.Firewall{ID: firewallID.(int64)}})
+	}
+
 	volumes := make([]*hcloud.Volume, 0, len(c.Volumes))
 	for _, idOrName := range c.Volumes {
 		volume, _, err := client.Volume.Get(ctx, idOrName)
@@ -71,13 +135,35 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 			return errorHandler(state, ui, fmt.Sprintf("Could not fetch volume '%s'", idOrName), err)
 		}
 		if volume == nil {
+			if c.ignoresMissing(ignoreMissingVolumes) {
+				ui.Errorf("Could not find volume '%s', ignoring it", idOrName)
+				continue
+			}
 			return errorHandler(state, ui, "", fmt.Errorf("Could not find volume '%s'", idOrName))
 		}
 		volumes = append(volumes, volume)
 	}
+	if c.VolumeSelector != "" {
+		selected, err := client.Volume.AllWithOpts(ctx, hcloud.VolumeListOpts{
+			ListOpts: hcloud.ListOpts{LabelSelector: c.VolumeSelector},
+		})
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch volumes", err)
+		}
+		if len(selected) == 0 {
+			if !c.ignoresMissing(ignoreMissingVolumes) {
+				return errorHandler(state, ui, "", fmt.Errorf("Could not find volumes matching '%s'", c.VolumeSelector))
+			}
+			ui.Errorf("Could not find volumes matching '%s', ignoring it", c.VolumeSelector)
+		}
+		volumes = append(volumes, selected...)
+	}
+	volumeIDs := make([]int64, 0, len(volumes))
+	for _, volume := range volumes {
+		volumeIDs = append(volumeIDs, volume.ID)
+	}
 
 	var image *hcloud.Image
-	var err error
 	if c.Image != "" {
 		image, _, err = client.Image.GetForArchitecture(ctx, c.Image, serverType.Architecture)
 		if err != nil {
@@ -87,7 +173,7 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 			return errorHandler(state, ui, "", fmt.Errorf("Could not find image"))
 		}
 	} else {
-		image, err = getImageWithSelectors(ctx, client, c, serverType)
+		image, err = getImageWithSelectors(ctx, client, c, ui, serverType)
 		if err != nil {
 			return errorHandler(state, ui, "Could not find image", err)
 		}
@@ -99,12 +185,36 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 			image.ID, image.Deprecated.Format("2006-01-02"),
 		)
 	}
+	if msg, eol := checkImageEndOfLife(image, time.Now(), c.EOLWarningPeriod); msg != "" {
+		if eol && c.FailOnEOL {
+			return errorHandler(state, ui, "", errors.New(msg))
+		}
+		ui.Error(msg)
+	}
 
 	state.Put(StateSourceImageID, image.ID)
 
 	var networks []*hcloud.Network
-	for _, k := range c.Networks {
-		networks = append(networks, &hcloud.Network{ID: k})
+	for _, network := range c.Networks {
+		resolved, msg, err := getNetwork(ctx, client, network)
+		if err != nil {
+			return errorHandler(state, ui, msg, err)
+		}
+		networks = append(networks, resolved)
+	}
+	if networkID, ok := state.GetOk(StateTemporaryNetworkID); ok {
+		networks = append(networks, &hcloud.Network{ID: networkID.(int64)})
+	}
+
+	if c.ServerNameConflict == serverNameConflictSuffix {
+		name, err := uniqueServerName(ctx, client, c.ServerName)
+		if err != nil {
+			return errorHandler(state, ui, "Could not check the server name", err)
+		}
+		if name != c.ServerName {
+			ui.Say(fmt.Sprintf("A server named %s already exists, using %s", c.ServerName, name))
+			c.ServerName = name
+		}
 	}
 
 	serverCreateOpts := hcloud.ServerCreateOpts{
@@ -112,63 +222,211 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 		ServerType: &hcloud.ServerType{Name: c.ServerType},
 		Image:      image,
 		Firewalls:  firewalls,
-                Volumes:    volumes,
+		Volumes:    volumes,
 		SSHKeys:    sshKeys,
 		Location:   &hcloud.Location{Name: c.Location},
 		UserData:   userData,
 		Networks:   networks,
-		Labels:     c.ServerLabels,
+		Labels:     inheritLabels(maps.Clone(c.ServerLabels), map[string]string{sourceImageLabel: strconv.FormatInt(image.ID, 10)}, nil),
 		PublicNet: &hcloud.ServerCreatePublicNet{
 			EnableIPv4: !c.PublicIPv4Disabled,
 			EnableIPv6: !c.PublicIPv6Disabled,
 		},
 	}
 
+	if c.Datacenter != "" {
+		serverCreateOpts.Location = nil
+		serverCreateOpts.Datacenter = &hcloud.Datacenter{Name: c.Datacenter}
+	}
+
+	if c.VolumeAutomount && len(volumes) > 0 {
+		serverCreateOpts.Automount = hcloud.Ptr(true)
+	}
+
+	if placementGroupID, ok := state.GetOk(StatePlacementGroupID); ok {
+		serverCreateOpts.PlacementGroup = &hcloud.PlacementGroup{ID: placementGroupID.(int64)}
+	}
+
 	if !c.PublicIPv4Disabled && c.PublicIPv4 != "" {
-		publicIPv4, msg, err := getPrimaryIP(ctx, client, c.PublicIPv4)
+		publicIPv4, msg, err := getPrimaryIP(ctx, client, c.PublicIPv4, hcloud.PrimaryIPTypeIPv4, c.Location)
 		if err != nil {
 			return errorHandler(state, ui, msg, err)
 		}
 		if publicIPv4.Type != hcloud.PrimaryIPTypeIPv4 {
 			return errorHandler(state, ui, "", fmt.Errorf("Primary ip %s is not an IPv4 address", c.PublicIPv4))
 		}
+		if publicIPv4.AutoDelete {
+			if c.KeepPrimaryIP {
+				ui.Say(fmt.Sprintf("Disabling auto delete of primary ip %s...", c.PublicIPv4))
+				publicIPv4, _, err = client.PrimaryIP.Update(ctx, publicIPv4, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Ptr(false)})
+				if err != nil {
+					return errorHandler(state, ui, fmt.Sprintf("Could not disable auto delete of primary ip '%s'", c.PublicIPv4), err)
+				}
+			} else {
+				ui.Errorf("The primary ip %s has auto delete enabled, and will be deleted with the server", c.PublicIPv4)
+			}
+		}
 		serverCreateOpts.PublicNet.IPv4 = publicIPv4
 	}
 
 	if !c.PublicIPv6Disabled && c.PublicIPv6 != "" {
-		publicIPv6, msg, err := getPrimaryIP(ctx, client, c.PublicIPv6)
+		publicIPv6, msg, err := getPrimaryIP(ctx, client, c.PublicIPv6, hcloud.PrimaryIPTypeIPv6, c.Location)
 		if err != nil {
 			return errorHandler(state, ui, msg, err)
 		}
 		if publicIPv6.Type != hcloud.PrimaryIPTypeIPv6 {
 			return errorHandler(state, ui, "", fmt.Errorf("Primary ip %s is not an IPv6 address", c.PublicIPv6))
 		}
+		if publicIPv6.AutoDelete {
+			if c.KeepPrimaryIP {
+				ui.Say(fmt.Sprintf("Disabling auto delete of primary ip %s...", c.PublicIPv6))
+				publicIPv6, _, err = client.PrimaryIP.Update(ctx, publicIPv6, hcloud.PrimaryIPUpdateOpts{AutoDelete: hcloud.Ptr(false)})
+				if err != nil {
+					return errorHandler(state, ui, fmt.Sprintf("Could not disable auto delete of primary ip '%s'", c.PublicIPv6), err)
+				}
+			} else {
+				ui.Errorf("The primary ip %s has auto delete enabled, and will be deleted with the server", c.PublicIPv6)
+			}
+		}
 		serverCreateOpts.PublicNet.IPv6 = publicIPv6
 	}
 
-	if c.UpgradeServerType != "" {
+	if (c.PrimaryIPAutoDelete != nil && !*c.PrimaryIPAutoDelete) || len(c.PrimaryIPLabels) > 0 {
+		// The Primary IPs created with the server are always deleted with it,
+		// and cannot be labeled
+		publicNet := serverCreateOpts.PublicNet
+		if (publicNet.EnableIPv4 && publicNet.IPv4 == nil) || (publicNet.EnableIPv6 && publicNet.IPv6 == nil) {
+			if serverCreateOpts.Datacenter == nil {
+				datacenter, err := getLocationDatacenter(ctx, client, c.Location)
+				if err != nil {
+					return errorHandler(state, ui, "Could not fetch datacenter", err)
+				}
+				serverCreateOpts.Location = nil
+				serverCreateOpts.Datacenter = datacenter
+			}
+
+			for _, ipType := range []hcloud.PrimaryIPType{hcloud.PrimaryIPTypeIPv4, hcloud.PrimaryIPTypeIPv6} {
+				if ipType == hcloud.PrimaryIPTypeIPv4 && (!publicNet.EnableIPv4 || publicNet.IPv4 != nil) ||
+					ipType == hcloud.PrimaryIPTypeIPv6 && (!publicNet.EnableIPv6 || publicNet.IPv6 != nil) {
+					continue
+				}
+
+				ui.Say(fmt.Sprintf("Creating primary %s...", ipType))
+				primaryIP, err := createPrimaryIP(ctx, client, c, ipType, serverCreateOpts.Datacenter)
+				if err != nil {
+					return errorHandler(state, ui, fmt.Sprintf("Could not create primary %s", ipType), err)
+				}
+				s.primaryIPIds = append(s.primaryIPIds, primaryIP.ID)
+				state.Put(StatePrimaryIPIDs, s.primaryIPIds)
+
+				if ipType == hcloud.PrimaryIPTypeIPv4 {
+					publicNet.IPv4 = primaryIP
+				} else {
+					publicNet.IPv6 = primaryIP
+				}
+			}
+		}
+	}
+
+	// The networks with a static IP are attached before the server is started
+	attachments := make([]hcloud.ServerAttachToNetworkOpts, 0, len(c.NetworkAttachments))
+	for _, attachment := range c.NetworkAttachments {
+		network := &hcloud.Network{ID: attachment.ID}
+		if attachment.Name != "" {
+			var msg string
+			network, msg, err = getNetwork(ctx, client, attachment.Name)
+			if err != nil {
+				return errorHandler(state, ui, msg, err)
+			}
+		}
+		// The IPs were validated in the config
+		opts := hcloud.ServerAttachToNetworkOpts{Network: network}
+		if attachment.IP != "" {
+			opts.IP = net.ParseIP(attachment.IP)
+		}
+		for _, aliasIP := range attachment.AliasIPs {
+			opts.AliasIPs = append(opts.AliasIPs, net.ParseIP(aliasIP))
+		}
+		attachments = append(attachments, opts)
+	}
+
+	if c.upgradesBeforeProvisioning() || len(attachments) > 0 || !c.startsAfterCreate() {
 		serverCreateOpts.StartAfterCreate = hcloud.Ptr(false)
 	}
 
 	serverCreateResult, _, err := client.Server.Create(ctx, serverCreateOpts)
+	if err != nil && c.DatacenterFallback && isPlacementFailure(err) {
+		// The primary IPs are bound to the datacenter, and the server cannot be
+		// placed anywhere else
+		publicNet := serverCreateOpts.PublicNet
+		if publicNet.IPv4 != nil || publicNet.IPv6 != nil {
+			ui.Errorf("Cannot fall back to another datacenter, the primary ips are bound to %s", c.Datacenter)
+		} else {
+			datacenter, _, dcErr := client.Datacenter.Get(ctx, c.Datacenter)
+			if dcErr != nil {
+				return errorHandler(state, ui, "Could not fetch datacenter", dcErr)
+			}
+			if datacenter == nil || datacenter.Location == nil {
+				return errorHandler(state, ui, "", fmt.Errorf("Could not find datacenter '%s'", c.Datacenter))
+			}
+			ui.Errorf("Could not place server in datacenter %s (%s), falling back to any datacenter in location %s",
+				c.Datacenter, err, datacenter.Location.Name)
+			serverCreateOpts.Datacenter = nil
+			serverCreateOpts.Location = &hcloud.Location{Name: datacenter.Location.Name}
+			serverCreateResult, _, err = client.Server.Create(ctx, serverCreateOpts)
+		}
+	}
 	if err != nil {
-		return errorHandler(state, ui, "Could not create server", err)
+		return errorHandler(state, ui, "Could not create server", withConfigFields(err, serverCreateConfigFields))
 	}
 
 	// We use this in cleanup
 	s.serverId = serverCreateResult.Server.ID
 
-	if err := client.Action.WaitFor(ctx, serverCreateResult.Action); err != nil {
-		return errorHandler(state, ui, "Could not create server", err)
+	if serverCreateResult.RootPassword != "" {
+		// The password is not used by the build, but it must never show up in
+		// the logs, even of a failed build
+		packersdk.LogSecretFilter.Set(serverCreateResult.RootPassword)
 	}
-	if err := client.Action.WaitFor(ctx, serverCreateResult.NextActions...); err != nil {
+
+	// The next actions attach the networks, firewalls and volumes
+	actions := append([]*hcloud.Action{serverCreateResult.Action}, serverCreateResult.NextActions...)
+	if err := waitForActions(ctx, client, ui, actions...); err != nil {
 		return errorHandler(state, ui, "Could not create server", err)
 	}
 
 	// Store server data for later
 	server := serverCreateResult.Server
 
+	if len(attachments) > 0 {
+		ui.Say("Attaching server to networks...")
+		for _, opts := range attachments {
+			action, _, err := client.Server.AttachToNetwork(ctx, server, opts)
+			if err != nil {
+				return errorHandler(state, ui, fmt.Sprintf("Could not attach server to network %d", opts.Network.ID), err)
+			}
+			if err := client.Action.WaitFor(ctx, action); err != nil {
+				return errorHandler(state, ui, fmt.Sprintf("Could not attach server to network %d", opts.Network.ID), err)
+			}
+		}
+	}
+
+	// The private networks attached by the next actions or the attachments are
+	// missing from the create response, and the server may only be reachable
+	// over them
+	if len(networks) > 0 || len(attachments) > 0 {
+		server, _, err = client.Server.GetByID(ctx, server.ID)
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch server", err)
+		}
+		if server == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("server %d not found", s.serverId))
+		}
+	}
+
 	state.Put(StateServerID, server.ID)
+	state.Put(StateServerCreated, server.Created)
+	state.Put(StateVolumeIDs, volumeIDs)
 	// instance_id is the generic term used so that users can have access to the
 	// instance id inside of the provisioners, used in step_provision.
 	state.Put(StateInstanceID, server.ID)
@@ -179,30 +437,63 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 	}
 	state.Put(StateServerIP, serverIP)
 
+	privateIPs := map[string]string{}
+	networkNames := []string{}
+	if len(networks) > 0 || len(attachments) > 0 {
+		privateIPs, networkNames, err = getPrivateIPs(ctx, client, server)
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch server private ips", err)
+		}
+	}
+	generatedData := &packerbuilderdata.GeneratedData{State: state}
+	generatedData.Put("PrivateIPs", privateIPs)
+
+	// The resources attached to the server
+	firewallIDs := make([]int64, 0, len(firewalls))
+	for _, firewall := range firewalls {
+		firewallIDs = append(firewallIDs, firewall.Firewall.ID)
+	}
+	networkIDs := make([]int64, 0, len(server.PrivateNet))
+	for _, privateNet := range server.PrivateNet {
+		networkIDs = append(networkIDs, privateNet.Network.ID)
+	}
+	primaryIPIDs := []int64{}
+	for _, id := range []int64{server.PublicNet.IPv4.ID, server.PublicNet.IPv6.ID} {
+		if id != 0 {
+			primaryIPIDs = append(primaryIPIDs, id)
+		}
+	}
+	generatedData.Put("FirewallIDs", formatIDs(firewallIDs))
+	generatedData.Put("NetworkIDs", formatIDs(networkIDs))
+	generatedData.Put("NetworkNames", networkNames)
+	generatedData.Put("VolumeIDs", formatIDs(volumeIDs))
+	generatedData.Put("PrimaryIPIDs", formatIDs(primaryIPIDs))
+
 	// Wait that the server to settle before continuing. Prevents possible `locked`
 	// error when changing the server type.
-	actions, err := getServerRunningActions(ctx, client, server)
+	actions, err = getServerRunningActions(ctx, client, server)
 	if err != nil {
 		return errorHandler(state, ui, "Could not fetch server running actions", err)
 	}
-	if err := client.Action.WaitFor(ctx, actions...); err != nil {
+	if err := waitForActions(ctx, client, ui, actions...); err != nil {
 		return errorHandler(state, ui, "Could not wait for server running actions", err)
 	}
 
-	if c.UpgradeServerType != "" {
-		ui.Say("Upgrading server type...")
-		serverChangeTypeAction, _, err := client.Server.ChangeType(ctx, server, hcloud.ServerChangeTypeOpts{
-			ServerType:  &hcloud.ServerType{Name: c.UpgradeServerType},
-			UpgradeDisk: false,
-		})
-		if err != nil {
-			return errorHandler(state, ui, "Could not upgrade server type", err)
-		}
+	// The firewalls may be applied after the server booted, and reject the
+	// first connections of the communicator
+	if err := waitForFirewalls(ctx, client, ui, server, c.PollInterval); err != nil {
+		return errorHandler(state, ui, "Could not wait for the firewalls to be applied", err)
+	}
 
-		if err := client.Action.WaitFor(ctx, serverChangeTypeAction); err != nil {
+	if c.upgradesBeforeProvisioning() {
+		ui.Say("Upgrading server type...")
+		if err := changeServerType(ctx, client, server, c.UpgradeServerType, c.UpgradeDisk); err != nil {
 			return errorHandler(state, ui, "Could not upgrade server type", err)
 		}
+	}
 
+	// Without start_after_create, the server is started by stepPowerOnServer
+	if serverCreateOpts.StartAfterCreate != nil && c.startsAfterCreate() {
 		ui.Say("Starting server...")
 		serverPoweronAction, _, err := client.Server.Poweron(ctx, server)
 		if err != nil {
@@ -214,21 +505,21 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 		}
 	}
 
-	if c.RescueMode != "" {
+	if c.RescueMode != "" && !c.startsAfterCreate() {
+		// The server boots into the rescue system once it is started
 		ui.Say("Enabling Rescue Mode...")
-		_, err := setRescue(ctx, client, server, c.RescueMode, sshKeys)
-		if err != nil {
+		if _, err := setRescue(ctx, client, server, c.RescueMode, sshKeys); err != nil {
 			return errorHandler(state, ui, "Could not enable rescue mode", err)
 		}
-		ui.Say("Rebooting server...")
-		action, _, err := client.Server.Reset(ctx, server)
-		if err != nil {
-			return errorHandler(state, ui, "Could not reboot server", err)
-		}
-		if err := client.Action.WaitFor(ctx, action); err != nil {
-			return errorHandler(state, ui, "Could not reboot server", err)
+	} else if c.RescueMode != "" {
+		ui.Say("Enabling Rescue Mode and rebooting server...")
+		if err := rebootIntoRescue(ctx, client, server, c.RescueMode, sshKeys); err != nil {
+			return errorHandler(state, ui, "", err)
 		}
 	}
+	if c.RescueMode != "" {
+		state.Put(StateRescueActive, true)
+	}
 
 	return multistep.ActionContinue
 }
@@ -236,16 +527,61 @@ func (s *stepCreateServer) Run(ctx context.Context, state multistep.StateBag) mu
 func (s *stepCreateServer) Cleanup(state multistep.StateBag) {
 	c, ui, client := UnpackState(state)
 	// If the serverID isn't there, we probably never created it
-	if s.serverId == 0 || c.KeepServer == true {
+	if s.serverId == 0 {
+		for _, id := range s.primaryIPIds {
+			ui.Say("Deleting unused primary ip...")
+			if _, err := client.PrimaryIP.Delete(context.TODO(), &hcloud.PrimaryIP{ID: id}); err != nil {
+				errorHandler(state, ui, fmt.Sprintf("Could not delete primary ip %d (please delete it manually)", id), err)
+			}
+		}
+		return
+	}
+	if c.KeepServer {
+		return
+	}
+
+	protected, err := isProtectedServer(context.TODO(), client, c, s.serverId)
+	if err != nil {
+		errorHandler(state, ui, "Could not check server protection (please destroy it manually)", err)
+		return
+	}
+	if protected {
+		ui.Say(fmt.Sprintf("Server %d is protected by the configuration, not destroying it", s.serverId))
 		return
 	}
 
 	// Destroy the server we just created
 	ui.Say("Destroying server...")
-	_, _, err := client.Server.DeleteWithResult(context.TODO(), &hcloud.Server{ID: s.serverId})
+	err = retryStep(context.TODO(), c, ui, c.StepRetries, func(ctx context.Context, attempt int) error {
+		return destroyServer(ctx, client, s.serverId)
+	})
+	if err != nil {
+		errorHandler(state, ui, fmt.Sprintf(
+			"Could not destroy server %d, it is still billed (please destroy it manually)", s.serverId), err)
+	}
+}
+
+// destroyServer deletes the server and verifies that it is gone.
+func destroyServer(ctx context.Context, client *hcloud.Client, serverID int64) error {
+	result, _, err := client.Server.DeleteWithResult(ctx, &hcloud.Server{ID: serverID})
+	if err != nil {
+		if hcloud.IsError(err, hcloud.ErrorCodeNotFound) {
+			return nil
+		}
+		return err
+	}
+	if err := client.Action.WaitFor(ctx, result.Action); err != nil {
+		return err
+	}
+
+	server, _, err := client.Server.GetByID(ctx, serverID)
 	if err != nil {
-		errorHandler(state, ui, "Could not destroy server (please destroy it manually)", err)
+		return err
+	}
+	if server != nil {
+		return fmt.Errorf("server %d still exists after deletion", serverID)
 	}
+	return nil
 }
 
 func setRescue(ctx context.Context, client *hcloud.Client, server *hcloud.Server, rescue string, sshKeys []*hcloud.SSHKey) (string, error) {
@@ -287,7 +623,66 @@ func setRescue(ctx context.Context, client *hcloud.Client, server *hcloud.Server
 	return "", nil
 }
 
-func getImageWithSelectors(ctx context.Context, client *hcloud.Client, c *Config, serverType *hcloud.ServerType) (*hcloud.Image, error) {
+// getPrivateIPs returns the private IPs of the server by network name, and the
+// names of the networks in the order they are attached.
+func getPrivateIPs(ctx context.Context, client *hcloud.Client, server *hcloud.Server) (map[string]string, []string, error) {
+	privateIPs := make(map[string]string, len(server.PrivateNet))
+	names := make([]string, 0, len(server.PrivateNet))
+	for _, privateNet := range server.PrivateNet {
+		network, _, err := client.Network.GetByID(ctx, privateNet.Network.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		if network == nil {
+			return nil, nil, fmt.Errorf("network %d not found", privateNet.Network.ID)
+		}
+		privateIPs[network.Name] = privateNet.IP.String()
+		names = append(names, network.Name)
+	}
+	return privateIPs, names, nil
+}
+
+// changeServerType changes the type of the stopped server, and waits for the
+// change to complete.
+func changeServerType(ctx context.Context, client *hcloud.Client, server *hcloud.Server, serverType string, upgradeDisk bool) error {
+	action, _, err := client.Server.ChangeType(ctx, server, hcloud.ServerChangeTypeOpts{
+		ServerType:  &hcloud.ServerType{Name: serverType},
+		UpgradeDisk: upgradeDisk,
+	})
+	if err != nil {
+		return err
+	}
+	return client.Action.WaitFor(ctx, action)
+}
+
+// uniqueServerName returns the name, or the name with a random suffix if a
+// server with the name already exists.
+func uniqueServerName(ctx context.Context, client *hcloud.Client, name string) (string, error) {
+	candidate := name
+	for i := 0; i < 5; i++ {
+		server, _, err := client.Server.GetByName(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if server == nil {
+			return candidate, nil
+		}
+		candidate = suffixedServerName(name)
+	}
+	return "", fmt.Errorf("could not find an unused name for server '%s'", name)
+}
+
+// formatIDs formats the IDs as strings, as the generated data only supports
+// strings.
+func formatIDs(ids []int64) []string {
+	formatted := make([]string, 0, len(ids))
+	for _, id := range ids {
+		formatted = append(formatted, strconv.FormatInt(id, 10))
+	}
+	return formatted
+}
+
+func getImageWithSelectors(ctx context.Context, client *hcloud.Client, c *Config, ui packersdk.Ui, serverType *hcloud.ServerType) (*hcloud.Image, error) {
 	var allImages []*hcloud.Image
 
 	selector := strings.Join(c.ImageFilter.WithSelector, ",")
@@ -297,7 +692,9 @@ func getImageWithSelectors(ctx context.Context, client *hcloud.Client, c *Config
 		Architecture: []hcloud.Architecture{serverType.Architecture},
 	}
 
-	allImages, err := client.Image.AllWithOpts(ctx, opts)
+	allImages, err := listAll(ctx, c, ui, "images", &opts.ListOpts, func(ctx context.Context) ([]*hcloud.Image, *hcloud.Response, error) {
+		return client.Image.List(ctx, opts)
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -305,19 +702,57 @@ func getImageWithSelectors(ctx context.Context, client *hcloud.Client, c *Config
 		return nil, fmt.Errorf("no image found for selector %q", selector)
 	}
 	if len(allImages) > 1 {
-		if !c.ImageFilter.MostRecent {
-			return nil, fmt.Errorf("more than one image found for selector %q", selector)
-		}
-
 		sort.Slice(allImages, func(i, j int) bool {
 			return allImages[i].Created.After(allImages[j].Created)
 		})
+
+		candidates := make([]string, 0, len(allImages))
+		for _, image := range allImages {
+			candidates = append(candidates, fmt.Sprintf("%d (%s, created %s)",
+				image.ID, image.Description, image.Created.Format(time.RFC3339)))
+		}
+		log.Printf("images found for selector %q: %s", selector, strings.Join(candidates, ", "))
+
+		onMultiple := c.ImageFilter.OnMultiple
+		if onMultiple == "" && c.ImageFilter.MostRecent {
+			onMultiple = onMultipleNewest
+		}
+		switch onMultiple {
+		case onMultipleNewest:
+		case onMultipleOldest:
+			slices.Reverse(allImages)
+		default:
+			return nil, fmt.Errorf("more than one image found for selector %q: %s", selector, strings.Join(candidates, ", "))
+		}
 	}
 
 	return allImages[0], nil
 }
 
-func getPrimaryIP(ctx context.Context, client *hcloud.Client, publicIP string) (*hcloud.PrimaryIP, string, error) {
+// getPrimaryIP returns the Primary IP with the ID, the name or the address. With
+// a label selector, it returns the first matching Primary IP of the type which
+// is unassigned and in the location.
+func getPrimaryIP(ctx context.Context, client *hcloud.Client, publicIP string, ipType hcloud.PrimaryIPType, location string) (*hcloud.PrimaryIP, string, error) {
+	if isLabelSelector(publicIP) {
+		matches, err := client.PrimaryIP.AllWithOpts(ctx, hcloud.PrimaryIPListOpts{
+			ListOpts: hcloud.ListOpts{LabelSelector: publicIP},
+		})
+		if err != nil {
+			return nil, fmt.Sprintf("Could not fetch primary ips matching '%s'", publicIP), err
+		}
+		for _, match := range matches {
+			if match.Type != ipType || match.AssigneeID != 0 {
+				continue
+			}
+			if location != "" && match.Datacenter != nil && match.Datacenter.Location != nil &&
+				match.Datacenter.Location.Name != location {
+				continue
+			}
+			return match, "", nil
+		}
+		return nil, "", fmt.Errorf("Label selector '%s' matches no unassigned %s primary ip in location '%s'", publicIP, ipType, location)
+	}
+
 	hcloudPublicIP, _, err := client.PrimaryIP.Get(ctx, publicIP)
 	if err != nil {
 		return nil, fmt.Sprintf("Could not fetch primary ip '%s'", publicIP), err
@@ -334,6 +769,51 @@ func getPrimaryIP(ctx context.Context, client *hcloud.Client, publicIP string) (
 	return hcloudPublicIP, "", nil
 }
 
+// getNetwork returns the network with the ID, the name, or the network matched
+// by the label selector. A label selector must match exactly one network.
+func getNetwork(ctx context.Context, client *hcloud.Client, network string) (*hcloud.Network, string, error) {
+	if id, err := strconv.ParseInt(network, 10, 64); err == nil {
+		return &hcloud.Network{ID: id}, "", nil
+	}
+
+	if !isLabelSelector(network) {
+		hcloudNetwork, _, err := client.Network.Get(ctx, network)
+		if err != nil {
+			return nil, fmt.Sprintf("Could not fetch network '%s'", network), err
+		}
+		if hcloudNetwork == nil {
+			return nil, "", fmt.Errorf("Could not find network '%s'", network)
+		}
+		return hcloudNetwork, "", nil
+	}
+
+	matches, err := client.Network.AllWithOpts(ctx, hcloud.NetworkListOpts{
+		ListOpts: hcloud.ListOpts{LabelSelector: network},
+	})
+	if err != nil {
+		return nil, fmt.Sprintf("Could not fetch networks matching '%s'", network), err
+	}
+	switch len(matches) {
+	case 0:
+		return nil, "", fmt.Errorf("Could not find network matching '%s'", network)
+	case 1:
+		return matches[0], "", nil
+	default:
+		names := make([]string, 0, len(matches))
+		for _, match := range matches {
+			names = append(names, fmt.Sprintf("%s (ID: %d)", match.Name, match.ID))
+		}
+		return nil, "", fmt.Errorf("Label selector '%s' matches %d networks, expected one: %s",
+			network, len(matches), strings.Join(names, ", "))
+	}
+}
+
+// isLabelSelector reports whether the value is a label selector rather than
+// the name of a resource, which cannot contain the selector operators.
+func isLabelSelector(value string) bool {
+	return strings.ContainsAny(value, "=!(") || strings.Contains(value, " in ") || strings.Contains(value, " notin ")
+}
+
 func firstAvailableIP(server *hcloud.Server) string {
 	switch {
 	case !server.PublicNet.IPv4.IsUnspecified():
@@ -369,3 +849,66 @@ func getServerRunningActions(ctx context.Context, client *hcloud.Client, server
 
 	return actions, nil
 }
+
+// waitForFirewalls polls the server until all its firewalls are applied, for at
+// most firewallApplyTimeout.
+func waitForFirewalls(ctx context.Context, client *hcloud.Client, ui packersdk.Ui, server *hcloud.Server, interval time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, firewallApplyTimeout)
+	defer cancel()
+
+	said := false
+	for {
+		pending := 0
+		for _, firewall := range server.PublicNet.Firewalls {
+			if firewall.Status != hcloud.FirewallStatusApplied {
+				pending++
+			}
+		}
+		if pending == 0 {
+			return nil
+		}
+		if !said {
+			ui.Say(fmt.Sprintf("Waiting for %d firewalls to be applied...", pending))
+			said = true
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%d firewalls are still not applied: %w", pending, ctx.Err())
+		case <-time.After(interval):
+		}
+
+		var err error
+		server, _, err = client.Server.GetByID(ctx, server.ID)
+		if err != nil {
+			return err
+		}
+		if server == nil {
+			return fmt.Errorf("server not found")
+		}
+	}
+}
+
+// isProtectedServer reports whether the server is protected from any cleanup,
+// as it matches the protected_label_selector.
+func isProtectedServer(ctx context.Context, client *hcloud.Client, c *Config, serverID int64) (bool, error) {
+	if c.ProtectedLabelSelector == "" {
+		return false, nil
+	}
+
+	server, _, err := client.Server.GetByID(ctx, serverID)
+	if err != nil {
+		return false, err
+	}
+	if server == nil {
+		return false, nil
+	}
+	return matchesLabelSelector(server.Labels, c.ProtectedLabelSelector)
+}
+
+// isPlacementFailure reports whether the server could not be created because
+// the datacenter cannot host it at the moment, e.g. during a maintenance.
+func isPlacementFailure(err error) bool {
+	return hcloud.IsError(err, hcloud.ErrorCodePlacementError) ||
+		hcloud.IsError(err, hcloud.ErrorCodeResourceUnavailable)
+}
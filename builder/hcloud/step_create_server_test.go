@@ -4,10 +4,13 @@
 package hcloud
 
 import (
+	"context"
 	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
@@ -42,6 +45,7 @@ func TestStepCreateServer(t *testing.T) {
 						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
 						assert.Equal(t, "dummy-server", payload.Name)
 						assert.Equal(t, int64(114690387), payload.Image.ID)
+						assert.Equal(t, "114690387", (*payload.Labels)[sourceImageLabel])
 						assert.Equal(t, "nbg1", payload.Location)
 						assert.Equal(t, "cpx11", payload.ServerType.Name)
 						assert.True(t, payload.PublicNet.EnableIPv4)
@@ -49,20 +53,701 @@ func TestStepCreateServer(t *testing.T) {
 						assert.Nil(t, payload.Networks)
 					},
 					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" },
+						"root_password": "secret"
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				serverID, ok := state.Get(StateServerID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), serverID)
+
+				instanceID, ok := state.Get(StateInstanceID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), instanceID)
+
+				serverIP, ok := state.Get(StateServerIP).(string)
+				assert.True(t, ok)
+				assert.Equal(t, "1.2.3.4", serverIP)
+
+				assert.Equal(t, "<sensitive>", packersdk.LogSecretFilter.FilterString("secret"))
+			},
+		},
+		{
+			Name: "happy without ssh keys",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.NoSSHKeys = true
+				c.SSHKeys = nil
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "dummy-server", payload.Name)
+						assert.Equal(t, int64(114690387), payload.Image.ID)
+						assert.Equal(t, "nbg1", payload.Location)
+						assert.Equal(t, "cpx11", payload.ServerType.Name)
+						assert.True(t, payload.PublicNet.EnableIPv4)
+						assert.True(t, payload.PublicNet.EnableIPv6)
+						assert.Nil(t, payload.Networks)
+						assert.Empty(t, payload.SSHKeys)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" },
+						"root_password": "secret"
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				serverID, ok := state.Get(StateServerID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), serverID)
+
+				instanceID, ok := state.Get(StateInstanceID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), instanceID)
+
+				serverIP, ok := state.Get(StateServerIP).(string)
+				assert.True(t, ok)
+				assert.Equal(t, "1.2.3.4", serverIP)
+
+			},
+		},
+		{
+			Name: "happy without start after create",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.StartAfterCreate = hcloud.Ptr(false)
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "dummy-server", payload.Name)
+						assert.Equal(t, int64(114690387), payload.Image.ID)
+						assert.Equal(t, "nbg1", payload.Location)
+						assert.Equal(t, "cpx11", payload.ServerType.Name)
+						assert.True(t, payload.PublicNet.EnableIPv4)
+						assert.True(t, payload.PublicNet.EnableIPv6)
+						assert.Nil(t, payload.Networks)
+						assert.False(t, *payload.StartAfterCreate)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" },
+						"root_password": "secret"
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				serverID, ok := state.Get(StateServerID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), serverID)
+
+				instanceID, ok := state.Get(StateInstanceID).(int64)
+				assert.True(t, ok)
+				assert.Equal(t, int64(8), instanceID)
+
+				serverIP, ok := state.Get(StateServerIP).(string)
+				assert.True(t, ok)
+				assert.Equal(t, "1.2.3.4", serverIP)
+
+			},
+		},
+		{
+			Name: "happy with network attachment",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.PublicIPv4Disabled = true
+				c.PublicIPv6Disabled = true
+				c.NetworkAttachments = []networkAttachment{{Name: "cluster", IP: "10.0.0.5", AliasIPs: []string{"10.0.0.6"}}}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "GET", Path: "/networks?name=cluster",
+					Status: 200,
+					JSONRaw: `{
+						"networks": [{ "id": 12, "name": "cluster" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Nil(t, payload.Networks)
+						assert.False(t, *payload.StartAfterCreate)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 3, "status": "success" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/attach_to_network",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionAttachToNetworkRequest{})
+						assert.Equal(t, int64(12), payload.Network)
+						assert.Equal(t, "10.0.0.5", *payload.IP)
+						assert.Equal(t, []*string{hcloud.Ptr("10.0.0.6")}, payload.AliasIPs)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=4&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 4, "status": "success" }]
+					}`,
+				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "private_net": [{ "network": 12, "ip": "10.0.0.5" }]}
+					}`,
+				},
+				{Method: "GET", Path: "/networks/12",
+					Status: 200,
+					JSONRaw: `{
+						"network": { "id": 12, "name": "cluster" }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/poweron",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 5, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=5&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 5, "status": "success" }]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, "10.0.0.5", state.Get(StateServerIP))
+
+				generatedData := state.Get(StateGeneratedData).(map[string]interface{})
+				assert.Equal(t, map[string]string{"cluster": "10.0.0.5"}, generatedData["PrivateIPs"])
+				assert.Equal(t, []string{"12"}, generatedData["NetworkIDs"])
+				assert.Equal(t, []string{"cluster"}, generatedData["NetworkNames"])
+				assert.Equal(t, []string{}, generatedData["FirewallIDs"])
+				assert.Equal(t, []string{}, generatedData["PrimaryIPIDs"])
+			},
+		},
+		{
+			Name: "happy with firewalls by id and label selector",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.Firewalls = []string{"12", "label:env=packer"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/12",
+					Status: 200,
+					JSONRaw: `{
+						"firewall": { "id": 12, "name": "base" }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls?label_selector=env%3Dpacker&page=1",
+					Status: 200,
+					JSONRaw: `{
+						"firewalls": [{ "id": 13, "name": "packer-ssh" }, { "id": 14, "name": "packer-http" }]
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, []schema.ServerCreateFirewalls{{Firewall: 12}, {Firewall: 13}, {Firewall: 14}}, payload.Firewalls)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 3, "status": "success" }]
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "ignore missing firewall and volume",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.Firewalls = []string{"staging-fw"}
+				c.Volumes = []string{"staging-cache"}
+				c.IgnoreMissing = []string{ignoreMissingFirewalls, ignoreMissingVolumes}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls?name=staging-fw",
+					Status: 200,
+					JSONRaw: `{
+						"firewalls": []
+					}`,
+				},
+				{Method: "GET", Path: "/volumes?name=staging-cache",
+					Status: 200,
+					JSONRaw: `{
+						"volumes": []
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Empty(t, payload.Firewalls)
+						assert.Empty(t, payload.Volumes)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail with missing firewall",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.Firewalls = []string{"staging-fw"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls?name=staging-fw",
+					Status: 200,
+					JSONRaw: `{
+						"firewalls": []
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+		},
+		{
+			Name: "with datacenter",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.Datacenter = "nbg1-dc3"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "nbg1-dc3", payload.Datacenter)
+						assert.Empty(t, payload.Location)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "with server name conflict suffix",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.ServerNameConflict = serverNameConflictSuffix
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "GET", Path: "/servers?name=dummy-server",
+					Status: 200,
+					JSONRaw: `{
+						"servers": [{ "id": 7, "name": "dummy-server" }]
+					}`,
+				},
+				{Method: "GET",
+					Want: func(t *testing.T, req *http.Request) {
+						assert.Regexp(t, `^dummy-server-[0-9a-f]{8}$`, req.URL.Query().Get("name"))
+					},
+					Status: 200,
+					JSONRaw: `{
+						"servers": []
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Regexp(t, `^dummy-server-[0-9a-f]{8}$`, payload.Name)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server-1a2b3c4d", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "with datacenter fallback",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.Datacenter = "nbg1-dc3"
+				c.DatacenterFallback = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "nbg1-dc3", payload.Datacenter)
+					},
+					Status: 412,
+					JSONRaw: `{
+						"error": { "code": "placement_error", "message": "error during placement" }
+					}`,
+				},
+				{Method: "GET", Path: "/datacenters?name=nbg1-dc3",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [{ "id": 2, "name": "nbg1-dc3", "location": { "id": 1, "name": "nbg1" }}]
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Empty(t, payload.Datacenter)
+						assert.Equal(t, "nbg1", payload.Location)
+					},
+					Status: 201,
 					JSONRaw: `{
 						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
 						"action": { "id": 3, "status": "running" }
 					}`,
 				},
-				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy with primary ip without auto delete",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.PublicIPv6Disabled = true
+				c.PrimaryIPAutoDelete = hcloud.Ptr(false)
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
 					Status: 200,
 					JSONRaw: `{
-						"actions": [
-							{ "id": 3, "status": "success" }
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" }},
+							{ "id": 3, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" }}
 						],
 						"meta": { "pagination": { "page": 1 }}
 					}`,
 				},
+				{Method: "POST", Path: "/primary_ips",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &hcloud.PrimaryIPCreateOpts{})
+						assert.Equal(t, hcloud.PrimaryIPTypeIPv4, payload.Type)
+						assert.Equal(t, "nbg1-dc3", payload.Datacenter)
+						assert.False(t, *payload.AutoDelete)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"primary_ip": { "id": 20, "type": "ipv4", "ip": "1.2.3.4" }
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "3", payload.Datacenter)
+						assert.Empty(t, payload.Location)
+						assert.Equal(t, int64(20), payload.PublicNet.IPv4ID)
+						assert.False(t, payload.PublicNet.EnableIPv6)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
 				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
 					Status: 200,
 					JSONRaw: `{
@@ -73,17 +758,83 @@ func TestStepCreateServer(t *testing.T) {
 			},
 			WantStepAction: multistep.ActionContinue,
 			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
-				serverID, ok := state.Get(StateServerID).(int64)
-				assert.True(t, ok)
-				assert.Equal(t, int64(8), serverID)
-
-				instanceID, ok := state.Get(StateInstanceID).(int64)
-				assert.True(t, ok)
-				assert.Equal(t, int64(8), instanceID)
-
-				serverIP, ok := state.Get(StateServerIP).(string)
-				assert.True(t, ok)
-				assert.Equal(t, "1.2.3.4", serverIP)
+				assert.Equal(t, int64(8), state.Get(StateServerID))
+			},
+		},
+		{
+			Name: "happy with labeled primary ip",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.PublicIPv6Disabled = true
+				c.PrimaryIPLabels = map[string]string{"team": "builds"}
+				c.buildID = "build-id"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" }},
+							{ "id": 3, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" }}
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "POST", Path: "/primary_ips",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &hcloud.PrimaryIPCreateOpts{})
+						assert.Equal(t, hcloud.PrimaryIPTypeIPv4, payload.Type)
+						assert.Equal(t, "nbg1-dc3", payload.Datacenter)
+						assert.True(t, *payload.AutoDelete)
+						assert.Equal(t, map[string]string{"team": "builds", buildIDLabel: "build-id"}, payload.Labels)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"primary_ip": { "id": 20, "type": "ipv4", "ip": "1.2.3.4" }
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "3", payload.Datacenter)
+						assert.Empty(t, payload.Location)
+						assert.Equal(t, int64(20), payload.PublicNet.IPv4ID)
+						assert.False(t, payload.PublicNet.EnableIPv6)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}},
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(8), state.Get(StateServerID))
+				assert.Equal(t, []int64{20}, state.Get(StatePrimaryIPIDs))
 			},
 		},
 		{
@@ -126,7 +877,7 @@ func TestStepCreateServer(t *testing.T) {
 					},
 					Status: 201,
 					JSONRaw: `{
-						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "127.0.0.1" }, "ipv6": { "ip": "::1" }}},
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "127.0.0.1" }, "ipv6": { "ip": "::1" }, "firewalls": [{ "id": 986532, "status": "pending" }]}},
 						"action": { "id": 3, "status": "running" }
 					}`,
 				},
@@ -146,6 +897,12 @@ func TestStepCreateServer(t *testing.T) {
 						"meta": { "pagination": { "page": 1 }}
 					}`,
 				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "firewalls": [{ "id": 986532, "status": "applied" }]}}
+					}`,
+				},
 			},
 			WantStepAction: multistep.ActionContinue,
 			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
@@ -166,7 +923,7 @@ func TestStepCreateServer(t *testing.T) {
 			Name: "happy with network",
 			Step: &stepCreateServer{},
 			SetupConfigFunc: func(c *Config) {
-				c.Networks = []int64{12}
+				c.Networks = []string{"12"}
 			},
 			SetupStateFunc: func(state multistep.StateBag) {
 				state.Put(StateSSHKeyID, int64(1))
@@ -209,6 +966,18 @@ func TestStepCreateServer(t *testing.T) {
 						"meta": { "pagination": { "page": 1 }}
 					}`,
 				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "1.2.3.4" }}, "private_net": [{ "network": 12, "ip": "10.0.0.2" }]}
+					}`,
+				},
+				{Method: "GET", Path: "/networks/12",
+					Status: 200,
+					JSONRaw: `{
+						"network": { "id": 12, "name": "cluster" }
+					}`,
+				},
 				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
 					Status: 200,
 					JSONRaw: `{
@@ -231,6 +1000,9 @@ func TestStepCreateServer(t *testing.T) {
 				serverIP, ok := state.Get(StateServerIP).(string)
 				assert.True(t, ok)
 				assert.Equal(t, "1.2.3.4", serverIP)
+
+				generatedData := state.Get(StateGeneratedData).(map[string]interface{})
+				assert.Equal(t, map[string]string{"cluster": "10.0.0.2"}, generatedData["PrivateIPs"])
 			},
 		},
 		{
@@ -333,6 +1105,88 @@ func TestStepCreateServer(t *testing.T) {
 				assert.Equal(t, "127.0.0.1", serverIP)
 			},
 		},
+		{
+			Name: "happy with public ipv4 label selector and keep_primary_ip",
+			Step: &stepCreateServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.PublicIPv4 = "team=builds"
+				c.PublicIPv6Disabled = true
+				c.KeepPrimaryIP = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSSHKeyID, int64(1))
+				state.Put(StateServerType, &hcloud.ServerType{ID: 9, Name: "cpx11", Architecture: "x86"})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/ssh_keys/1",
+					Status: 200,
+					JSONRaw: `{
+						"ssh_key": { "id": 1 }
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&include_deprecated=true&name=debian-12",
+					Status: 200,
+					JSONRaw: `{
+						"images": [{ "id": 114690387, "name": "debian-12", "description": "Debian 12", "architecture": "x86" }]
+					}`,
+				},
+				{Method: "GET", Path: "/primary_ips?label_selector=team%3Dbuilds&page=1",
+					Status: 200,
+					JSONRaw: `{
+						"primary_ips": [
+							{ "id": 10, "ip": "127.0.0.2", "type": "ipv6", "datacenter": { "location": { "name": "nbg1" }}},
+							{ "id": 11, "ip": "127.0.0.3", "type": "ipv4", "assignee_id": 5, "datacenter": { "location": { "name": "nbg1" }}},
+							{ "id": 12, "ip": "127.0.0.4", "type": "ipv4", "datacenter": { "location": { "name": "fsn1" }}},
+							{ "id": 13, "ip": "127.0.0.1", "type": "ipv4", "auto_delete": true, "datacenter": { "location": { "name": "nbg1" }}}
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "PUT", Path: "/primary_ips/13",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &hcloud.PrimaryIPUpdateOpts{})
+						assert.False(t, *payload.AutoDelete)
+					},
+					Status: 200,
+					JSONRaw: `{
+						"primary_ip": { "id": 13, "ip": "127.0.0.1", "type": "ipv4", "auto_delete": false }
+					}`,
+				},
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.NotNil(t, payload.PublicNet)
+						assert.Equal(t, int64(13), payload.PublicNet.IPv4ID)
+						assert.False(t, payload.PublicNet.EnableIPv6)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "dummy-server", "public_net": { "ipv4": { "ip": "127.0.0.1" }}},
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/firewalls/actions?page=1&status=running",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, "127.0.0.1", state.Get(StateServerIP))
+			},
+		},
 		{
 			Name: "happy with public ipv4 and ipv6 addresses",
 			Step: &stepCreateServer{},
@@ -683,3 +1537,180 @@ func TestFirstAvailableIP(t *testing.T) {
 		})
 	}
 }
+
+func TestStepCleanupServer(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepCreateServer{serverId: 8},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 404,
+					JSONRaw: `{
+						"error": { "code": "not_found", "message": "server not found" }
+					}`,
+				},
+			},
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StateError)
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name:         "fail delete protected",
+			Step:         &stepCreateServer{serverId: 8},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/servers/8",
+					Status: 403,
+					JSONRaw: `{
+						"error": { "code": "protected", "message": "server is delete protected" }
+					}`,
+				},
+			},
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.Contains(t, err.Error(), "Could not destroy server 8, it is still billed (please destroy it manually)")
+			},
+		},
+		{
+			Name:         "protected by label selector",
+			Step:         &stepCreateServer{serverId: 8},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.ProtectedLabelSelector = "keep"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "labels": { "keep": "" }}
+					}`,
+				},
+			},
+		},
+	})
+}
+
+func TestGetImageWithSelectors(t *testing.T) {
+	testCases := []struct {
+		name       string
+		onMultiple string
+		wantID     int64
+		wantErr    string
+	}{
+		{name: "newest", onMultiple: onMultipleNewest, wantID: 2},
+		{name: "oldest", onMultiple: onMultipleOldest, wantID: 1},
+		{name: "fail", onMultiple: onMultipleFail, wantErr: `more than one image found for selector "name==my-image": ` +
+			`2 (new, created 2024-02-01T00:00:00Z), 1 (old, created 2024-01-01T00:00:00Z)`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+				{Method: "GET", Path: "/images?architecture=x86&label_selector=name%3D%3Dmy-image&page=1&status=available",
+					Status: 200,
+					JSONRaw: `{
+						"images": [
+							{ "id": 1, "description": "old", "created": "2024-01-01T00:00:00Z" },
+							{ "id": 2, "description": "new", "created": "2024-02-01T00:00:00Z" }
+						]
+					}`,
+				},
+			}))
+			defer server.Close()
+			client := hcloud.NewClient(hcloud.WithEndpoint(server.URL))
+
+			c := &Config{ImageFilter: &imageFilter{WithSelector: []string{"name==my-image"}, OnMultiple: tc.onMultiple}}
+			image, err := getImageWithSelectors(context.Background(), client, c, &packersdk.MockUi{},
+				&hcloud.ServerType{Architecture: hcloud.ArchitectureX86})
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantID, image.ID)
+		})
+	}
+}
+
+func TestGetNetwork(t *testing.T) {
+	testCases := []struct {
+		name     string
+		network  string
+		requests []mockutil.Request
+		wantID   int64
+		wantErr  string
+	}{
+		{name: "id", network: "12", wantID: 12},
+		{name: "name", network: "build-net",
+			requests: []mockutil.Request{
+				{Method: "GET", Path: "/networks?name=build-net",
+					Status:  200,
+					JSONRaw: `{ "networks": [{ "id": 13, "name": "build-net" }] }`,
+				},
+			},
+			wantID: 13,
+		},
+		{name: "unknown name", network: "build-net",
+			requests: []mockutil.Request{
+				{Method: "GET", Path: "/networks?name=build-net",
+					Status:  200,
+					JSONRaw: `{ "networks": [] }`,
+				},
+			},
+			wantErr: "Could not find network 'build-net'",
+		},
+		{name: "selector", network: "team=builds",
+			requests: []mockutil.Request{
+				{Method: "GET", Path: "/networks?label_selector=team%3Dbuilds&page=1",
+					Status:  200,
+					JSONRaw: `{ "networks": [{ "id": 14, "name": "builds" }] }`,
+				},
+			},
+			wantID: 14,
+		},
+		{name: "ambiguous selector", network: "team=builds",
+			requests: []mockutil.Request{
+				{Method: "GET", Path: "/networks?label_selector=team%3Dbuilds&page=1",
+					Status:  200,
+					JSONRaw: `{ "networks": [{ "id": 14, "name": "builds" }, { "id": 15, "name": "builds-old" }] }`,
+				},
+			},
+			wantErr: "Label selector 'team=builds' matches 2 networks, expected one: builds (ID: 14), builds-old (ID: 15)",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(mockutil.Handler(t, tc.requests))
+			defer server.Close()
+			client := hcloud.NewClient(hcloud.WithEndpoint(server.URL))
+
+			network, _, err := getNetwork(context.Background(), client, tc.network)
+			if tc.wantErr != "" {
+				assert.EqualError(t, err, tc.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.wantID, network.ID)
+		})
+	}
+}
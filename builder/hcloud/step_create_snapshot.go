@@ -6,8 +6,11 @@ package hcloud
 import (
 	"context"
 	"fmt"
+	"log"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
@@ -24,22 +27,85 @@ func (s *stepCreateSnapshot) Run(ctx context.Context, state multistep.StateBag)
 
 	serverID := state.Get(StateServerID).(int64)
 
+	if len(c.QuiesceCommands) > 0 {
+		ui.Say("Quiescing the server...")
+		if err := runCommands(ctx, ui, state.Get(StateCommunicator).(packersdk.Communicator), c.QuiesceCommands); err != nil {
+			// Some commands may have succeeded, e.g. freezing one of several filesystems
+			if uerr := s.unquiesce(ctx, state); uerr != nil {
+				ui.Error(fmt.Sprintf("Could not unquiesce the server: %s", uerr))
+			}
+			return errorHandler(state, ui, "Could not quiesce the server", err)
+		}
+	}
+
 	ui.Say("Creating snapshot...")
 	ui.Say("This can take some time")
-	result, _, err := client.Server.CreateImage(ctx, &hcloud.Server{ID: serverID}, &hcloud.ServerCreateImageOpts{
-		Type:        hcloud.ImageTypeSnapshot,
-		Labels:      c.SnapshotLabels,
-		Description: hcloud.Ptr(c.SnapshotName),
+	// The provisioning already succeeded, retry harder before failing the build
+	retries := max(c.SnapshotRetries, c.StepRetries)
+	err := retryStep(ctx, c, ui, retries, func(ctx context.Context, attempt int) error {
+		if attempt > 0 {
+			// A previous attempt may have triggered the snapshot before failing
+			image, err := findBuildSnapshot(ctx, client, c, serverID)
+			if err != nil {
+				return err
+			}
+			if image != nil && image.Status == hcloud.ImageStatusCreating {
+				ui.Say(fmt.Sprintf("Waiting for snapshot %d created by a previous attempt...", image.ID))
+				image, err = waitForImage(ctx, client, image.ID, c.PollInterval)
+				if err != nil {
+					return err
+				}
+			}
+			if image != nil && image.Status == hcloud.ImageStatusAvailable {
+				ui.Say(fmt.Sprintf("Found snapshot %d created by a previous attempt", image.ID))
+				state.Put(StateSnapshotID, image.ID)
+				state.Put(StateSnapshotName, c.SnapshotName)
+				return nil
+			}
+			if image != nil {
+				// Do not leave a billed, broken snapshot with the labels of the build behind
+				log.Printf("snapshot %d of a previous attempt has status %s, deleting it and creating a new one", image.ID, image.Status)
+				if _, err := client.Image.Delete(ctx, image); err != nil {
+					return err
+				}
+			}
+		}
+
+		result, _, err := client.Server.CreateImage(ctx, &hcloud.Server{ID: serverID}, &hcloud.ServerCreateImageOpts{
+			Type:        hcloud.ImageTypeSnapshot,
+			Labels:      c.SnapshotLabels,
+			Description: hcloud.Ptr(c.SnapshotName),
+		})
+		if err != nil {
+			return err
+		}
+		state.Put(StateSnapshotID, result.Image.ID)
+		state.Put(StateSnapshotName, c.SnapshotName)
+
+		return client.Action.WaitFor(ctx, result.Action)
 	})
+	uerr := s.unquiesce(ctx, state)
 	if err != nil {
+		if uerr != nil {
+			ui.Error(fmt.Sprintf("Could not unquiesce the server: %s", uerr))
+		}
 		return errorHandler(state, ui, "Could not create snapshot", err)
 	}
-	state.Put(StateSnapshotID, result.Image.ID)
-	state.Put(StateSnapshotName, c.SnapshotName)
+	if uerr != nil {
+		return errorHandler(state, ui, "Could not unquiesce the server", uerr)
+	}
 
-	if err := client.Action.WaitFor(ctx, result.Action); err != nil {
-		return errorHandler(state, ui, "Could not create snapshot", err)
+	if len(c.VerifyBoot) > 0 {
+		// The old snapshot is kept until the new one is verified to boot
+		return multistep.ActionContinue
 	}
+	return deleteOldSnapshot(ctx, state)
+}
+
+// deleteOldSnapshot deletes the snapshot with the same name found by the pre
+// validate step, once the new snapshot is safely saved.
+func deleteOldSnapshot(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	_, ui, client := UnpackState(state)
 
 	oldSnap, found := state.GetOk(StateSnapshotIDOld)
 	if !found {
@@ -53,13 +119,64 @@ func (s *stepCreateSnapshot) Run(ctx context.Context, state multistep.StateBag)
 	// thus implementing an overwrite semantics.
 	ui.Say(fmt.Sprintf("Deleting old snapshot with ID: %d", oldSnapID))
 	image := &hcloud.Image{ID: oldSnapID}
-	_, err = client.Image.Delete(ctx, image)
+	_, err := client.Image.Delete(ctx, image)
 	if err != nil {
 		return errorHandler(state, ui, fmt.Sprintf("Could not delete old snapshot id=%d", oldSnapID), err)
 	}
 	return multistep.ActionContinue
 }
 
+// unquiesce runs the unquiesce_commands once the live snapshot was taken, or
+// failed, even if the build was cancelled in the meantime.
+func (s *stepCreateSnapshot) unquiesce(ctx context.Context, state multistep.StateBag) error {
+	c, ui, _ := UnpackState(state)
+
+	if len(c.UnquiesceCommands) == 0 {
+		return nil
+	}
+
+	ui.Say("Unquiescing the server...")
+	return runCommands(context.WithoutCancel(ctx), ui, state.Get(StateCommunicator).(packersdk.Communicator), c.UnquiesceCommands)
+}
+
+// findBuildSnapshot returns the snapshot of the server created by this build,
+// if any.
+func findBuildSnapshot(ctx context.Context, client *hcloud.Client, c *Config, serverID int64) (*hcloud.Image, error) {
+	images, err := client.Image.AllWithOpts(ctx, hcloud.ImageListOpts{
+		ListOpts: hcloud.ListOpts{LabelSelector: fmt.Sprintf("%s=%s", buildIDLabel, c.buildID)},
+		Type:     []hcloud.ImageType{hcloud.ImageTypeSnapshot},
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, image := range images {
+		if image.CreatedFrom != nil && image.CreatedFrom.ID == serverID {
+			return image, nil
+		}
+	}
+	return nil, nil
+}
+
+// waitForImage waits until the image is not being created anymore, and returns
+// it, or nil if it was deleted in the meantime.
+func waitForImage(ctx context.Context, client *hcloud.Client, imageID int64, interval time.Duration) (*hcloud.Image, error) {
+	for {
+		image, _, err := client.Image.GetByID(ctx, imageID)
+		if err != nil {
+			return nil, err
+		}
+		if image == nil || image.Status != hcloud.ImageStatusCreating {
+			return image, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
 func (s *stepCreateSnapshot) Cleanup(state multistep.StateBag) {
 	// no cleanup
 }
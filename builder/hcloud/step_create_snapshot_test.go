@@ -6,8 +6,10 @@ package hcloud
 import (
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
@@ -15,6 +17,9 @@ import (
 )
 
 func TestStepCreateSnapshot(t *testing.T) {
+	quiescedComm := &packersdk.MockCommunicator{}
+	failedComm := &packersdk.MockCommunicator{}
+
 	RunStepTestCases(t, []StepTestCase{
 		{
 			Name: "happy",
@@ -56,6 +61,194 @@ func TestStepCreateSnapshot(t *testing.T) {
 				assert.Equal(t, "dummy-snapshot", snapshotName)
 			},
 		},
+		{
+			Name: "retry finds snapshot of previous attempt",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.buildID = "abc"
+				c.StepRetries = 1
+				c.StepRetryDelay = time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 503,
+					JSONRaw: `{
+						"error": { "code": "service_error", "message": "service unavailable" }
+					}`,
+				},
+				{Method: "GET", Path: "/images?label_selector=packer.build_id%3Dabc&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": [
+							{ "id": 16, "type": "snapshot", "status": "available", "created_from": { "id": 8, "name": "dummy-server" } }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(16), state.Get(StateSnapshotID))
+			},
+		},
+		{
+			Name: "retry waits for snapshot of previous attempt being created",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.buildID = "abc"
+				c.StepRetries = 1
+				c.StepRetryDelay = time.Millisecond
+				c.PollInterval = time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 503,
+					JSONRaw: `{
+						"error": { "code": "service_error", "message": "service unavailable" }
+					}`,
+				},
+				{Method: "GET", Path: "/images?label_selector=packer.build_id%3Dabc&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": [
+							{ "id": 16, "type": "snapshot", "status": "creating", "created_from": { "id": 8, "name": "dummy-server" } }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "GET", Path: "/images/16",
+					Status: 200,
+					JSONRaw: `{
+						"image": { "id": 16, "type": "snapshot", "status": "creating" }
+					}`,
+				},
+				{Method: "GET", Path: "/images/16",
+					Status: 200,
+					JSONRaw: `{
+						"image": { "id": 16, "type": "snapshot", "status": "available" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(16), state.Get(StateSnapshotID))
+			},
+		},
+		{
+			Name: "retry deletes broken snapshot of previous attempt",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.buildID = "abc"
+				c.StepRetries = 1
+				c.StepRetryDelay = time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 503,
+					JSONRaw: `{
+						"error": { "code": "service_error", "message": "service unavailable" }
+					}`,
+				},
+				{Method: "GET", Path: "/images?label_selector=packer.build_id%3Dabc&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": [
+							{ "id": 16, "type": "snapshot", "status": "unavailable", "created_from": { "id": 8, "name": "dummy-server" } }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "DELETE", Path: "/images/16",
+					Status: 204,
+				},
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 17, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=4&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 4, "status": "success" }],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(17), state.Get(StateSnapshotID))
+			},
+		},
+		{
+			Name: "retry on internal server error",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.buildID = "abc"
+				c.SnapshotRetries = 1
+				c.StepRetryDelay = time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 500,
+					JSONRaw: `{
+						"error": { "code": "unknown_error", "message": "internal server error" }
+					}`,
+				},
+				{Method: "GET", Path: "/images?label_selector=packer.build_id%3Dabc&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": [],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 17, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 4, "status": "success" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(17), state.Get(StateSnapshotID))
+			},
+		},
 		{
 			Name: "fail create image",
 			Step: &stepCreateSnapshot{},
@@ -168,6 +361,36 @@ func TestStepCreateSnapshot(t *testing.T) {
 				assert.Equal(t, "dummy-snapshot", snapshotName)
 			},
 		},
+		{
+			Name: "keep old snapshot until verify boot",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.VerifyBoot = []verifyBootTarget{{ServerType: "cax11"}}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateSnapshotIDOld, int64(20))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
 		{
 			Name: "fail with old snapshot",
 			Step: &stepCreateSnapshot{},
@@ -209,5 +432,69 @@ func TestStepCreateSnapshot(t *testing.T) {
 				assert.Regexp(t, "Could not delete old snapshot id=20: .*", err.Error())
 			},
 		},
+		{
+			Name: "live snapshot with quiesce commands",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.LiveSnapshot = true
+				c.QuiesceCommands = []string{"sudo fsfreeze -f /srv"}
+				c.UnquiesceCommands = []string{"sudo fsfreeze -u /srv"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateCommunicator, quiescedComm)
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Want: func(t *testing.T, req *http.Request) {
+						assert.True(t, quiescedComm.StartCalled)
+						assert.Equal(t, "sudo fsfreeze -f /srv", quiescedComm.StartCmd.Command)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "dummy-snapshot", "type": "snapshot" },
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, "sudo fsfreeze -u /srv", quiescedComm.StartCmd.Command)
+			},
+		},
+		{
+			Name: "fail live snapshot and unquiesce",
+			Step: &stepCreateSnapshot{},
+			SetupConfigFunc: func(c *Config) {
+				c.LiveSnapshot = true
+				c.QuiesceCommands = []string{"sudo fsfreeze -f /srv"}
+				c.UnquiesceCommands = []string{"sudo fsfreeze -u /srv"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateCommunicator, failedComm)
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/create_image",
+					Status: 400,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.Regexp(t, "Could not create snapshot: .*", err.Error())
+				assert.Equal(t, "sudo fsfreeze -u /srv", failedComm.StartCmd.Command)
+			},
+		},
 	})
 }
@@ -9,26 +9,97 @@ import (
 	"log"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
-	"github.com/hashicorp/packer-plugin-sdk/uuid"
 
 	"github.com/hetznercloud/hcloud-go/v2/hcloud"
 )
 
 type stepCreateSSHKey struct {
 	keyId int64
+
+	// sharedStore is set when the key is shared with the other sources of the
+	// build.
+	sharedStore *runStore
+}
+
+// sharedSSHKey is the temporary SSH key shared by the sources of a build. It
+// is reference-counted, and deleted when the last source finishes.
+type sharedSSHKey struct {
+	KeyID      int64  `json:"key_id"`
+	PrivateKey []byte `json:"private_key"`
+	PublicKey  []byte `json:"public_key"`
+	References int    `json:"references"`
 }
 
 func (s *stepCreateSSHKey) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	c, ui, client := UnpackState(state)
 
-	ui.Say("Uploading temporary SSH key for instance...")
+	if c.NoSSHKeys {
+		// The communicator authenticates as a user created by the user data
+		return multistep.ActionContinue
+	}
 
 	if c.Comm.SSHPublicKey == nil {
 		return errorHandler(state, ui, "", fmt.Errorf("missing SSH public key in communicator"))
 	}
 
+	if c.ShareTemporaryKey {
+		if s.sharedStore == nil {
+			s.sharedStore = newRunStore()
+		}
+		return s.runShared(ctx, state)
+	}
+
+	ui.Say("Uploading temporary SSH key for instance...")
+
+	key, err := createSSHKey(ctx, client, c)
+	if err != nil {
+		return errorHandler(state, ui, "Could not upload temporary SSH key", err)
+	}
+
+	// We use this to check cleanup
+	s.keyId = key.ID
+
+	// Remember some state for the future
+	state.Put(StateSSHKeyID, key.ID)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepCreateSSHKey) runShared(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	shared := &sharedSSHKey{}
+	err := s.sharedStore.update(ctx, "ssh_key", shared, func() (bool, error) {
+		if shared.References > 0 {
+			ui.Say("Using temporary SSH key shared with the other sources...")
+			c.Comm.SSHPrivateKey = shared.PrivateKey
+			c.Comm.SSHPublicKey = shared.PublicKey
+		} else {
+			ui.Say("Uploading temporary SSH key shared with the other sources...")
+			key, err := createSSHKey(ctx, client, c)
+			if err != nil {
+				return false, err
+			}
+			shared.KeyID = key.ID
+			shared.PrivateKey = c.Comm.SSHPrivateKey
+			shared.PublicKey = c.Comm.SSHPublicKey
+		}
+		shared.References++
+		s.keyId = shared.KeyID
+		return true, nil
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not upload temporary SSH key", err)
+	}
+
+	state.Put(StateSSHKeyID, s.keyId)
+
+	return multistep.ActionContinue
+}
+
+func createSSHKey(ctx context.Context, client *hcloud.Client, c *Config) (*hcloud.SSHKey, error) {
 	// The name of the public key on the Hetzner Cloud
-	name := fmt.Sprintf("packer-%s", uuid.TimeOrderedUUID())
+	name := c.resourceName()
 
 	// Create the key!
 	key, _, err := client.SSHKey.Create(ctx, hcloud.SSHKeyCreateOpts{
@@ -37,18 +108,12 @@ func (s *stepCreateSSHKey) Run(ctx context.Context, state multistep.StateBag) mu
 		Labels:    c.SSHKeysLabels,
 	})
 	if err != nil {
-		return errorHandler(state, ui, "Could not upload temporary SSH key", err)
+		return nil, err
 	}
 
-	// We use this to check cleanup
-	s.keyId = key.ID
-
 	log.Printf("temporary ssh key name: %s", name)
 
-	// Remember some state for the future
-	state.Put(StateSSHKeyID, key.ID)
-
-	return multistep.ActionContinue
+	return key, nil
 }
 
 func (s *stepCreateSSHKey) Cleanup(state multistep.StateBag) {
@@ -59,6 +124,25 @@ func (s *stepCreateSSHKey) Cleanup(state multistep.StateBag) {
 
 	_, ui, client := UnpackState(state)
 
+	if s.sharedStore != nil {
+		shared := &sharedSSHKey{}
+		err := s.sharedStore.update(context.TODO(), "ssh_key", shared, func() (bool, error) {
+			shared.References--
+			if shared.References > 0 {
+				log.Printf("temporary ssh key still used by %d sources", shared.References)
+				return true, nil
+			}
+
+			ui.Say("Deleting shared temporary SSH key...")
+			_, err := client.SSHKey.Delete(context.TODO(), &hcloud.SSHKey{ID: s.keyId})
+			return false, err
+		})
+		if err != nil {
+			errorHandler(state, ui, "Could not cleanup temporary SSH key", err)
+		}
+		return
+	}
+
 	ui.Say("Deleting temporary SSH key...")
 	_, err := client.SSHKey.Delete(context.TODO(), &hcloud.SSHKey{ID: s.keyId})
 	if err != nil {
@@ -5,6 +5,7 @@ package hcloud
 
 import (
 	"net/http"
+	"path/filepath"
 	"testing"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
@@ -16,11 +17,25 @@ import (
 
 func TestStepCreateSSHKey(t *testing.T) {
 	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "no ssh keys",
+			Step: &stepCreateSSHKey{},
+			SetupConfigFunc: func(c *Config) {
+				c.NoSSHKeys = true
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StateSSHKeyID)
+				assert.False(t, ok)
+			},
+		},
 		{
 			Name: "happy",
 			Step: &stepCreateSSHKey{},
 			SetupConfigFunc: func(c *Config) {
 				c.Comm.SSHPublicKey = []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILBN85MgkHac/Q+iyPS8+88eBDn2SEGnU4/uLvj6lbT0")
+				c.SSHKeysLabels = map[string]string{"team": "builds", buildIDLabel: "build-id"}
 			},
 			WantRequests: []mockutil.Request{
 				{Method: "POST", Path: "/ssh_keys",
@@ -28,6 +43,7 @@ func TestStepCreateSSHKey(t *testing.T) {
 						payload := decodeJSONBody(t, req.Body, &schema.SSHKeyCreateRequest{})
 						assert.Regexp(t, "packer([a-z0-9-]+)$", payload.Name)
 						assert.Equal(t, "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILBN85MgkHac/Q+iyPS8+88eBDn2SEGnU4/uLvj6lbT0", payload.PublicKey)
+						assert.Equal(t, map[string]string{"team": "builds", buildIDLabel: "build-id"}, *payload.Labels)
 					},
 					Status: 201,
 					JSONRaw: `{
@@ -63,3 +79,66 @@ func TestStepCleanupSSHKey(t *testing.T) {
 		},
 	})
 }
+
+func TestStepCreateSSHKeyShared(t *testing.T) {
+	store := &runStore{dir: t.TempDir()}
+	publicKey := []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILBN85MgkHac/Q+iyPS8+88eBDn2SEGnU4/uLvj6lbT0")
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "first source uploads the key",
+			Step: &stepCreateSSHKey{sharedStore: store},
+			SetupConfigFunc: func(c *Config) {
+				c.ShareTemporaryKey = true
+				c.Comm.SSHPrivateKey = []byte("private")
+				c.Comm.SSHPublicKey = publicKey
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/ssh_keys",
+					Status: 201,
+					JSONRaw: `{
+						"ssh_key": { "id": 8, "name": "packer-659596d1-93df-3868-8170-42139065172e" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, int64(8), state.Get(StateSSHKeyID))
+			},
+		},
+		{
+			Name: "second source reuses the key",
+			Step: &stepCreateSSHKey{sharedStore: store},
+			SetupConfigFunc: func(c *Config) {
+				c.ShareTemporaryKey = true
+				c.Comm.SSHPrivateKey = []byte("other")
+				c.Comm.SSHPublicKey = []byte("ssh-ed25519 other")
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				c, _, _ := UnpackState(state)
+				assert.Equal(t, int64(8), state.Get(StateSSHKeyID))
+				assert.Equal(t, []byte("private"), c.Comm.SSHPrivateKey)
+				assert.Equal(t, publicKey, c.Comm.SSHPublicKey)
+			},
+		},
+		{
+			Name:         "cleanup keeps the key in use",
+			Step:         &stepCreateSSHKey{keyId: 8, sharedStore: store},
+			StepFuncName: "cleanup",
+		},
+		{
+			Name:         "cleanup deletes the key of the last source",
+			Step:         &stepCreateSSHKey{keyId: 8, sharedStore: store},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "DELETE", Path: "/ssh_keys/8",
+					Status: 204,
+				},
+			},
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.NoFileExists(t, filepath.Join(store.dir, "ssh_key.json"))
+			},
+		},
+	})
+}
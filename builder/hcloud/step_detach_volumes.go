@@ -0,0 +1,47 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepDetachVolumes detaches the volumes of the build server once it was shut
+// down, before the snapshot is taken, so they are available to other builds
+// while the snapshot is created. The volumes of a kept server, or of a server
+// still running for a live snapshot, stay attached.
+type stepDetachVolumes struct{}
+
+func (s *stepDetachVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	volumeIDs, _ := state.Get(StateVolumeIDs).([]int64)
+	if len(volumeIDs) == 0 || c.KeepServer || c.LiveSnapshot {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Detaching volumes...")
+	actions := make([]*hcloud.Action, 0, len(volumeIDs))
+	for _, volumeID := range volumeIDs {
+		action, _, err := client.Volume.Detach(ctx, &hcloud.Volume{ID: volumeID})
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not detach volume %d", volumeID), err)
+		}
+		actions = append(actions, action)
+	}
+	if err := waitForActions(ctx, client, ui, actions...); err != nil {
+		return errorHandler(state, ui, "Could not detach volumes", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepDetachVolumes) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepDetachVolumes(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "no volumes",
+			Step:           &stepDetachVolumes{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "kept server",
+			Step: &stepDetachVolumes{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateVolumeIDs, []int64{5})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepDetachVolumes{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateVolumeIDs, []int64{5, 6})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/volumes/5/actions/detach",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "POST", Path: "/volumes/6/actions/detach",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&id=4&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" },
+							{ "id": 4, "status": "success" }
+						]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail",
+			Step: &stepDetachVolumes{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateVolumeIDs, []int64{5})
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/volumes/5/actions/detach",
+					Status: 423,
+					JSONRaw: `{
+						"error": { "code": "locked", "message": "volume is locked" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+		},
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/hashicorp/packer-plugin-sdk/multistep/commonsteps"
+)
+
+// stepDevModeProvision runs the provisioners, and in dev mode, offers to run
+// them again against the same server when they failed, after the watched
+// files changed, to shorten the edit and test loop of template authors.
+type stepDevModeProvision struct {
+	commonsteps.StepProvision
+}
+
+func (s *stepDevModeProvision) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	action := s.StepProvision.Run(ctx, state)
+	if !c.DevMode {
+		return action
+	}
+
+	for action == multistep.ActionHalt {
+		rawErr, ok := state.GetOk(StateError)
+		if !ok {
+			// Cancelled
+			return action
+		}
+
+		ui.Error(fmt.Sprintf("Provisioning failed: %s", rawErr))
+		if serverIP, ok := state.Get(StateServerIP).(string); ok {
+			// The temporary key is dumped in dev mode, as in debug mode
+			keyFile := c.Comm.SSHPrivateKeyFile
+			if keyFile == "" {
+				keyFile = fmt.Sprintf("ssh_key_%s.pem", c.PackerBuildName)
+			}
+			ui.Message(fmt.Sprintf("The server is kept for dev mode, connect with: ssh -i %s %s@%s", keyFile, c.Comm.SSHUsername, serverIP))
+		}
+		if len(c.DevModeWatch) > 0 {
+			ui.Say(fmt.Sprintf("Waiting for changes to %s...", strings.Join(c.DevModeWatch, ", ")))
+			if err := waitForChanges(ctx, c.DevModeWatch, time.Second); err != nil {
+				return action
+			}
+		}
+
+		answer, err := ui.Ask("Run the provisioners again? [Y/n]")
+		if err != nil {
+			return action
+		}
+		if answer = strings.ToLower(strings.TrimSpace(answer)); answer == "n" || answer == "no" {
+			return action
+		}
+
+		state.Remove(StateError)
+		action = s.StepProvision.Run(ctx, state)
+	}
+
+	return action
+}
+
+// waitForChanges waits until a file in the paths, or below them for
+// directories, was created, modified or deleted.
+func waitForChanges(ctx context.Context, paths []string, interval time.Duration) error {
+	initial := modTimes(paths)
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+
+		current := modTimes(paths)
+		if len(current) != len(initial) {
+			return nil
+		}
+		for path, modTime := range current {
+			if !initial[path].Equal(modTime) {
+				return nil
+			}
+		}
+	}
+}
+
+// modTimes returns the modification time of the files in the paths.
+func modTimes(paths []string) map[string]time.Time {
+	times := map[string]time.Time{}
+	for _, root := range paths {
+		_ = filepath.WalkDir(root, func(path string, entry fs.DirEntry, err error) error {
+			if err != nil || entry.IsDir() {
+				return nil
+			}
+			if info, err := entry.Info(); err == nil {
+				times[path] = info.ModTime()
+			}
+			return nil
+		})
+	}
+	return times
+}
@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// answeringUi answers the questions with the given answers.
+type answeringUi struct {
+	*packersdk.BasicUi
+	answers []string
+}
+
+func (u *answeringUi) Ask(string) (string, error) {
+	if len(u.answers) == 0 {
+		return "", errors.New("no answer")
+	}
+	answer := u.answers[0]
+	u.answers = u.answers[1:]
+	return answer, nil
+}
+
+func TestStepDevModeProvision(t *testing.T) {
+	testCases := []struct {
+		name       string
+		devMode    bool
+		answers    []string
+		wantAction multistep.StepAction
+		wantRuns   int
+	}{
+		{name: "disabled", devMode: false, wantAction: multistep.ActionHalt, wantRuns: 1},
+		{name: "rerun until success", devMode: true, answers: []string{"y", ""}, wantAction: multistep.ActionContinue, wantRuns: 3},
+		{name: "abort", devMode: true, answers: []string{"n"}, wantAction: multistep.ActionHalt, wantRuns: 1},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			runs := 0
+			hook := &packersdk.MockHook{RunFunc: func(context.Context) error {
+				runs++
+				if runs < 3 {
+					return errors.New("provisioner failed")
+				}
+				return nil
+			}}
+
+			var out bytes.Buffer
+			ui := &answeringUi{BasicUi: &packersdk.BasicUi{Writer: &out, ErrorWriter: &out}, answers: tc.answers}
+
+			c := &Config{DevMode: tc.devMode}
+			c.PackerBuildName = "dummy"
+			c.Comm.SSHUsername = "root"
+
+			state := &multistep.BasicStateBag{}
+			state.Put(StateConfig, c)
+			state.Put(StateUI, ui)
+			state.Put(StateHCloudClient, &hcloud.Client{})
+			state.Put(StateHook, hook)
+			state.Put(StateServerIP, "1.2.3.4")
+
+			action := (&stepDevModeProvision{}).Run(context.Background(), state)
+			assert.Equal(t, tc.wantAction, action)
+			assert.Equal(t, tc.wantRuns, runs)
+			if tc.devMode {
+				assert.Contains(t, out.String(), "connect with: ssh -i ssh_key_dummy.pem root@1.2.3.4")
+			}
+		})
+	}
+}
+
+func TestWaitForChanges(t *testing.T) {
+	dir := t.TempDir()
+	script := filepath.Join(dir, "provision.sh")
+	require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\n"), 0o644))
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		_ = os.WriteFile(filepath.Join(dir, "new.sh"), []byte("#!/bin/sh\n"), 0o644)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, waitForChanges(ctx, []string{dir}, 10*time.Millisecond))
+
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, waitForChanges(ctx, []string{script}, 10*time.Millisecond), context.DeadlineExceeded)
+}
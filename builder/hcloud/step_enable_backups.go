@@ -0,0 +1,41 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepEnableBackups enables the backups of a kept server right after it was
+// created, so it immediately participates in the backup window.
+type stepEnableBackups struct{}
+
+func (s *stepEnableBackups) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.EnableBackups {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	ui.Say("Enabling server backups...")
+	action, _, err := client.Server.EnableBackup(ctx, &hcloud.Server{ID: serverID}, "")
+	if err != nil {
+		return errorHandler(state, ui, "Could not enable server backups", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not enable server backups", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepEnableBackups) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
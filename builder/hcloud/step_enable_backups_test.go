@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepEnableBackups(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepEnableBackups{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepEnableBackups{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.EnableBackups = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/enable_backup",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail",
+			Step: &stepEnableBackups{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.EnableBackups = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/enable_backup",
+					Status: 423,
+					JSONRaw: `{
+						"error": { "code": "locked", "message": "server is locked" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+		},
+	})
+}
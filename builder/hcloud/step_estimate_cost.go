@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// costEstimate is the estimated cost of the build server of a source.
+type costEstimate struct {
+	ServerType string  `json:"server_type"`
+	Location   string  `json:"location"`
+	Hours      int     `json:"hours"`
+	Cost       float64 `json:"cost"`
+	Currency   string  `json:"currency"`
+}
+
+// stepEstimateCost prints the estimated cost of the build server, and the
+// combined estimate of all the sources of the build that reached this step.
+// In dry-run mode, the build stops after the estimate without creating any
+// resource.
+type stepEstimateCost struct {
+	store *runStore
+
+	// stored is set once the estimate was added to the store, to be removed
+	// again in cleanup.
+	stored bool
+}
+
+func (s *stepEstimateCost) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	capped := slices.ContainsFunc(c.policies, func(p *policy) bool { return p.MaxBuildCost > 0 })
+	if !c.CostEstimate && !c.DryRun && !capped && c.MaxHourlyPrice == 0 {
+		return multistep.ActionContinue
+	}
+
+	serverType := state.Get(StateServerType).(*hcloud.ServerType)
+	serverTypes := []*hcloud.ServerType{serverType}
+	if c.UpgradeServerType != "" {
+		// The server runs with the upgraded server type for most of the build
+		upgradeServerType, _, err := client.ServerType.Get(ctx, c.UpgradeServerType)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch upgrade server type '%s'", c.UpgradeServerType), err)
+		}
+		if upgradeServerType != nil {
+			serverType = upgradeServerType
+			serverTypes = append(serverTypes, upgradeServerType)
+		}
+	}
+
+	if c.MaxHourlyPrice > 0 {
+		pricing, _, err := client.Pricing.Get(ctx)
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch the pricing", err)
+		}
+		for _, st := range serverTypes {
+			if err := checkHourlyPrice(pricing, st, c.Location, c.MaxHourlyPrice); err != nil {
+				return errorHandler(state, ui, "", err)
+			}
+		}
+	}
+	if !c.CostEstimate && !c.DryRun && !capped {
+		return multistep.ActionContinue
+	}
+
+	estimate, err := estimateServerCost(serverType, c.Location, c.EstimatedBuildDuration.Hours())
+	if err != nil {
+		return errorHandler(state, ui, "Could not estimate the cost of the build", err)
+	}
+	ui.Say(fmt.Sprintf("Estimated cost: 1 server %s in %s for %dh: %.4f %s",
+		estimate.ServerType, estimate.Location, estimate.Hours, estimate.Cost, estimate.Currency))
+
+	state.Put(StateCostEstimate, estimate)
+
+	for _, p := range c.policies {
+		if err := p.checkCost(estimate); err != nil {
+			return errorHandler(state, ui, "", err)
+		}
+	}
+
+	if s.store == nil {
+		s.store = newRunStore()
+	}
+	estimates := map[string]costEstimate{}
+	err = s.store.update(ctx, "cost_estimate", &estimates, func() (bool, error) {
+		estimates[c.PackerBuildName] = *estimate
+		return true, nil
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not aggregate the cost of the build", err)
+	}
+	s.stored = true
+	if len(estimates) > 1 {
+		ui.Say(summarizeCostEstimates(estimates))
+	}
+
+	if c.DryRun {
+		ui.Say("Dry run, stopping the build before creating any resource")
+		return multistep.ActionHalt
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepEstimateCost) Cleanup(state multistep.StateBag) {
+	if !s.stored {
+		return
+	}
+	c, ui, _ := UnpackState(state)
+
+	// The estimates of the other sources of this run are still needed by the
+	// sources running in parallel, the last one removes the store
+	estimates := map[string]costEstimate{}
+	err := s.store.update(context.TODO(), "cost_estimate", &estimates, func() (bool, error) {
+		delete(estimates, c.PackerBuildName)
+		return len(estimates) > 0, nil
+	})
+	if err != nil {
+		ui.Error(fmt.Sprintf("Could not remove the cost estimate of the build: %s", err))
+	}
+	s.stored = false
+}
+
+// checkHourlyPrice returns an error if the hourly price of the server type in
+// the location, as returned by the pricing API, exceeds the max_hourly_price.
+func checkHourlyPrice(pricing hcloud.Pricing, serverType *hcloud.ServerType, location string, maxPrice float64) error {
+	i := slices.IndexFunc(pricing.ServerTypes, func(p hcloud.ServerTypePricing) bool {
+		return p.ServerType != nil && p.ServerType.ID == serverType.ID
+	})
+	if i < 0 {
+		return fmt.Errorf("no pricing for server type '%s'", serverType.Name)
+	}
+	price, err := estimateServerCost(&hcloud.ServerType{
+		Name:     serverType.Name,
+		Pricings: pricing.ServerTypes[i].Pricings,
+	}, location, 1)
+	if err != nil {
+		return err
+	}
+	if price.Cost > maxPrice {
+		return fmt.Errorf("the hourly price of server type '%s' in %s is %.4f %s, exceeding the max_hourly_price of %.4f %s",
+			serverType.Name, location, price.Cost, price.Currency, maxPrice, price.Currency)
+	}
+	return nil
+}
+
+// estimateServerCost returns the cost of the server type in the location for
+// the duration, billed per started hour.
+func estimateServerCost(serverType *hcloud.ServerType, location string, hours float64) (*costEstimate, error) {
+	for _, pricing := range serverType.Pricings {
+		if pricing.Location == nil || pricing.Location.Name != location {
+			continue
+		}
+		hourly, err := strconv.ParseFloat(pricing.Hourly.Gross, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid hourly price '%s': %w", pricing.Hourly.Gross, err)
+		}
+		billed := max(int(math.Ceil(hours)), 1)
+		return &costEstimate{
+			ServerType: serverType.Name,
+			Location:   location,
+			Hours:      billed,
+			Cost:       hourly * float64(billed),
+			Currency:   pricing.Hourly.Currency,
+		}, nil
+	}
+	return nil, fmt.Errorf("no pricing for server type '%s' in location '%s'", serverType.Name, location)
+}
+
+// summarizeCostEstimates returns the combined estimate of the sources.
+func summarizeCostEstimates(estimates map[string]costEstimate) string {
+	counts := map[string]int{}
+	total := 0.0
+	currency := ""
+	for _, estimate := range estimates {
+		counts[estimate.ServerType]++
+		total += estimate.Cost
+		currency = estimate.Currency
+	}
+
+	types := make([]string, 0, len(counts))
+	for serverType, count := range counts {
+		types = append(types, fmt.Sprintf("%dx %s", count, serverType))
+	}
+	slices.Sort(types)
+
+	return fmt.Sprintf("Estimated cost of the %d sources of this build (%s): %.4f %s",
+		len(estimates), strings.Join(types, ", "), total, currency)
+}
@@ -0,0 +1,200 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestEstimateServerCost(t *testing.T) {
+	serverType := &hcloud.ServerType{
+		Name: "cpx11",
+		Pricings: []hcloud.ServerTypeLocationPricing{
+			{Location: &hcloud.Location{Name: "fsn1"}, Hourly: hcloud.Price{Currency: "EUR", Gross: "0.0080"}},
+			{Location: &hcloud.Location{Name: "nbg1"}, Hourly: hcloud.Price{Currency: "EUR", Gross: "0.0100"}},
+		},
+	}
+
+	estimate, err := estimateServerCost(serverType, "nbg1", 1.5)
+	require.NoError(t, err)
+	assert.Equal(t, 2, estimate.Hours)
+	assert.InDelta(t, 0.02, estimate.Cost, 1e-9)
+	assert.Equal(t, "EUR", estimate.Currency)
+
+	_, err = estimateServerCost(serverType, "hel1", 1)
+	assert.EqualError(t, err, "no pricing for server type 'cpx11' in location 'hel1'")
+}
+
+func TestSummarizeCostEstimates(t *testing.T) {
+	assert.Equal(t,
+		"Estimated cost of the 3 sources of this build (1x cx22, 2x cpx11): 0.0300 EUR",
+		summarizeCostEstimates(map[string]costEstimate{
+			"debian": {ServerType: "cpx11", Cost: 0.01, Currency: "EUR"},
+			"ubuntu": {ServerType: "cpx11", Cost: 0.01, Currency: "EUR"},
+			"fedora": {ServerType: "cx22", Cost: 0.01, Currency: "EUR"},
+		}),
+	)
+}
+
+func TestStepEstimateCost(t *testing.T) {
+	setupState := func(state multistep.StateBag) {
+		state.Put(StateServerType, &hcloud.ServerType{
+			ID:   1,
+			Name: "cpx11",
+			Pricings: []hcloud.ServerTypeLocationPricing{
+				{Location: &hcloud.Location{Name: "nbg1"}, Hourly: hcloud.Price{Currency: "EUR", Gross: "0.0100"}},
+			},
+		})
+	}
+
+	store := &runStore{dir: t.TempDir()}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepEstimateCost{store: store},
+			SetupStateFunc: setupState,
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "estimate",
+			Step: &stepEstimateCost{store: store},
+			SetupConfigFunc: func(c *Config) {
+				c.PackerBuildName = "debian"
+				c.CostEstimate = true
+				c.EstimatedBuildDuration = time.Hour
+			},
+			SetupStateFunc: setupState,
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "dry run",
+			Step: &stepEstimateCost{store: store},
+			SetupConfigFunc: func(c *Config) {
+				c.PackerBuildName = "ubuntu"
+				c.DryRun = true
+				c.EstimatedBuildDuration = time.Hour
+			},
+			SetupStateFunc: setupState,
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StateError)
+				assert.False(t, ok)
+
+				estimates := map[string]costEstimate{}
+				require.NoError(t, store.update(context.Background(), "cost_estimate", &estimates, func() (bool, error) {
+					return true, nil
+				}))
+				assert.Len(t, estimates, 2)
+			},
+		},
+		{
+			Name: "fail with policy cost cap",
+			Step: &stepEstimateCost{store: store},
+			SetupConfigFunc: func(c *Config) {
+				c.PackerBuildName = "fedora"
+				c.EstimatedBuildDuration = 3 * time.Hour
+				c.policies = []*policy{{MaxBuildCost: 0.02, source: "block"}}
+			},
+			SetupStateFunc: setupState,
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err := state.Get(StateError).(error)
+				assert.EqualError(t, err, "policy block: the estimated cost 0.0300 EUR exceeds the maximum cost of a build 0.0200 EUR")
+			},
+		},
+		{
+			Name: "within max hourly price",
+			Step: &stepEstimateCost{store: store},
+			SetupConfigFunc: func(c *Config) {
+				c.MaxHourlyPrice = 0.01
+			},
+			SetupStateFunc: setupState,
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/pricing",
+					Status: 200,
+					JSONRaw: `{
+						"pricing": { "currency": "EUR", "server_types": [
+							{ "id": 1, "name": "cpx11", "prices": [{ "location": "nbg1", "price_hourly": { "gross": "0.0100", "net": "0.0084" }}]},
+							{ "id": 99, "name": "ccx63", "prices": [{ "location": "nbg1", "price_hourly": { "gross": "0.8000", "net": "0.6723" }}]}
+						]}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				_, ok := state.GetOk(StateCostEstimate)
+				assert.False(t, ok)
+			},
+		},
+		{
+			Name: "fail with upgrade server type above max hourly price",
+			Step: &stepEstimateCost{store: store},
+			SetupConfigFunc: func(c *Config) {
+				c.MaxHourlyPrice = 0.05
+				c.UpgradeServerType = "ccx63"
+			},
+			SetupStateFunc: setupState,
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/server_types?name=ccx63",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 99, "name": "ccx63", "architecture": "x86"}]
+					}`,
+				},
+				{Method: "GET", Path: "/pricing",
+					Status: 200,
+					JSONRaw: `{
+						"pricing": { "currency": "EUR", "server_types": [
+							{ "id": 1, "name": "cpx11", "prices": [{ "location": "nbg1", "price_hourly": { "gross": "0.0100", "net": "0.0084" }}]},
+							{ "id": 99, "name": "ccx63", "prices": [{ "location": "nbg1", "price_hourly": { "gross": "0.8000", "net": "0.6723" }}]}
+						]}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err := state.Get(StateError).(error)
+				assert.EqualError(t, err, "the hourly price of server type 'ccx63' in nbg1 is 0.8000 EUR, exceeding the max_hourly_price of 0.0500 EUR")
+			},
+		},
+		{
+			Name:         "cleanup removes the estimate",
+			Step:         &stepEstimateCost{store: store, stored: true},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.PackerBuildName = "debian"
+			},
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				estimates := map[string]costEstimate{}
+				require.NoError(t, store.update(context.Background(), "cost_estimate", &estimates, func() (bool, error) {
+					return true, nil
+				}))
+				assert.Len(t, estimates, 1)
+				assert.Contains(t, estimates, "ubuntu")
+			},
+		},
+		{
+			Name:         "cleanup removes the store with the last estimate",
+			Step:         &stepEstimateCost{store: store, stored: true},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.PackerBuildName = "ubuntu"
+			},
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.NoDirExists(t, store.dir)
+			},
+		},
+	})
+}
@@ -0,0 +1,56 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepFinalizeKeptServer gives the kept server its final name and labels once
+// the build succeeded, instead of the temporary ones of the build, and powers
+// it on with kept_server_state set to running.
+type stepFinalizeKeptServer struct{}
+
+func (s *stepFinalizeKeptServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.KeepServer {
+		return multistep.ActionContinue
+	}
+
+	server := &hcloud.Server{ID: state.Get(StateServerID).(int64)}
+
+	if c.KeptServerName != "" || c.KeptServerLabels != nil {
+		ui.Say("Updating the name and labels of the kept server...")
+		_, _, err := client.Server.Update(ctx, server, hcloud.ServerUpdateOpts{
+			Name:   c.KeptServerName,
+			Labels: c.KeptServerLabels,
+		})
+		if err != nil {
+			return errorHandler(state, ui, "Could not update the kept server", err)
+		}
+	}
+
+	// The server was shut down for the snapshot, unless it was live
+	if c.KeptServerState == keptServerStateRunning && !c.LiveSnapshot {
+		ui.Say("Powering on the kept server...")
+		action, _, err := client.Server.Poweron(ctx, server)
+		if err != nil {
+			return errorHandler(state, ui, "Could not power on the kept server", err)
+		}
+		if err := client.Action.WaitFor(ctx, action); err != nil {
+			return errorHandler(state, ui, "Could not power on the kept server", err)
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepFinalizeKeptServer) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
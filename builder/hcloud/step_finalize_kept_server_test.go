@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepFinalizeKeptServer(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepFinalizeKeptServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepFinalizeKeptServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.KeptServerName = "web-1"
+				c.KeptServerLabels = map[string]string{"role": "web"}
+				c.KeptServerState = keptServerStateRunning
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "PUT", Path: "/servers/8",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerUpdateRequest{})
+						assert.Equal(t, "web-1", payload.Name)
+						assert.Equal(t, map[string]string{"role": "web"}, *payload.Labels)
+					},
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "name": "web-1", "labels": { "role": "web" }}
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/poweron",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 3, "status": "success" }]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "live snapshot keeps running",
+			Step: &stepFinalizeKeptServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.LiveSnapshot = true
+				c.KeptServerState = keptServerStateRunning
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
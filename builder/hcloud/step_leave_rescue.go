@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepLeaveRescue takes the kept server out of the rescue system when the
+// build fails while it is enabled or booted, so the server boots the installed
+// OS, unless leave_rescue_enabled is set to debug the failure in the rescue
+// system. It only acts on cleanup, right before the server is destroyed or kept.
+type stepLeaveRescue struct{}
+
+func (s *stepLeaveRescue) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	return multistep.ActionContinue
+}
+
+func (s *stepLeaveRescue) Cleanup(state multistep.StateBag) {
+	if active, _ := state.Get(StateRescueActive).(bool); !active {
+		return
+	}
+	_, failed := state.GetOk(StateError)
+	_, cancelled := state.GetOk(multistep.StateCancelled)
+	if !failed && !cancelled {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	// Otherwise the server is destroyed anyway
+	if !c.KeepServer {
+		return
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+	if c.LeaveRescueEnabled {
+		ui.Say(fmt.Sprintf("Leaving the kept server %d in rescue mode for debugging", serverID))
+		return
+	}
+
+	ui.Say("Disabling Rescue Mode on the kept server...")
+	if err := leaveRescue(context.TODO(), client, serverID); err != nil {
+		errorHandler(state, ui, fmt.Sprintf("Could not leave rescue mode on server %d (please reboot it manually)", serverID), err)
+	}
+}
+
+// leaveRescue disables the rescue mode of the server, and reboots it if it is
+// running, as it then runs the rescue system.
+func leaveRescue(ctx context.Context, client *hcloud.Client, serverID int64) error {
+	server, _, err := client.Server.GetByID(ctx, serverID)
+	if err != nil {
+		return err
+	}
+	if server == nil {
+		return fmt.Errorf("server %d not found", serverID)
+	}
+
+	if server.RescueEnabled {
+		action, _, err := client.Server.DisableRescue(ctx, server)
+		if err != nil {
+			return err
+		}
+		if err := client.Action.WaitFor(ctx, action); err != nil {
+			return err
+		}
+	}
+
+	if server.Status == hcloud.ServerStatusRunning {
+		action, _, err := client.Server.Reset(ctx, server)
+		if err != nil {
+			return err
+		}
+		if err := client.Action.WaitFor(ctx, action); err != nil {
+			return err
+		}
+	}
+	return nil
+}
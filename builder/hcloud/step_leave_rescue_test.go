@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepLeaveRescue(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "kept server booted in rescue after failure",
+			Step:         &stepLeaveRescue{},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateRescueActive, true)
+				state.Put(StateError, errors.New("provisioning failed"))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "status": "running", "rescue_enabled": true }
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/disable_rescue",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 3, "status": "success" }]
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/reset",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=4&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [{ "id": 4, "status": "success" }]
+					}`,
+				},
+			},
+		},
+		{
+			Name:         "kept server left in rescue",
+			Step:         &stepLeaveRescue{},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.LeaveRescueEnabled = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateRescueActive, true)
+				state.Put(StateError, errors.New("provisioning failed"))
+			},
+		},
+		{
+			Name:         "successful build",
+			Step:         &stepLeaveRescue{},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateRescueActive, true)
+			},
+		},
+		{
+			Name:         "destroyed server",
+			Step:         &stepLeaveRescue{},
+			StepFuncName: "cleanup",
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateRescueActive, true)
+				state.Put(StateError, errors.New("provisioning failed"))
+			},
+		},
+	})
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// stepMountVolumes mounts the volumes attached to the build server on the
+// volume_mount_paths, instead of the /mnt/HC_Volume_<id> directories used by
+// the automount.
+type stepMountVolumes struct{}
+
+func (s *stepMountVolumes) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if len(c.VolumeMountPaths) == 0 {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	volumes := make([]string, 0, len(c.VolumeMountPaths))
+	for idOrName := range c.VolumeMountPaths {
+		volumes = append(volumes, idOrName)
+	}
+	slices.Sort(volumes)
+
+	for _, idOrName := range volumes {
+		mountPath := c.VolumeMountPaths[idOrName]
+
+		volume, _, err := client.Volume.Get(ctx, idOrName)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch volume '%s'", idOrName), err)
+		}
+		if volume == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not find volume '%s'", idOrName))
+		}
+
+		ui.Say(fmt.Sprintf("Mounting volume %s on %s...", volume.Name, mountPath))
+
+		command := mountVolumeCommand(volume.LinuxDevice, mountPath, c.VolumeAutomount)
+		if c.Comm.SSHUsername != "root" {
+			command = fmt.Sprintf("sudo -n sh -c '%s'", command)
+		}
+
+		cmd := &packersdk.RemoteCmd{Command: command}
+		if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not mount volume '%s'", idOrName), err)
+		}
+		if cmd.ExitStatus() != 0 {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not mount volume '%s': exit status %d", idOrName, cmd.ExitStatus()))
+		}
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepMountVolumes) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// mountVolumeCommand returns the command mounting the device on the path. An
+// automounted device is unmounted first, so it is only available on the path.
+func mountVolumeCommand(device, mountPath string, automount bool) string {
+	command := fmt.Sprintf("mkdir -p %[1]s && mount %[2]s %[1]s", mountPath, device)
+	if automount {
+		command = fmt.Sprintf("{ umount %s || true; } && %s", device, command)
+	}
+	return command
+}
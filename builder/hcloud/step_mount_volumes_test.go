@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepMountVolumes(t *testing.T) {
+	comm := &packersdk.MockCommunicator{}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepMountVolumes{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepMountVolumes{},
+			SetupConfigFunc: func(c *Config) {
+				c.VolumeAutomount = true
+				c.Comm.SSHUsername = "root"
+				c.VolumeMountPaths = map[string]string{"data": "/srv/data"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, comm)
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/volumes?name=data",
+					Status: 200,
+					JSONRaw: `{
+						"volumes": [{ "id": 5, "name": "data", "linux_device": "/dev/disk/by-id/scsi-0HC_Volume_5" }]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t,
+					"{ umount /dev/disk/by-id/scsi-0HC_Volume_5 || true; } && mkdir -p /srv/data && mount /dev/disk/by-id/scsi-0HC_Volume_5 /srv/data",
+					comm.StartCmd.Command)
+			},
+		},
+		{
+			Name: "volume not found",
+			Step: &stepMountVolumes{},
+			SetupConfigFunc: func(c *Config) {
+				c.VolumeMountPaths = map[string]string{"data": "/srv/data"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, comm)
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/volumes?name=data",
+					Status: 200,
+					JSONRaw: `{
+						"volumes": []
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "Could not find volume 'data'")
+			},
+		},
+	})
+}
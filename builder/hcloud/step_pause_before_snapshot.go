@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepPauseBeforeSnapshot waits after the provisioning, before the server is
+// rebooted into rescue or shut down, so background processes like the journal
+// flush or the package database writes can settle before the disk is captured.
+type stepPauseBeforeSnapshot struct{}
+
+func (s *stepPauseBeforeSnapshot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.PauseBeforeSnapshot <= 0 {
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Waiting %s before the snapshot...", c.PauseBeforeSnapshot))
+	select {
+	case <-ctx.Done():
+		return errorHandler(state, ui, "", ctx.Err())
+	case <-time.After(c.PauseBeforeSnapshot):
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPauseBeforeSnapshot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
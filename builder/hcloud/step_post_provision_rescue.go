@@ -0,0 +1,100 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepPostProvisionRescue reboots the server into the rescue system after the
+// provisioning, and runs commands on it before the snapshot is taken, e.g. to
+// shrink the filesystem or rewrite the partition table.
+type stepPostProvisionRescue struct{}
+
+func (s *stepPostProvisionRescue) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	commands := slices.Clone(c.PostProvisionRescueCommands)
+	if c.ShrinkDiskToGB > 0 {
+		commands = append(commands, shrinkDiskCommands(c.ShrinkDiskToGB)...)
+	}
+	if len(commands) == 0 {
+		return multistep.ActionContinue
+	}
+
+	server := &hcloud.Server{ID: state.Get(StateServerID).(int64)}
+	serverIP := state.Get(StateServerIP).(string)
+	sshKeyID, ok := state.Get(StateSSHKeyID).(int64)
+	if !ok {
+		return errorHandler(state, ui, "", errors.New("The rescue system requires the temporary SSH key, which was not uploaded"))
+	}
+
+	ui.Say("Enabling Rescue Mode and restarting server...")
+	err := restartIntoRescue(ctx, client, server, string(hcloud.ServerRescueTypeLinux64), []*hcloud.SSHKey{{ID: sshKeyID}}, c.PollInterval)
+	if err != nil {
+		return errorHandler(state, ui, "", err)
+	}
+	state.Put(StateRescueActive, true)
+
+	ui.Say("Connecting to the rescue system...")
+	comm, err := connectSSH(ctx, c, serverIP, rescueUsername)
+	if err != nil {
+		return errorHandler(state, ui, "Could not connect to the rescue system", err)
+	}
+
+	ui.Say("Running rescue commands...")
+	err = runCommands(ctx, ui, comm, commands)
+	comm.Close()
+	if err != nil {
+		return errorHandler(state, ui, "Could not run rescue commands", err)
+	}
+
+	// The rescue system stays active until the server is shut down.
+	ui.Say("Disabling Rescue Mode...")
+	action, _, err := client.Server.DisableRescue(ctx, server)
+	if err != nil {
+		return errorHandler(state, ui, "Could not disable rescue mode", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not disable rescue mode", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPostProvisionRescue) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// rootPartitionFile stores the number of the root partition detected in the
+// rescue system, for the next shrink commands.
+const rootPartitionFile = "/tmp/packer-root-partition"
+
+// shrinkDiskCommands returns the commands shrinking the ext4 root filesystem
+// and its partition to fit in the given size. The root partition is detected
+// as the last partition on the disk, and the commands fail unless it holds an
+// ext4 filesystem, as anything else cannot be shrunk safely.
+func shrinkDiskCommands(sizeGB int) []string {
+	// Keep one GB of margin for the partitions located before the root partition.
+	size := sizeGB - 1
+	partition := fmt.Sprintf("/dev/sda$(cat %s)", rootPartitionFile)
+	return []string{
+		fmt.Sprintf("sfdisk -d /dev/sda | awk -F'[ :,=]+' '/^\\/dev\\/sda/ { if ($3 > start) { start = $3; number = substr($1, 9) } } END { print number }' > %[1]s && "+
+			"[ -s %[1]s ] && [ \"$(blkid -o value -s TYPE %[2]s)\" = ext4 ] || "+
+			"{ echo 'the last partition of /dev/sda does not hold an ext4 filesystem' >&2; exit 1; }", rootPartitionFile, partition),
+		// e2fsck exits with 1 when it corrected errors of the filesystem
+		fmt.Sprintf("e2fsck -f -y %s || [ $? -le 1 ]", partition),
+		fmt.Sprintf("resize2fs %s %dG", partition, size),
+		fmt.Sprintf("echo ',%dG' | sfdisk --no-reread -N $(cat %s) /dev/sda", size, rootPartitionFile),
+		"partprobe /dev/sda",
+		fmt.Sprintf("e2fsck -f -y %s || [ $? -le 1 ]", partition),
+	}
+}
@@ -0,0 +1,116 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepPostProvisionRescue(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepPostProvisionRescue{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail to enable rescue",
+			Step: &stepPostProvisionRescue{},
+			SetupConfigFunc: func(c *Config) {
+				c.PostProvisionRescueCommands = []string{"resize2fs -M /dev/sda1"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerIP, "1.2.3.4")
+				state.Put(StateSSHKeyID, int64(1))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/enable_rescue",
+					Status: 423,
+					JSONRaw: `{
+						"error": { "code": "locked", "message": "server is locked" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.ErrorContains(t, err, "could not enable rescue mode: server is locked (locked)")
+			},
+		},
+		{
+			Name: "shut down cleanly before the rescue system",
+			Step: &stepPostProvisionRescue{},
+			SetupConfigFunc: func(c *Config) {
+				c.PostProvisionRescueCommands = []string{"resize2fs -M /dev/sda1"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerIP, "1.2.3.4")
+				state.Put(StateSSHKeyID, int64(1))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/enable_rescue",
+					Status: 201,
+					JSONRaw: `{
+						"root_password": "secret",
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/shutdown",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "success" }
+					}`,
+				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/servers/8",
+					Status: 200,
+					JSONRaw: `{
+						"server": { "id": 8, "status": "off" }
+					}`,
+				},
+				{Method: "POST", Path: "/servers/8/actions/poweron",
+					Status: 423,
+					JSONRaw: `{
+						"error": { "code": "locked", "message": "server is locked" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.ErrorContains(t, err, "could not power on server: server is locked (locked)")
+			},
+		},
+	})
+}
+
+func TestShrinkDiskCommands(t *testing.T) {
+	commands := shrinkDiskCommands(10)
+	require.Len(t, commands, 6)
+	assert.Contains(t, commands[0], "sfdisk -d /dev/sda | awk")
+	assert.Contains(t, commands[0], `[ "$(blkid -o value -s TYPE /dev/sda$(cat /tmp/packer-root-partition))" = ext4 ]`)
+	assert.Equal(t, []string{
+		"e2fsck -f -y /dev/sda$(cat /tmp/packer-root-partition) || [ $? -le 1 ]",
+		"resize2fs /dev/sda$(cat /tmp/packer-root-partition) 9G",
+		"echo ',9G' | sfdisk --no-reread -N $(cat /tmp/packer-root-partition) /dev/sda",
+		"partprobe /dev/sda",
+		"e2fsck -f -y /dev/sda$(cat /tmp/packer-root-partition) || [ $? -le 1 ]",
+	}, commands[1:])
+}
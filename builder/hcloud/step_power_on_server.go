@@ -0,0 +1,42 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepPowerOnServer starts the server created with start_after_create false,
+// once the volumes, networks and ISO were attached, so the devices are present
+// at the first boot.
+type stepPowerOnServer struct{}
+
+func (s *stepPowerOnServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if c.startsAfterCreate() {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	ui.Say("Starting server...")
+	action, _, err := client.Server.Poweron(ctx, &hcloud.Server{ID: serverID})
+	if err != nil {
+		return errorHandler(state, ui, "Could not start server", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not start server", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepPowerOnServer) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -7,6 +7,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
 
 	"github.com/hashicorp/packer-plugin-sdk/multistep"
 
@@ -23,6 +24,64 @@ type stepPreValidate struct {
 func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
 	c, ui, client := UnpackState(state)
 
+	var datacenters []*hcloud.Datacenter
+	if c.Datacenter != "" {
+		ui.Say(fmt.Sprintf("Validating datacenter: %s", c.Datacenter))
+		datacenter, _, err := client.Datacenter.Get(ctx, c.Datacenter)
+		if err != nil {
+			return errorHandler(state, ui, fmt.Sprintf("Could not fetch datacenter '%s'", c.Datacenter), err)
+		}
+		if datacenter == nil {
+			return errorHandler(state, ui, "", fmt.Errorf("Could not find datacenter '%s'", c.Datacenter))
+		}
+		// The location of the datacenter is used to look up the prices, the
+		// network zone, and the other location bound resources
+		c.Location = datacenter.Location.Name
+		datacenters = []*hcloud.Datacenter{datacenter}
+	}
+
+	// The datacenters of the location are needed to resolve a server type
+	// which can be created, and to check the availability below
+	where := c.Datacenter
+	if where == "" {
+		where = c.Location
+		all, err := client.Datacenter.All(ctx)
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch datacenters", err)
+		}
+		datacenters = slices.DeleteFunc(all, func(datacenter *hcloud.Datacenter) bool {
+			return datacenter.Location == nil || datacenter.Location.Name != c.Location
+		})
+	}
+
+	if c.ServerTypeSpec != nil && c.ServerType == "" {
+		ui.Say(fmt.Sprintf("Resolving the cheapest %s...", c.ServerTypeSpec))
+		serverTypes, err := client.ServerType.All(ctx)
+		if err != nil {
+			return errorHandler(state, ui, "Could not fetch server types", err)
+		}
+		var forbidden []string
+		for _, p := range c.policies {
+			forbidden = append(forbidden, p.ForbiddenServerTypes...)
+		}
+		serverType, err := resolveServerType(serverTypes, c.ServerTypeSpec, c.Location, datacenters, forbidden, c.MaxHourlyPrice)
+		if err != nil {
+			return errorHandler(state, ui, "", err)
+		}
+		ui.Message(fmt.Sprintf("Resolved server type: %s", serverType.Name))
+		c.ServerType = serverType.Name
+	}
+
+	// The policies were checked in Prepare, but the location of the datacenter
+	// and the resolved server type are only known now
+	var violations []error
+	for _, p := range c.policies {
+		violations = append(violations, p.checkPlacement(c)...)
+	}
+	if len(violations) > 0 {
+		return errorHandler(state, ui, "", errors.Join(violations...))
+	}
+
 	ui.Say(fmt.Sprintf("Validating server types: %s", c.ServerType))
 	serverType, _, err := client.ServerType.Get(ctx, c.ServerType)
 	if err != nil {
@@ -31,11 +90,17 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 	if serverType == nil {
 		return errorHandler(state, ui, "", fmt.Errorf("Could not find server type '%s'", c.ServerType))
 	}
+	if c.Architecture != "" && serverType.Architecture != hcloud.Architecture(c.Architecture) {
+		return errorHandler(state, ui, "", fmt.Errorf(
+			"server type '%s' has the architecture '%s', but architecture '%s' is required",
+			c.ServerType, serverType.Architecture, c.Architecture))
+	}
 	state.Put(StateServerType, serverType)
 
+	var upgradeServerType *hcloud.ServerType
 	if c.UpgradeServerType != "" {
 		ui.Say(fmt.Sprintf("Validating upgrade server types: %s", c.UpgradeServerType))
-		upgradeServerType, _, err := client.ServerType.Get(ctx, c.UpgradeServerType)
+		upgradeServerType, _, err = client.ServerType.Get(ctx, c.UpgradeServerType)
 		if err != nil {
 			return errorHandler(state, ui, fmt.Sprintf("Could not fetch upgrade server type '%s'", c.UpgradeServerType), err)
 		}
@@ -50,6 +115,17 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 		}
 	}
 
+	// Checking the availability here fails the build before the SSH key is
+	// uploaded and the IPs are allocated, instead of at the server creation
+	for _, st := range []*hcloud.ServerType{serverType, upgradeServerType} {
+		if st == nil {
+			continue
+		}
+		if err := checkServerTypeAvailable(datacenters, st, where); err != nil {
+			return errorHandler(state, ui, "", err)
+		}
+	}
+
 	ui.Say(fmt.Sprintf("Validating snapshot name: %s", s.SnapshotName))
 
 	// We would like to ask only for snapshots with a certain name using
@@ -59,7 +135,9 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 		Type:         []hcloud.ImageType{hcloud.ImageTypeSnapshot},
 		Architecture: []hcloud.Architecture{serverType.Architecture},
 	}
-	snapshots, err := client.Image.AllWithOpts(ctx, opts)
+	snapshots, err := listAll(ctx, c, ui, "snapshots", &opts.ListOpts, func(ctx context.Context) ([]*hcloud.Image, *hcloud.Response, error) {
+		return client.Image.List(ctx, opts)
+	})
 	if err != nil {
 		return errorHandler(state, ui, "Could not fetch snapshots", err)
 	}
@@ -88,3 +166,21 @@ func (s *stepPreValidate) Run(ctx context.Context, state multistep.StateBag) mul
 // No-op
 func (s *stepPreValidate) Cleanup(multistep.StateBag) {
 }
+
+// checkServerTypeAvailable returns an error unless the server type can
+// currently be created in one of the datacenters.
+func checkServerTypeAvailable(datacenters []*hcloud.Datacenter, serverType *hcloud.ServerType, where string) error {
+	sameType := func(other *hcloud.ServerType) bool { return other.ID == serverType.ID }
+
+	supported := false
+	for _, datacenter := range datacenters {
+		if slices.ContainsFunc(datacenter.ServerTypes.Available, sameType) {
+			return nil
+		}
+		supported = supported || slices.ContainsFunc(datacenter.ServerTypes.Supported, sameType)
+	}
+	if supported {
+		return fmt.Errorf("%s is temporarily not available in %s", serverType.Name, where)
+	}
+	return fmt.Errorf("%s is not offered in %s", serverType.Name, where)
+}
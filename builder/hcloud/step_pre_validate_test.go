@@ -25,6 +25,15 @@ func TestStepPreValidate(t *testing.T) {
 				c.UpgradeServerType = "cpx21"
 			},
 			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" },
+								"server_types": { "supported": [9, 10], "available": [9, 10] }}
+						]
+					}`,
+				},
 				{Method: "GET", Path: "/server_types?name=cpx11",
 					Status: 200,
 					JSONRaw: `{
@@ -54,6 +63,188 @@ func TestStepPreValidate(t *testing.T) {
 				assert.False(t, ok)
 			},
 		},
+		{
+			Name: "with datacenter",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.Location = ""
+				c.Datacenter = "fsn1-dc14"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?name=fsn1-dc14",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [{ "id": 4, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" },
+							"server_types": { "supported": [9], "available": [9] }}]
+					}`,
+				},
+				{Method: "GET", Path: "/server_types?name=cpx11",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 9, "name": "cpx11", "architecture": "x86"}]
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": []
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				c, _, _ := UnpackState(state)
+				assert.Equal(t, "fsn1", c.Location)
+			},
+		},
+		{
+			Name: "fail with location of datacenter forbidden by policy",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.Location = ""
+				c.Datacenter = "fsn1-dc14"
+				c.policies = []*policy{{ForbiddenLocations: []string{"fsn1"}, source: "block"}}
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?name=fsn1-dc14",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [{ "id": 4, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" },
+							"server_types": { "supported": [9], "available": [9] }}]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "policy block: location 'fsn1' is forbidden")
+			},
+		},
+		{
+			Name: "fail with unknown datacenter",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.Location = ""
+				c.Datacenter = "fsn1-dc99"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?name=fsn1-dc99",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": []
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+		},
+		{
+			Name: "fail with server type not offered in location",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.ServerType = "cax11"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" },
+								"server_types": { "supported": [9], "available": [9] }},
+							{ "id": 4, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" },
+								"server_types": { "supported": [9, 45], "available": [9, 45] }}
+						]
+					}`,
+				},
+				{Method: "GET", Path: "/server_types?name=cax11",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 45, "name": "cax11", "architecture": "arm"}]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "cax11 is not offered in nbg1")
+			},
+		},
+		{
+			Name: "fail with upgrade server type temporarily not available",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.Location = ""
+				c.Datacenter = "fsn1-dc14"
+				c.UpgradeServerType = "cpx21"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?name=fsn1-dc14",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [{ "id": 4, "name": "fsn1-dc14", "location": { "id": 1, "name": "fsn1" },
+							"server_types": { "supported": [9, 10], "available": [9] }}]
+					}`,
+				},
+				{Method: "GET", Path: "/server_types?name=cpx11",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 9, "name": "cpx11", "architecture": "x86"}]
+					}`,
+				},
+				{Method: "GET", Path: "/server_types?name=cpx21",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 10, "name": "cpx21", "architecture": "x86"}]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "cpx21 is temporarily not available in fsn1-dc14")
+			},
+		},
+		{
+			Name: "fail with architecture mismatch",
+			Step: &stepPreValidate{
+				SnapshotName: "dummy-snapshot",
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.Architecture = "arm"
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": []
+					}`,
+				},
+				{Method: "GET", Path: "/server_types?name=cpx11",
+					Status: 200,
+					JSONRaw: `{
+						"server_types": [{ "id": 9, "name": "cpx11", "architecture": "x86"}]
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "server type 'cpx11' has the architecture 'x86', but architecture 'arm' is required")
+			},
+		},
 		{
 			Name: "fail with existing snapshot",
 			Step: &stepPreValidate{
@@ -64,6 +255,15 @@ func TestStepPreValidate(t *testing.T) {
 				c.UpgradeServerType = "cpx21"
 			},
 			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" },
+								"server_types": { "supported": [9, 10], "available": [9, 10] }}
+						]
+					}`,
+				},
 				{Method: "GET", Path: "/server_types?name=cpx11",
 					Status: 200,
 					JSONRaw: `{
@@ -108,6 +308,15 @@ func TestStepPreValidate(t *testing.T) {
 				c.UpgradeServerType = "cpx21"
 			},
 			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/datacenters?page=1&per_page=50",
+					Status: 200,
+					JSONRaw: `{
+						"datacenters": [
+							{ "id": 2, "name": "nbg1-dc3", "location": { "id": 2, "name": "nbg1" },
+								"server_types": { "supported": [9, 10], "available": [9, 10] }}
+						]
+					}`,
+				},
 				{Method: "GET", Path: "/server_types?name=cpx11",
 					Status: 200,
 					JSONRaw: `{
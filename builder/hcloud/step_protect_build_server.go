@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepProtectBuildServer enables the delete protection on the build server for
+// the duration of the build, so external cleanup scripts cannot remove it while
+// the build is still running.
+type stepProtectBuildServer struct {
+	serverId int64
+}
+
+func (s *stepProtectBuildServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.ProtectBuildServer {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	ui.Say("Enabling server delete protection...")
+	action, _, err := client.Server.ChangeProtection(ctx, &hcloud.Server{ID: serverID}, hcloud.ServerChangeProtectionOpts{
+		Delete:  hcloud.Ptr(true),
+		Rebuild: hcloud.Ptr(true),
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not enable server protection", err)
+	}
+
+	// We use this in cleanup
+	s.serverId = serverID
+
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not enable server protection", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepProtectBuildServer) Cleanup(state multistep.StateBag) {
+	// If the serverID isn't there, we never enabled the protection
+	if s.serverId == 0 {
+		return
+	}
+
+	c, ui, client := UnpackState(state)
+
+	if c.KeepServer && c.ProtectServer {
+		// The kept server stays protected, see stepProtectKeptServer
+		return
+	}
+
+	ui.Say("Disabling server delete protection...")
+	action, _, err := client.Server.ChangeProtection(context.TODO(), &hcloud.Server{ID: s.serverId}, hcloud.ServerChangeProtectionOpts{
+		Delete:  hcloud.Ptr(false),
+		Rebuild: hcloud.Ptr(false),
+	})
+	if err != nil {
+		errorHandler(state, ui, "Could not disable server protection", err)
+		return
+	}
+	if err := client.Action.WaitFor(context.TODO(), action); err != nil {
+		errorHandler(state, ui, "Could not disable server protection", err)
+	}
+}
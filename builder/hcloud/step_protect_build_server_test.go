@@ -0,0 +1,104 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepProtectBuildServer(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepProtectBuildServer{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepProtectBuildServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.ProtectBuildServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/change_protection",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionChangeProtectionRequest{})
+						assert.True(t, *payload.Delete)
+						assert.True(t, *payload.Rebuild)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
+
+func TestStepCleanupProtectBuildServer(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:         "happy",
+			Step:         &stepProtectBuildServer{serverId: 8},
+			StepFuncName: "cleanup",
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/change_protection",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionChangeProtectionRequest{})
+						assert.False(t, *payload.Delete)
+						assert.False(t, *payload.Rebuild)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "running" }
+					}`,
+				},
+				{Method: "GET", Path: "/actions?id=3&page=1&sort=status&sort=id",
+					Status: 200,
+					JSONRaw: `{
+						"actions": [
+							{ "id": 3, "status": "success" }
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+		},
+		{
+			Name:         "kept protected server",
+			Step:         &stepProtectBuildServer{serverId: 8},
+			StepFuncName: "cleanup",
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.ProtectServer = true
+			},
+			WantRequests: []mockutil.Request{},
+		},
+	})
+}
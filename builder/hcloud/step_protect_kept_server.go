@@ -0,0 +1,44 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepProtectKeptServer enables the delete and rebuild protection on the kept
+// server once the build succeeded, so it cannot be deleted by accident.
+type stepProtectKeptServer struct{}
+
+func (s *stepProtectKeptServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if !c.KeepServer || !c.ProtectServer {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	ui.Say("Enabling delete and rebuild protection on the kept server...")
+	action, _, err := client.Server.ChangeProtection(ctx, &hcloud.Server{ID: serverID}, hcloud.ServerChangeProtectionOpts{
+		Delete:  hcloud.Ptr(true),
+		Rebuild: hcloud.Ptr(true),
+	})
+	if err != nil {
+		return errorHandler(state, ui, "Could not enable server protection", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not enable server protection", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepProtectKeptServer) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
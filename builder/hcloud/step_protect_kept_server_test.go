@@ -0,0 +1,57 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepProtectKeptServer(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepProtectKeptServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepProtectKeptServer{},
+			SetupConfigFunc: func(c *Config) {
+				c.KeepServer = true
+				c.ProtectServer = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/change_protection",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionChangeProtectionRequest{})
+						assert.True(t, *payload.Delete)
+						assert.True(t, *payload.Rebuild)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
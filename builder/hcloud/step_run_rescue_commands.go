@@ -0,0 +1,68 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"slices"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepRunRescueCommands runs the rescue_commands on the rescue system the
+// server booted into, e.g. to partition the disk or write an image, then
+// reboots the server into the installed OS for the regular provisioning.
+type stepRunRescueCommands struct{}
+
+func (s *stepRunRescueCommands) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if len(c.RescueCommands) == 0 {
+		return multistep.ActionContinue
+	}
+
+	server := &hcloud.Server{ID: state.Get(StateServerID).(int64)}
+	serverIP := state.Get(StateServerIP).(string)
+
+	ui.Say("Connecting to the rescue system...")
+	comm, err := connectSSH(ctx, c, serverIP, rescueUsername)
+	if err != nil {
+		return errorHandler(state, ui, "Could not connect to the rescue system", err)
+	}
+
+	ui.Say("Running rescue commands...")
+	// The server is reset below, the writes of the commands must be flushed to
+	// the disk before
+	err = runCommands(ctx, ui, comm, append(slices.Clone(c.RescueCommands), "sync"))
+	comm.Close()
+	if err != nil {
+		return errorHandler(state, ui, "Could not run rescue commands", err)
+	}
+
+	ui.Say("Disabling Rescue Mode and rebooting server...")
+	action, _, err := client.Server.DisableRescue(ctx, server)
+	if err != nil {
+		return errorHandler(state, ui, "Could not disable rescue mode", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not disable rescue mode", err)
+	}
+
+	action, _, err = client.Server.Reset(ctx, server)
+	if err != nil {
+		return errorHandler(state, ui, "Could not reboot server", err)
+	}
+	if err := client.Action.WaitFor(ctx, action); err != nil {
+		return errorHandler(state, ui, "Could not reboot server", err)
+	}
+	state.Put(StateRescueActive, false)
+
+	return multistep.ActionContinue
+}
+
+func (s *stepRunRescueCommands) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
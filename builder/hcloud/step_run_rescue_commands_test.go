@@ -0,0 +1,43 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepRunRescueCommands(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepRunRescueCommands{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "fail to connect",
+			Step: &stepRunRescueCommands{},
+			SetupConfigFunc: func(c *Config) {
+				c.RescueMode = "linux64"
+				c.RescueCommands = []string{"wipefs -a /dev/sda"}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+				state.Put(StateServerIP, "1.2.3.4")
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.ErrorContains(t, err, "Could not connect to the rescue system: could not parse SSH private key")
+			},
+		},
+	})
+}
@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepShowLabelsDiff prints the changes of the labels and metadata of the
+// snapshot, compared to the previous most recent snapshot matching the
+// labels_diff_selector, so reviewers see what changed between the image
+// generations.
+type stepShowLabelsDiff struct{}
+
+func (s *stepShowLabelsDiff) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	snapshotID, ok := state.GetOk(StateSnapshotID)
+	if c.LabelsDiffSelector == "" || !ok {
+		return multistep.ActionContinue
+	}
+
+	// The diff is informative, and must not fail the build
+	snapshot, _, err := client.Image.GetByID(ctx, snapshotID.(int64))
+	if err != nil || snapshot == nil {
+		ui.Errorf("Could not fetch snapshot %d to compare it: %v", snapshotID, err)
+		return multistep.ActionContinue
+	}
+
+	opts := hcloud.ImageListOpts{
+		ListOpts:     hcloud.ListOpts{LabelSelector: c.LabelsDiffSelector},
+		Type:         []hcloud.ImageType{hcloud.ImageTypeSnapshot},
+		Architecture: []hcloud.Architecture{snapshot.Architecture},
+	}
+	images, err := listAll(ctx, c, ui, "snapshots", &opts.ListOpts, func(ctx context.Context) ([]*hcloud.Image, *hcloud.Response, error) {
+		return client.Image.List(ctx, opts)
+	})
+	if err != nil {
+		ui.Errorf("Could not fetch the snapshots matching '%s' to compare them: %s", c.LabelsDiffSelector, err)
+		return multistep.ActionContinue
+	}
+
+	var previous *hcloud.Image
+	for _, image := range images {
+		// The snapshots of a previous attempt of this build are not a previous
+		// generation
+		if buildID, ok := image.Labels[buildIDLabel]; image.ID == snapshot.ID || ok && buildID == c.buildID {
+			continue
+		}
+		if previous == nil || image.Created.After(previous.Created) {
+			previous = image
+		}
+	}
+	if previous == nil {
+		ui.Say(fmt.Sprintf("No previous snapshot matches '%s', nothing to compare", c.LabelsDiffSelector))
+		return multistep.ActionContinue
+	}
+
+	ui.Say(fmt.Sprintf("Changes since snapshot %d (%s):", previous.ID, previous.Description))
+	changes := imageDiff(previous, snapshot)
+	if len(changes) == 0 {
+		ui.Message("no changes")
+	}
+	for _, change := range changes {
+		ui.Message(change)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepShowLabelsDiff) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// imageDiff returns the changes between the images, one line per changed
+// metadata or label, prefixed with ~, + or -. The build id label, changing
+// with every build, is left out.
+func imageDiff(previous, current *hcloud.Image) []string {
+	var changes []string
+	for _, field := range []struct {
+		name     string
+		previous string
+		current  string
+	}{
+		{"description", previous.Description, current.Description},
+		{"os_flavor", previous.OSFlavor, current.OSFlavor},
+		{"os_version", previous.OSVersion, current.OSVersion},
+		{"disk_size", strconv.FormatFloat(float64(previous.DiskSize), 'f', -1, 32), strconv.FormatFloat(float64(current.DiskSize), 'f', -1, 32)},
+	} {
+		if field.previous != field.current {
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", field.name, field.previous, field.current))
+		}
+	}
+	return append(changes, labelsDiff(previous.Labels, current.Labels)...)
+}
+
+// labelsDiff returns the added (+), removed (-) and changed (~) labels, sorted
+// by key.
+func labelsDiff(previous, current map[string]string) []string {
+	keys := make([]string, 0, len(previous)+len(current))
+	for key := range previous {
+		keys = append(keys, key)
+	}
+	for key := range current {
+		if _, ok := previous[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+
+	var changes []string
+	for _, key := range keys {
+		if key == buildIDLabel {
+			continue
+		}
+		old, inPrevious := previous[key]
+		value, inCurrent := current[key]
+		switch {
+		case !inPrevious:
+			changes = append(changes, fmt.Sprintf("+ %s=%s", key, value))
+		case !inCurrent:
+			changes = append(changes, fmt.Sprintf("- %s=%s", key, old))
+		case old != value:
+			changes = append(changes, fmt.Sprintf("~ %s: %s -> %s", key, old, value))
+		}
+	}
+	return changes
+}
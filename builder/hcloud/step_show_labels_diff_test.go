@@ -0,0 +1,95 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepShowLabelsDiff(t *testing.T) {
+	ui := &packersdk.MockUi{}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "disabled",
+			Step: &stepShowLabelsDiff{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSnapshotID, int64(16))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepShowLabelsDiff{},
+			SetupConfigFunc: func(c *Config) {
+				c.LabelsDiffSelector = "role=web"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateUI, ui)
+				state.Put(StateSnapshotID, int64(16))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "GET", Path: "/images/16",
+					Status: 200,
+					JSONRaw: `{
+						"image": { "id": 16, "description": "web-2", "architecture": "x86", "disk_size": 20,
+						           "labels": { "role": "web", "version": "2" }}
+					}`,
+				},
+				{Method: "GET", Path: "/images?architecture=x86&label_selector=role%3Dweb&page=1&type=snapshot",
+					Status: 200,
+					JSONRaw: `{
+						"images": [
+							{ "id": 16, "description": "web-2", "created": "2026-10-02T00:00:00Z",
+							  "labels": { "role": "web", "version": "2" }},
+							{ "id": 14, "description": "web-1", "created": "2026-10-01T00:00:00Z", "disk_size": 20,
+							  "labels": { "role": "web", "version": "1", "beta": "true" }},
+							{ "id": 12, "description": "web-0", "created": "2026-09-01T00:00:00Z",
+							  "labels": { "role": "web" }}
+						],
+						"meta": { "pagination": { "page": 1 }}
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				messages := make([]string, 0, len(ui.SayMessages))
+				for _, message := range ui.SayMessages {
+					messages = append(messages, message.Message)
+				}
+				assert.Contains(t, messages, "Changes since snapshot 14 (web-1):")
+				assert.Equal(t, "~ version: 1 -> 2", ui.MessageMessage)
+			},
+		},
+	})
+}
+
+func TestImageDiff(t *testing.T) {
+	previous := &hcloud.Image{
+		Description: "web-1",
+		DiskSize:    20,
+		Labels:      map[string]string{"role": "web", "version": "1", "beta": "true", buildIDLabel: "a"},
+	}
+	current := &hcloud.Image{
+		Description: "web-2",
+		DiskSize:    20,
+		Labels:      map[string]string{"role": "web", "version": "2", "team": "builds", buildIDLabel: "b"},
+	}
+	assert.Equal(t, []string{
+		"~ description: web-1 -> web-2",
+		"- beta=true",
+		"+ team=builds",
+		"~ version: 1 -> 2",
+	}, imageDiff(previous, current))
+
+	assert.Empty(t, labelsDiff(previous.Labels, previous.Labels))
+}
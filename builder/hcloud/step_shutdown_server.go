@@ -15,7 +15,12 @@ type stepShutdownServer struct{}
 
 //nolint:gosimple,goimports
 func (s *stepShutdownServer) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
-	_, ui, client := UnpackState(state)
+	c, ui, client := UnpackState(state)
+
+	if c.LiveSnapshot {
+		ui.Say("Keeping the server running for a live snapshot")
+		return multistep.ActionContinue
+	}
 
 	serverID := state.Get(StateServerID).(int64)
 
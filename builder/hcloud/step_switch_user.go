@@ -0,0 +1,73 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// stepSwitchUser authorizes the temporary SSH key for the provisioning user,
+// while being connected as the initial user of the image. The initial user must
+// be allowed to run commands with sudo without password.
+type stepSwitchUser struct{}
+
+func (s *stepSwitchUser) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.SSHInitialUsername == "" {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	if c.Comm.SSHPublicKey == nil {
+		return errorHandler(state, ui, "", fmt.Errorf("missing SSH public key in communicator"))
+	}
+
+	ui.Say(fmt.Sprintf("Authorizing temporary SSH key for user '%s'...", c.Comm.SSHUsername))
+	cmd := &packersdk.RemoteCmd{
+		Command: switchUserCommand(c.Comm.SSHUsername, strings.TrimSpace(string(c.Comm.SSHPublicKey))),
+	}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return errorHandler(state, ui, "Could not authorize temporary SSH key", err)
+	}
+	if cmd.ExitStatus() != 0 {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not authorize temporary SSH key: exit status %d", cmd.ExitStatus()))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepSwitchUser) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// initialSSHConfig returns the SSH configuration connecting as the
+// ssh_initial_username with the temporary key of the communicator, which is
+// only known once it was generated, after the steps were built.
+func initialSSHConfig(initialComm, comm *communicator.Config) func(multistep.StateBag) (*gossh.ClientConfig, error) {
+	return func(state multistep.StateBag) (*gossh.ClientConfig, error) {
+		initialComm.SSHPrivateKey = comm.SSHPrivateKey
+		return initialComm.SSHConfigFunc()(state)
+	}
+}
+
+func switchUserCommand(username, publicKey string) string {
+	script := strings.Join([]string{
+		"set -e",
+		fmt.Sprintf(`home=$(getent passwd %s | cut -d: -f6)`, username),
+		fmt.Sprintf(`install -d -m 700 -o %s "$home/.ssh"`, username),
+		fmt.Sprintf(`echo "%s" >> "$home/.ssh/authorized_keys"`, publicKey),
+		fmt.Sprintf(`chown %s "$home/.ssh/authorized_keys"`, username),
+		`chmod 600 "$home/.ssh/authorized_keys"`,
+	}, "; ")
+	return fmt.Sprintf("sudo -n sh -c '%s'", script)
+}
@@ -0,0 +1,72 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepSwitchUser(t *testing.T) {
+	comm := &packersdk.MockCommunicator{}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepSwitchUser{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepSwitchUser{},
+			SetupConfigFunc: func(c *Config) {
+				c.SSHInitialUsername = "admin"
+				c.Comm.SSHUsername = "root"
+				c.Comm.SSHPublicKey = []byte("ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILBN85MgkHac/Q+iyPS8+88eBDn2SEGnU4/uLvj6lbT0\n")
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, comm)
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.True(t, comm.StartCalled)
+				assert.Contains(t, comm.StartCmd.Command, "getent passwd root")
+				assert.Contains(t, comm.StartCmd.Command, `echo "ssh-ed25519 AAAAC3NzaC1lZDI1NTE5AAAAILBN85MgkHac/Q+iyPS8+88eBDn2SEGnU4/uLvj6lbT0" >> "$home/.ssh/authorized_keys"`)
+			},
+		},
+	})
+}
+
+func TestInitialSSHConfig(t *testing.T) {
+	comm := communicator.Config{Type: "ssh"}
+	comm.SSHUsername = "root"
+	initialComm := comm
+	initialComm.SSHUsername = "admin"
+	sshConfig := initialSSHConfig(&initialComm, &comm)
+
+	// The temporary key is generated after the steps were built
+	_, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	block, err := gossh.MarshalPrivateKey(privateKey, "")
+	require.NoError(t, err)
+	comm.SSHPrivateKey = pem.EncodeToMemory(block)
+
+	config, err := sshConfig(&multistep.BasicStateBag{})
+	require.NoError(t, err)
+	assert.Equal(t, "admin", config.User)
+	assert.Len(t, config.Auth, 1)
+}
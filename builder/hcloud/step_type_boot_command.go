@@ -0,0 +1,85 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/bootcommand"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepTypeBootCommand types the boot_command over the VNC console of the
+// server, e.g. to drive the installer of an ISO, before the communicator
+// connects.
+type stepTypeBootCommand struct {
+	// dial connects to the console, replaced in the tests.
+	dial func(ctx context.Context, wssURL, password string) (bootcommand.VNCKeyEvent, error)
+}
+
+func (s *stepTypeBootCommand) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if len(c.BootCommand) == 0 {
+		return multistep.ActionContinue
+	}
+
+	serverID := state.Get(StateServerID).(int64)
+
+	if c.BootWait > 0 {
+		ui.Say(fmt.Sprintf("Waiting %s for boot...", c.BootWait))
+		select {
+		case <-ctx.Done():
+			return multistep.ActionHalt
+		case <-time.After(c.BootWait):
+		}
+	}
+
+	ui.Say("Connecting to the VNC console...")
+	result, _, err := client.Server.RequestConsole(ctx, &hcloud.Server{ID: serverID})
+	if err != nil {
+		return errorHandler(state, ui, "Could not request console", err)
+	}
+	packersdk.LogSecretFilter.Set(result.Password)
+
+	dial := s.dial
+	if dial == nil {
+		dial = func(ctx context.Context, wssURL, password string) (bootcommand.VNCKeyEvent, error) {
+			return dialConsole(ctx, wssURL, password)
+		}
+	}
+	console, err := dial(ctx, result.WSSURL, result.Password)
+	if err != nil {
+		return errorHandler(state, ui, "Could not connect to the VNC console", err)
+	}
+	if closer, ok := console.(interface{ Close() error }); ok {
+		defer closer.Close()
+	}
+
+	command, err := interpolate.Render(c.FlatBootCommand(), &c.ctx)
+	if err != nil {
+		return errorHandler(state, ui, "Could not render boot_command", err)
+	}
+	seq, err := bootcommand.GenerateExpressionSequence(command)
+	if err != nil {
+		return errorHandler(state, ui, "Could not parse boot_command", err)
+	}
+
+	ui.Say("Typing the boot command over VNC...")
+	if err := seq.Do(ctx, bootcommand.NewVNCDriver(console, c.BootKeyInterval)); err != nil {
+		return errorHandler(state, ui, "Could not type boot_command", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepTypeBootCommand) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,74 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/bootcommand"
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+type fakeKeyEvents struct {
+	keys []uint32
+}
+
+func (f *fakeKeyEvents) KeyEvent(key uint32, down bool) error {
+	if down {
+		f.keys = append(f.keys, key)
+	}
+	return nil
+}
+
+func TestStepTypeBootCommand(t *testing.T) {
+	events := &fakeKeyEvents{}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "no boot command",
+			Step: &stepTypeBootCommand{},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepTypeBootCommand{
+				dial: func(_ context.Context, wssURL, password string) (bootcommand.VNCKeyEvent, error) {
+					assert.Equal(t, "wss://console.hetzner.cloud/?server_id=8&token=abc", wssURL)
+					assert.Equal(t, "secret", password)
+					return events, nil
+				},
+			},
+			SetupConfigFunc: func(c *Config) {
+				c.BootCommand = []string{"ab"}
+				c.BootKeyInterval = time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/request_console",
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "success" },
+						"wss_url": "wss://console.hetzner.cloud/?server_id=8&token=abc",
+						"password": "secret"
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, []uint32{'a', 'b'}, events.keys)
+			},
+		},
+	})
+}
@@ -0,0 +1,38 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepUpgradeServerType upgrades the type of the server after the provisioning,
+// once the server is shut down, with upgrade_server_type_timing set to
+// after_provisioning.
+type stepUpgradeServerType struct{}
+
+func (s *stepUpgradeServerType) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if c.UpgradeServerType == "" || c.upgradesBeforeProvisioning() {
+		return multistep.ActionContinue
+	}
+
+	server := &hcloud.Server{ID: state.Get(StateServerID).(int64)}
+
+	ui.Say("Upgrading server type...")
+	if err := changeServerType(ctx, client, server, c.UpgradeServerType, c.UpgradeDisk); err != nil {
+		return errorHandler(state, ui, "Could not upgrade server type", err)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepUpgradeServerType) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepUpgradeServerType(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name: "upgraded before provisioning",
+			Step: &stepUpgradeServerType{},
+			SetupConfigFunc: func(c *Config) {
+				c.UpgradeServerType = "cpx31"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepUpgradeServerType{},
+			SetupConfigFunc: func(c *Config) {
+				c.UpgradeServerType = "cpx31"
+				c.UpgradeServerTypeTiming = upgradeAfterProvisioning
+				c.UpgradeDisk = true
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerID, int64(8))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers/8/actions/change_type",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerActionChangeTypeRequest{})
+						assert.Equal(t, "cpx31", payload.ServerType.Name)
+						assert.True(t, payload.UpgradeDisk)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+	})
+}
@@ -0,0 +1,111 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	gossh "golang.org/x/crypto/ssh"
+)
+
+// stepValidateSSHD verifies after the provisioning, while the build keys are
+// still authorized, that a new SSH connection is accepted and that the sshd
+// configuration of the image does not lock out its future users.
+type stepValidateSSHD struct {
+	SSHConfig func(multistep.StateBag) (*gossh.ClientConfig, error)
+}
+
+func (s *stepValidateSSHD) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if !c.ValidateSSHD {
+		return multistep.ActionContinue
+	}
+
+	ui.Say("Validating the sshd configuration...")
+
+	if c.Comm.SSHBastionHost != "" || c.Comm.SSHProxyHost != "" {
+		ui.Message("Skipping the new SSH connection, as the server is reached over a bastion or a proxy")
+	} else if err := s.handshake(state); err != nil {
+		ui.Error(fmt.Sprintf("Warning: sshd does not accept new connections with the build key anymore: %s", err))
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	command := "/usr/sbin/sshd -T"
+	if c.Comm.SSHUsername != "root" {
+		command = "sudo -n " + command
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &stdout, Stderr: &stderr}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return errorHandler(state, ui, "Could not validate the sshd configuration", err)
+	}
+	if status := cmd.Wait(); status != 0 {
+		ui.Error(fmt.Sprintf("Warning: the sshd configuration is invalid, sshd will not start: %s",
+			strings.TrimSpace(stderr.String())))
+		return multistep.ActionContinue
+	}
+
+	for _, warning := range sshdLockoutWarnings(stdout.String()) {
+		ui.Error("Warning: " + warning)
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepValidateSSHD) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// handshake opens a new SSH connection to the server, to verify that sshd
+// still accepts the build key after the provisioning.
+func (s *stepValidateSSHD) handshake(state multistep.StateBag) error {
+	c, _, _ := UnpackState(state)
+
+	config, err := s.SSHConfig(state)
+	if err != nil {
+		return err
+	}
+	config.Timeout = 30 * time.Second
+
+	address := net.JoinHostPort(state.Get(StateServerIP).(string), strconv.Itoa(c.Comm.Port()))
+	client, err := gossh.Dial("tcp", address, config)
+	if err != nil {
+		return err
+	}
+	return client.Close()
+}
+
+// sshdLockoutWarnings returns the warnings about the effective sshd
+// configuration, as printed by sshd -T, which locks out every user.
+func sshdLockoutWarnings(output string) []string {
+	options := map[string]string{}
+	for _, line := range strings.Split(output, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), " ")
+		if ok {
+			options[strings.ToLower(key)] = value
+		}
+	}
+
+	var warnings []string
+	if options["pubkeyauthentication"] == "no" &&
+		options["passwordauthentication"] == "no" &&
+		options["kbdinteractiveauthentication"] == "no" {
+		warnings = append(warnings, "sshd disables the public key, password and keyboard-interactive authentications, nobody will be able to log in")
+	}
+	if options["pubkeyauthentication"] != "no" && options["authorizedkeysfile"] == "none" &&
+		options["authorizedkeyscommand"] == "none" && options["passwordauthentication"] == "no" {
+		warnings = append(warnings, "sshd reads no authorized keys and disables the password authentication, nobody will be able to log in")
+	}
+	return warnings
+}
@@ -0,0 +1,110 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	gossh "golang.org/x/crypto/ssh"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepValidateSSHD(t *testing.T) {
+	// A port which does not accept connections
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	sshConfig := func(multistep.StateBag) (*gossh.ClientConfig, error) {
+		return &gossh.ClientConfig{User: "root", HostKeyCallback: gossh.InsecureIgnoreHostKey()}, nil
+	}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepValidateSSHD{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "lockout",
+			Step: &stepValidateSSHD{SSHConfig: sshConfig},
+			SetupConfigFunc: func(c *Config) {
+				c.ValidateSSHD = true
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = port
+				c.Comm.SSHUsername = "builder"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerIP, "127.0.0.1")
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{
+					StartStdout: "port 22\npubkeyauthentication no\npasswordauthentication no\nkbdinteractiveauthentication no\n",
+				})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				comm := state.Get(StateCommunicator).(*packersdk.MockCommunicator)
+				assert.Equal(t, "sudo -n /usr/sbin/sshd -T", comm.StartCmd.Command)
+
+				ui := state.Get(StateUI).(*packersdk.MockUi)
+				assert.True(t, ui.ErrorCalled)
+				assert.Contains(t, ui.ErrorMessage, "nobody will be able to log in")
+			},
+		},
+		{
+			Name: "invalid configuration",
+			Step: &stepValidateSSHD{SSHConfig: sshConfig},
+			SetupConfigFunc: func(c *Config) {
+				c.ValidateSSHD = true
+				c.Comm.Type = "ssh"
+				c.Comm.SSHUsername = "root"
+				c.Comm.SSHBastionHost = "bastion.example.com"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{
+					StartExitStatus: 255,
+					StartStderr:     "/etc/ssh/sshd_config line 3: Bad configuration option: Foo\n",
+				})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				comm := state.Get(StateCommunicator).(*packersdk.MockCommunicator)
+				assert.Equal(t, "/usr/sbin/sshd -T", comm.StartCmd.Command)
+
+				ui := state.Get(StateUI).(*packersdk.MockUi)
+				assert.Equal(t,
+					"Warning: the sshd configuration is invalid, sshd will not start: /etc/ssh/sshd_config line 3: Bad configuration option: Foo",
+					ui.ErrorMessage)
+				assert.Contains(t, ui.SayMessages[len(ui.SayMessages)-1].Message, "Validating the sshd configuration")
+			},
+		},
+	})
+}
+
+func TestSSHDLockoutWarnings(t *testing.T) {
+	testCases := []struct {
+		name   string
+		output string
+		want   int
+	}{
+		{name: "defaults", output: "pubkeyauthentication yes\npasswordauthentication no\nauthorizedkeysfile .ssh/authorized_keys\n", want: 0},
+		{name: "no authentication", output: "pubkeyauthentication no\npasswordauthentication no\nkbdinteractiveauthentication no\n", want: 1},
+		{name: "no authorized keys", output: "pubkeyauthentication yes\npasswordauthentication no\nauthorizedkeysfile none\nauthorizedkeyscommand none\n", want: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Len(t, sshdLockoutWarnings(tc.output), tc.want)
+		})
+	}
+}
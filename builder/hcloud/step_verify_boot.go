@@ -0,0 +1,136 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/sdk-internals/communicator/ssh"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// stepVerifyBoot boots the snapshot on the verify_boot server types and
+// locations in parallel, and checks that the communicator port accepts
+// connections on each, to validate the snapshot is portable before it is
+// published. The verification servers are always deleted. A snapshot which
+// failed the verification is deleted, and the old snapshot replaced with -force
+// is only deleted once the verification succeeded.
+type stepVerifyBoot struct{}
+
+func (s *stepVerifyBoot) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, client := UnpackState(state)
+
+	if len(c.VerifyBoot) == 0 {
+		return multistep.ActionContinue
+	}
+
+	snapshotID := state.Get(StateSnapshotID).(int64)
+	var sshKeys []*hcloud.SSHKey
+	if sshKeyID, ok := state.GetOk(StateSSHKeyID); ok {
+		sshKeys = []*hcloud.SSHKey{{ID: sshKeyID.(int64)}}
+	}
+
+	ui.Say(fmt.Sprintf("Verifying the snapshot boots on %d server types...", len(c.VerifyBoot)))
+
+	// The names are generated upfront, as the naming convention is not safe
+	// for concurrent use
+	names := make([]string, len(c.VerifyBoot))
+	for i := range c.VerifyBoot {
+		names[i] = c.resourceName()
+	}
+
+	targets := make([]verifyBootTarget, len(c.VerifyBoot))
+	for i, target := range c.VerifyBoot {
+		if target.Location == "" {
+			target.Location = c.Location
+		}
+		targets[i] = target
+	}
+
+	errs := make([]error, len(targets))
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target verifyBootTarget) {
+			defer wg.Done()
+			errs[i] = verifyBoot(ctx, client, ui, c, hcloud.ServerCreateOpts{
+				Name:       names[i],
+				ServerType: &hcloud.ServerType{Name: target.ServerType},
+				Image:      &hcloud.Image{ID: snapshotID},
+				Location:   &hcloud.Location{Name: target.Location},
+				SSHKeys:    sshKeys,
+				Labels:     c.buildLabels(),
+			})
+		}(i, target)
+	}
+	wg.Wait()
+
+	var failed []error
+	for i, target := range targets {
+		if errs[i] != nil {
+			ui.Errorf("Snapshot did not boot on %s in %s: %s", target.ServerType, target.Location, errs[i])
+			failed = append(failed, fmt.Errorf("%s in %s: %w", target.ServerType, target.Location, errs[i]))
+		} else {
+			ui.Message(fmt.Sprintf("Snapshot booted on %s in %s", target.ServerType, target.Location))
+		}
+	}
+	if len(failed) > 0 {
+		ui.Say(fmt.Sprintf("Deleting unverified snapshot with ID: %d", snapshotID))
+		// The snapshot must be deleted even if the build was cancelled
+		if _, err := client.Image.Delete(context.WithoutCancel(ctx), &hcloud.Image{ID: snapshotID}); err != nil {
+			ui.Error(fmt.Sprintf("Could not delete unverified snapshot id=%d: %s", snapshotID, err))
+		}
+		return errorHandler(state, ui, "Could not verify the snapshot boots", errors.Join(failed...))
+	}
+
+	return deleteOldSnapshot(ctx, state)
+}
+
+func (s *stepVerifyBoot) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// verifyBoot creates a server from the snapshot, waits for the port to accept
+// connections, and deletes the server.
+func verifyBoot(ctx context.Context, client *hcloud.Client, ui packersdk.Ui, c *Config, opts hcloud.ServerCreateOpts) error {
+	result, _, err := client.Server.Create(ctx, opts)
+	if err != nil {
+		return fmt.Errorf("could not create server: %w", err)
+	}
+	defer func() {
+		// The server must be deleted even if the build was cancelled
+		if _, _, err := client.Server.DeleteWithResult(context.Background(), result.Server); err != nil {
+			ui.Error(fmt.Sprintf("Could not delete verification server id=%d, delete it manually: %s", result.Server.ID, err))
+		}
+	}()
+
+	actions := append([]*hcloud.Action{result.Action}, result.NextActions...)
+	if err := client.Action.WaitFor(ctx, actions...); err != nil {
+		return fmt.Errorf("could not start server: %w", err)
+	}
+
+	serverIP := firstAvailableIP(result.Server)
+	if serverIP == "" {
+		return errors.New("could not find available ip")
+	}
+
+	port := c.Comm.Port()
+	ctx, cancel := context.WithTimeout(ctx, c.VerifyBootTimeout)
+	defer cancel()
+	// The verification servers are reached directly, the bastion or proxy of
+	// the communicator may not route to them
+	address := net.JoinHostPort(serverIP, strconv.Itoa(port))
+	if err := waitForTCP(ctx, address, ssh.ConnectFunc("tcp", address), c.PollInterval); err != nil {
+		return fmt.Errorf("timeout waiting for port %d on %s to accept connections", port, serverIP)
+	}
+	return nil
+}
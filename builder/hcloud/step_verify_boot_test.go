@@ -0,0 +1,166 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestStepVerifyBoot(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepVerifyBoot{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepVerifyBoot{},
+			SetupConfigFunc: func(c *Config) {
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = openPort
+				c.VerifyBoot = []verifyBootTarget{{ServerType: "cax11", Location: "hel1"}}
+				c.VerifyBootTimeout = time.Second
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSnapshotID, int64(16))
+				state.Put(StateSSHKeyID, int64(1))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers",
+					Want: func(t *testing.T, req *http.Request) {
+						payload := decodeJSONBody(t, req.Body, &schema.ServerCreateRequest{})
+						assert.Equal(t, "cax11", payload.ServerType.Name)
+						assert.Equal(t, "hel1", payload.Location)
+						assert.Equal(t, int64(16), payload.Image.ID)
+						assert.Equal(t, []int64{1}, payload.SSHKeys)
+					},
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 9, "name": "verify", "public_net": { "ipv4": { "ip": "127.0.0.1" }}},
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "DELETE", Path: "/servers/9",
+					Status: 200,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "delete old snapshot once verified",
+			Step: &stepVerifyBoot{},
+			SetupConfigFunc: func(c *Config) {
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = openPort
+				c.VerifyBoot = []verifyBootTarget{{ServerType: "cax11", Location: "hel1"}}
+				c.VerifyBootTimeout = time.Second
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSnapshotID, int64(16))
+				state.Put(StateSnapshotIDOld, int64(20))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers",
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 9, "name": "verify", "public_net": { "ipv4": { "ip": "127.0.0.1" }}},
+						"action": { "id": 3, "status": "success" }
+					}`,
+				},
+				{Method: "DELETE", Path: "/servers/9",
+					Status: 200,
+					JSONRaw: `{
+						"action": { "id": 4, "status": "running" }
+					}`,
+				},
+				{Method: "DELETE", Path: "/images/20",
+					Status: 204,
+				},
+			},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "keep old snapshot when verification fails",
+			Step: &stepVerifyBoot{},
+			SetupConfigFunc: func(c *Config) {
+				c.VerifyBoot = []verifyBootTarget{{ServerType: "cax11"}}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSnapshotID, int64(16))
+				state.Put(StateSnapshotIDOld, int64(20))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers",
+					Status: 201,
+					JSONRaw: `{
+						"server": { "id": 9, "name": "verify", "public_net": { "ipv4": { "ip": "127.0.0.1" }}},
+						"action": { "id": 3, "status": "error", "error": { "code": "boot_failed", "message": "could not boot" }}
+					}`,
+				},
+				{Method: "DELETE", Path: "/servers/9",
+					Status: 500,
+					JSONRaw: `{
+						"error": { "code": "server_error", "message": "internal error" }
+					}`,
+				},
+				{Method: "DELETE", Path: "/images/16",
+					Status: 204,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.ErrorContains(t, err, "Could not verify the snapshot boots: cax11 in nbg1: could not start server")
+			},
+		},
+		{
+			Name: "fail to create",
+			Step: &stepVerifyBoot{},
+			SetupConfigFunc: func(c *Config) {
+				c.VerifyBoot = []verifyBootTarget{{ServerType: "cax11"}}
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateSnapshotID, int64(16))
+			},
+			WantRequests: []mockutil.Request{
+				{Method: "POST", Path: "/servers",
+					Status: 400,
+					JSONRaw: `{
+						"error": { "code": "invalid_input", "message": "architecture mismatch" }
+					}`,
+				},
+				{Method: "DELETE", Path: "/images/16",
+					Status: 204,
+				},
+			},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.ErrorContains(t, err, "Could not verify the snapshot boots: cax11 in nbg1: could not create server: architecture mismatch")
+			},
+		},
+	})
+}
@@ -0,0 +1,82 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// The bootstrap script is written and run by cloud-init, which leaves a
+// marker once it completed, or failed.
+const (
+	bootstrapDir        = "/var/lib/packer-bootstrap"
+	bootstrapScriptPath = bootstrapDir + "/bootstrap.sh"
+	bootstrapDoneMarker = bootstrapDir + "/done"
+	bootstrapFailMarker = bootstrapDir + "/failed"
+	bootstrapLogPath    = "/var/log/packer-bootstrap.log"
+)
+
+// Exit status of the command checking the bootstrap markers.
+const (
+	bootstrapStatusDone    = 0
+	bootstrapStatusRunning = 1
+	bootstrapStatusFailed  = 2
+)
+
+// bootstrapCommand returns the cloud-init command running the bootstrap
+// script and leaving the completion marker.
+func bootstrapCommand() string {
+	return fmt.Sprintf("%s > %s 2>&1 && touch %s || touch %s",
+		bootstrapScriptPath, bootstrapLogPath, bootstrapDoneMarker, bootstrapFailMarker)
+}
+
+// stepWaitForBootstrap waits until the bootstrap script completed, before
+// provisioning the server.
+type stepWaitForBootstrap struct{}
+
+func (s *stepWaitForBootstrap) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.BootstrapScript == "" {
+		return multistep.ActionContinue
+	}
+
+	comm := state.Get(StateCommunicator).(packersdk.Communicator)
+
+	ui.Say("Waiting for the bootstrap script to complete...")
+	ctx, cancel := context.WithTimeout(ctx, c.BootstrapTimeout)
+	defer cancel()
+
+	command := fmt.Sprintf("test -f %s && exit %d; test -f %s && exit %d; exit %d",
+		bootstrapDoneMarker, bootstrapStatusDone,
+		bootstrapFailMarker, bootstrapStatusFailed,
+		bootstrapStatusRunning)
+	for {
+		cmd := &packersdk.RemoteCmd{Command: command}
+		if err := comm.Start(ctx, cmd); err != nil {
+			return errorHandler(state, ui, "Could not check the bootstrap script", err)
+		}
+		switch cmd.Wait() {
+		case bootstrapStatusDone:
+			return multistep.ActionContinue
+		case bootstrapStatusFailed:
+			return errorHandler(state, ui, "", fmt.Errorf("The bootstrap script failed, see %s on the server", bootstrapLogPath))
+		}
+
+		select {
+		case <-ctx.Done():
+			return errorHandler(state, ui, "", fmt.Errorf("Timeout waiting for the bootstrap script to complete"))
+		case <-time.After(c.PollInterval):
+		}
+	}
+}
+
+func (s *stepWaitForBootstrap) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepWaitForBootstrap(t *testing.T) {
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepWaitForBootstrap{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepWaitForBootstrap{},
+			SetupConfigFunc: func(c *Config) {
+				c.BootstrapScript = "bootstrap.sh"
+				c.BootstrapTimeout = time.Second
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{StartExitStatus: bootstrapStatusDone})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				comm := state.Get(StateCommunicator).(*packersdk.MockCommunicator)
+				assert.Equal(t,
+					"test -f /var/lib/packer-bootstrap/done && exit 0; test -f /var/lib/packer-bootstrap/failed && exit 2; exit 1",
+					comm.StartCmd.Command)
+			},
+		},
+		{
+			Name: "fail script",
+			Step: &stepWaitForBootstrap{},
+			SetupConfigFunc: func(c *Config) {
+				c.BootstrapScript = "bootstrap.sh"
+				c.BootstrapTimeout = time.Second
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{StartExitStatus: bootstrapStatusFailed})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				err, ok := state.Get(StateError).(error)
+				assert.True(t, ok)
+				assert.EqualError(t, err, "The bootstrap script failed, see /var/log/packer-bootstrap.log on the server")
+			},
+		},
+		{
+			Name: "fail timeout",
+			Step: &stepWaitForBootstrap{},
+			SetupConfigFunc: func(c *Config) {
+				c.BootstrapScript = "bootstrap.sh"
+				c.BootstrapTimeout = 50 * time.Millisecond
+				c.PollInterval = 10 * time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, &packersdk.MockCommunicator{StartExitStatus: bootstrapStatusRunning})
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+		},
+	})
+}
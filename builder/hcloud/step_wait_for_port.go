@@ -0,0 +1,77 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+)
+
+// stepWaitForPort waits before handing off to the communicator, optionally
+// pausing and checking that the communicator port accepts TCP connections. This
+// avoids noisy handshake retries on slow booting images.
+type stepWaitForPort struct{}
+
+func (s *stepWaitForPort) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if c.PauseAfterServerReady > 0 {
+		ui.Say(fmt.Sprintf("Waiting %s for the server to boot...", c.PauseAfterServerReady))
+		select {
+		case <-ctx.Done():
+			return errorHandler(state, ui, "", ctx.Err())
+		case <-time.After(c.PauseAfterServerReady):
+		}
+	}
+
+	if c.PortCheckTimeout == 0 {
+		return multistep.ActionContinue
+	}
+
+	serverIP := state.Get(StateServerIP).(string)
+	address := net.JoinHostPort(serverIP, strconv.Itoa(c.Comm.Port()))
+
+	connect, err := commConnectFunc(c, address)
+	if err != nil {
+		return errorHandler(state, ui, "", err)
+	}
+
+	ui.Say(fmt.Sprintf("Waiting for port %d to accept connections...", c.Comm.Port()))
+	ctx, cancel := context.WithTimeout(ctx, c.PortCheckTimeout)
+	defer cancel()
+
+	if err := waitForTCP(ctx, address, connect, c.PollInterval); err != nil {
+		return errorHandler(state, ui, "", fmt.Errorf("Timeout waiting for port %d on %s to accept connections", c.Comm.Port(), serverIP))
+	}
+	return multistep.ActionContinue
+}
+
+func (s *stepWaitForPort) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
+
+// waitForTCP waits until the address accepts TCP connections opened with
+// connect, or the context is done.
+func waitForTCP(ctx context.Context, address string, connect func() (net.Conn, error), interval time.Duration) error {
+	for {
+		conn, err := connect()
+		if err == nil {
+			conn.Close()
+			return nil
+		}
+		log.Printf("[DEBUG] TCP connection to %s failed: %s", address, err)
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepWaitForPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	closedListener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	closedPort := closedListener.Addr().(*net.TCPAddr).Port
+	closedListener.Close()
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepWaitForPort{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "port open",
+			Step: &stepWaitForPort{},
+			SetupConfigFunc: func(c *Config) {
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = openPort
+				c.PauseAfterServerReady = time.Millisecond
+				c.PortCheckTimeout = time.Second
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerIP, "127.0.0.1")
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "port closed",
+			Step: &stepWaitForPort{},
+			SetupConfigFunc: func(c *Config) {
+				c.Comm.Type = "ssh"
+				c.Comm.SSHPort = closedPort
+				c.PollInterval = 10 * time.Millisecond
+				c.PortCheckTimeout = 100 * time.Millisecond
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateServerIP, "127.0.0.1")
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionHalt,
+		},
+	})
+}
@@ -0,0 +1,88 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+// imageInfo is the breadcrumb written into the image, so servers created from
+// the snapshot can report which image they were cloned from.
+type imageInfo struct {
+	SnapshotName  string `json:"snapshot_name"`
+	BuildID       string `json:"build_id"`
+	BuildName     string `json:"build_name,omitempty"`
+	SourceImage   string `json:"source_image,omitempty"`
+	SourceImageID int64  `json:"source_image_id,omitempty"`
+	ServerType    string `json:"server_type"`
+	Location      string `json:"location"`
+	Created       string `json:"created"`
+}
+
+// stepWriteImageInfo writes the image info file into the image, after the
+// provisioning and before the snapshot is taken.
+type stepWriteImageInfo struct{}
+
+func (s *stepWriteImageInfo) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	c, ui, _ := UnpackState(state)
+
+	if !c.WriteImageInfo || c.SkipSnapshot {
+		return multistep.ActionContinue
+	}
+
+	comm, ok := state.Get(StateCommunicator).(packersdk.Communicator)
+	if !ok {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not write image info: no communicator"))
+	}
+
+	info := imageInfo{
+		SnapshotName: c.SnapshotName,
+		BuildID:      c.buildID,
+		BuildName:    c.PackerBuildName,
+		SourceImage:  c.Image,
+		ServerType:   c.ServerType,
+		Location:     c.Location,
+		Created:      time.Now().UTC().Format(time.RFC3339),
+	}
+	if sourceImageID, ok := state.GetOk(StateSourceImageID); ok {
+		info.SourceImageID = sourceImageID.(int64)
+	}
+	data, err := json.MarshalIndent(info, "", "  ")
+	if err != nil {
+		return errorHandler(state, ui, "Could not encode image info", err)
+	}
+
+	ui.Say(fmt.Sprintf("Writing image info to %s...", c.ImageInfoPath))
+
+	// The communicator user may not be allowed to write the file directly
+	tmpPath := "/tmp/packer-image-info.json"
+	if err := comm.Upload(tmpPath, bytes.NewReader(append(data, '\n')), nil); err != nil {
+		return errorHandler(state, ui, "Could not upload image info", err)
+	}
+
+	command := fmt.Sprintf("install -m 644 %s %s && rm -f %s", tmpPath, c.ImageInfoPath, tmpPath)
+	if c.Comm.SSHUsername != "root" {
+		command = fmt.Sprintf("sudo -n sh -c '%s'", command)
+	}
+	cmd := &packersdk.RemoteCmd{Command: command}
+	if err := cmd.RunWithUi(ctx, comm, ui); err != nil {
+		return errorHandler(state, ui, "Could not write image info", err)
+	}
+	if cmd.ExitStatus() != 0 {
+		return errorHandler(state, ui, "", fmt.Errorf("Could not write image info: exit status %d", cmd.ExitStatus()))
+	}
+
+	return multistep.ActionContinue
+}
+
+func (s *stepWriteImageInfo) Cleanup(state multistep.StateBag) {
+	// no cleanup
+}
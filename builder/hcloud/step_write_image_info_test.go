@@ -0,0 +1,58 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestStepWriteImageInfo(t *testing.T) {
+	comm := &packersdk.MockCommunicator{}
+
+	RunStepTestCases(t, []StepTestCase{
+		{
+			Name:           "disabled",
+			Step:           &stepWriteImageInfo{},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+		},
+		{
+			Name: "happy",
+			Step: &stepWriteImageInfo{},
+			SetupConfigFunc: func(c *Config) {
+				c.WriteImageInfo = true
+				c.ImageInfoPath = "/etc/packer-image-info.json"
+				c.Comm.SSHUsername = "admin"
+				c.buildID = "abc"
+			},
+			SetupStateFunc: func(state multistep.StateBag) {
+				state.Put(StateCommunicator, comm)
+				state.Put(StateSourceImageID, int64(114690387))
+			},
+			WantRequests:   []mockutil.Request{},
+			WantStepAction: multistep.ActionContinue,
+			WantStateFunc: func(t *testing.T, state multistep.StateBag) {
+				assert.Equal(t, "/tmp/packer-image-info.json", comm.UploadPath)
+
+				info := imageInfo{}
+				assert.NoError(t, json.Unmarshal([]byte(comm.UploadData), &info))
+				assert.Equal(t, "dummy-snapshot", info.SnapshotName)
+				assert.Equal(t, "abc", info.BuildID)
+				assert.Equal(t, "debian-12", info.SourceImage)
+				assert.Equal(t, int64(114690387), info.SourceImageID)
+
+				assert.Equal(t,
+					"sudo -n sh -c 'install -m 644 /tmp/packer-image-info.json /etc/packer-image-info.json && rm -f /tmp/packer-image-info.json'",
+					comm.StartCmd.Command)
+			},
+		},
+	})
+}
@@ -0,0 +1,294 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+)
+
+const (
+	// supportBundleAuditSize is the number of API requests kept for the bundle.
+	supportBundleAuditSize = 200
+	// supportBundleLogLines is the number of log lines fetched from the server.
+	supportBundleLogLines = 1000
+)
+
+// redactedConfigOptions matches the configuration options whose value must
+// not be written into the support bundle.
+var redactedConfigOptions = regexp.MustCompile(`(?i)token|password|passphrase|secret|private_key|user_data|variables`)
+
+// timelineEntry is a step of the build, as written into the support bundle.
+type timelineEntry struct {
+	Step     string    `json:"step"`
+	Started  time.Time `json:"started"`
+	Duration string    `json:"duration"`
+	Halted   bool      `json:"halted,omitempty"`
+}
+
+// auditEntry is an API request of the build, as written into the support
+// bundle.
+type auditEntry struct {
+	Time          time.Time `json:"time"`
+	Method        string    `json:"method"`
+	Path          string    `json:"path"`
+	Status        int       `json:"status,omitempty"`
+	Duration      string    `json:"duration"`
+	CorrelationID string    `json:"correlation_id,omitempty"`
+	Error         string    `json:"error,omitempty"`
+}
+
+// supportBundle records the timeline of the steps and the API requests of the
+// build, and writes them along with the redacted configuration, the server
+// details and its logs into a directory when a step fails, before the
+// resources are cleaned up.
+type supportBundle struct {
+	dir string
+
+	mu       sync.Mutex
+	timeline []timelineEntry
+	audit    []auditEntry
+	written  bool
+}
+
+func newSupportBundle(c *Config) *supportBundle {
+	return &supportBundle{
+		dir: filepath.Join(c.SupportBundleDir, fmt.Sprintf("packer-hcloud-%s", c.buildID)),
+	}
+}
+
+// transport returns a http.RoundTripper recording the API requests.
+func (b *supportBundle) transport(next http.RoundTripper) http.RoundTripper {
+	return &auditTransport{bundle: b, next: next}
+}
+
+// wrap returns the steps recording their timeline, and writing the bundle
+// when they fail.
+func (b *supportBundle) wrap(steps []multistep.Step) []multistep.Step {
+	wrapped := make([]multistep.Step, 0, len(steps))
+	for _, step := range steps {
+		wrapped = append(wrapped, &supportBundleStep{bundle: b, step: step})
+	}
+	return wrapped
+}
+
+// write writes the support bundle, only once per build.
+func (b *supportBundle) write(ctx context.Context, state multistep.StateBag) error {
+	b.mu.Lock()
+	if b.written {
+		b.mu.Unlock()
+		return nil
+	}
+	b.written = true
+	b.mu.Unlock()
+
+	c, _, client := UnpackState(state)
+
+	files := map[string]any{
+		"config.json": redactConfig(c),
+	}
+	if serverID, ok := state.GetOk(StateServerID); ok {
+		server, _, err := client.Server.GetByID(ctx, serverID.(int64))
+		if err != nil {
+			files["server.json"] = map[string]string{"error": err.Error()}
+		} else if server != nil {
+			files["server.json"] = server
+		}
+	}
+
+	b.mu.Lock()
+	files["timeline.json"] = b.timeline
+	files["api_audit.json"] = b.audit
+	b.mu.Unlock()
+
+	if err := os.MkdirAll(b.dir, 0o755); err != nil {
+		return err
+	}
+
+	if err, ok := state.GetOk(StateError); ok {
+		if err := os.WriteFile(filepath.Join(b.dir, "error.txt"), []byte(err.(error).Error()+"\n"), 0o644); err != nil {
+			return err
+		}
+	}
+	for name, content := range files {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return fmt.Errorf("could not encode %s: %w", name, err)
+		}
+		if err := os.WriteFile(filepath.Join(b.dir, name), append(data, '\n'), 0o644); err != nil {
+			return err
+		}
+	}
+
+	if comm, ok := state.Get(StateCommunicator).(packersdk.Communicator); ok {
+		if err := os.WriteFile(filepath.Join(b.dir, "server.log"), fetchServerLogs(ctx, c, comm), 0o644); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// fetchServerLogs returns the latest system logs of the server, or the reason
+// they could not be fetched. The logs are a best effort, the server may not be
+// reachable anymore.
+func fetchServerLogs(ctx context.Context, c *Config, comm packersdk.Communicator) []byte {
+	ctx, cancel := context.WithTimeout(ctx, time.Minute)
+	defer cancel()
+
+	command := fmt.Sprintf("journalctl -b --no-pager -n %[1]d || tail -n %[1]d /var/log/syslog /var/log/messages", supportBundleLogLines)
+	if c.Comm.SSHUsername != "root" {
+		command = fmt.Sprintf("sudo -n sh -c '%s' || %s", command, command)
+	}
+
+	var out bytes.Buffer
+	cmd := &packersdk.RemoteCmd{Command: command, Stdout: &out, Stderr: &out}
+	if err := comm.Start(ctx, cmd); err != nil {
+		return []byte(fmt.Sprintf("Could not fetch the server logs: %s\n", err))
+	}
+
+	done := make(chan int, 1)
+	go func() { done <- cmd.Wait() }()
+	select {
+	case <-done:
+		return out.Bytes()
+	case <-ctx.Done():
+		return []byte(fmt.Sprintf("Could not fetch the server logs: %s\n", ctx.Err()))
+	}
+}
+
+// redactConfig returns the configuration options by their template name, with
+// the values of the sensitive options redacted.
+func redactConfig(c *Config) map[string]any {
+	options := map[string]any{}
+	redactStruct(reflect.ValueOf(c).Elem(), options)
+	return options
+}
+
+func redactStruct(v reflect.Value, options map[string]any) {
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, flags, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+		value := v.Field(i)
+
+		if flags == "squash" {
+			if value.Kind() == reflect.Struct {
+				redactStruct(value, options)
+			}
+			continue
+		}
+		if name == "" || name == "-" || value.IsZero() {
+			continue
+		}
+		if redactedConfigOptions.MatchString(name) {
+			options[name] = "<redacted>"
+			continue
+		}
+
+		switch value.Kind() {
+		case reflect.Func, reflect.Chan, reflect.Interface, reflect.UnsafePointer:
+			continue
+		case reflect.Pointer:
+			if value.Elem().Kind() == reflect.Struct {
+				nested := map[string]any{}
+				redactStruct(value.Elem(), nested)
+				options[name] = nested
+				continue
+			}
+		case reflect.Struct:
+			if _, ok := value.Interface().(time.Time); !ok {
+				nested := map[string]any{}
+				redactStruct(value, nested)
+				options[name] = nested
+				continue
+			}
+		}
+		options[name] = value.Interface()
+	}
+}
+
+// supportBundleStep records the timeline of a step, and writes the support
+// bundle when the step fails.
+type supportBundleStep struct {
+	bundle *supportBundle
+	step   multistep.Step
+}
+
+func (s *supportBundleStep) Run(ctx context.Context, state multistep.StateBag) multistep.StepAction {
+	started := time.Now()
+	action := s.step.Run(ctx, state)
+
+	s.bundle.mu.Lock()
+	s.bundle.timeline = append(s.bundle.timeline, timelineEntry{
+		Step:     strings.TrimPrefix(fmt.Sprintf("%T", s.step), "*"),
+		Started:  started.UTC(),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+		Halted:   action == multistep.ActionHalt,
+	})
+	s.bundle.mu.Unlock()
+
+	if _, failed := state.GetOk(StateError); failed && action == multistep.ActionHalt {
+		ui := state.Get(StateUI).(packersdk.Ui)
+		ui.Say(fmt.Sprintf("Writing support bundle to %s...", s.bundle.dir))
+		if err := s.bundle.write(ctx, state); err != nil {
+			ui.Error(fmt.Sprintf("Could not write support bundle: %s", err))
+		}
+	}
+
+	return action
+}
+
+func (s *supportBundleStep) Cleanup(state multistep.StateBag) {
+	s.step.Cleanup(state)
+}
+
+// auditTransport records the API requests of the client for the support
+// bundle.
+type auditTransport struct {
+	bundle *supportBundle
+	next   http.RoundTripper
+}
+
+func (t *auditTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	started := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	entry := auditEntry{
+		Time:     started.UTC(),
+		Method:   req.Method,
+		Path:     req.URL.RequestURI(),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else {
+		entry.Status = resp.StatusCode
+		entry.CorrelationID = resp.Header.Get("X-Correlation-Id")
+	}
+
+	t.bundle.mu.Lock()
+	t.bundle.audit = append(t.bundle.audit, entry)
+	if len(t.bundle.audit) > supportBundleAuditSize {
+		t.bundle.audit = t.bundle.audit[len(t.bundle.audit)-supportBundleAuditSize:]
+	}
+	t.bundle.mu.Unlock()
+
+	return resp, err
+}
@@ -0,0 +1,127 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/multistep"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestRedactConfig(t *testing.T) {
+	c := &Config{
+		HCloudToken: "secret-token",
+		ServerType:  "cpx11",
+		UserData:    "#cloud-config\npassword: hunter2",
+		API:         &apiRoutes{ReadEndpoint: "https://read.example.com", ReadToken: "read-token"},
+	}
+	c.Comm.SSHPassword = "hunter2"
+
+	options := redactConfig(c)
+	assert.Equal(t, "<redacted>", options["token"])
+	assert.Equal(t, "<redacted>", options["user_data"])
+	assert.Equal(t, "<redacted>", options["ssh_password"])
+	assert.Equal(t, "cpx11", options["server_type"])
+	assert.Equal(t, map[string]any{
+		"read_endpoint": "https://read.example.com",
+		"read_token":    "<redacted>",
+	}, options["api"])
+	assert.NotContains(t, options, "location")
+
+	_, err := json.Marshal(options)
+	assert.NoError(t, err)
+}
+
+// failingStep is a step failing the build.
+type failingStep struct{}
+
+func (s *failingStep) Run(_ context.Context, state multistep.StateBag) multistep.StepAction {
+	state.Put(StateError, errors.New("boom"))
+	return multistep.ActionHalt
+}
+
+func (s *failingStep) Cleanup(multistep.StateBag) {}
+
+func TestSupportBundle(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/servers/1",
+			Status:  200,
+			JSONRaw: `{ "server": { "id": 1, "name": "dummy-server", "status": "running" }}`,
+		},
+	}))
+	defer server.Close()
+
+	c := &Config{SupportBundleDir: t.TempDir(), buildID: "build-id", HCloudToken: "secret-token"}
+	c.Comm.SSHUsername = "root"
+	bundle := newSupportBundle(c)
+	client := hcloud.NewClient(
+		hcloud.WithEndpoint(server.URL),
+		hcloud.WithHTTPClient(&http.Client{Transport: bundle.transport(http.DefaultTransport)}),
+	)
+
+	comm := &packersdk.MockCommunicator{StartStdout: "kernel: boot\n"}
+	var out bytes.Buffer
+	state := &multistep.BasicStateBag{}
+	state.Put(StateConfig, c)
+	state.Put(StateUI, &packersdk.BasicUi{Writer: &out, ErrorWriter: &out})
+	state.Put(StateHCloudClient, client)
+	state.Put(StateServerID, int64(1))
+	state.Put(StateCommunicator, comm)
+
+	steps := bundle.wrap([]multistep.Step{&stepEnableBackups{}, &failingStep{}})
+	runner := &multistep.BasicRunner{Steps: steps}
+	runner.Run(context.Background(), state)
+
+	dir := filepath.Join(c.SupportBundleDir, "packer-hcloud-build-id")
+	assert.Contains(t, out.String(), "Writing support bundle to "+dir)
+
+	content, err := os.ReadFile(filepath.Join(dir, "error.txt"))
+	require.NoError(t, err)
+	assert.Equal(t, "boom\n", string(content))
+
+	var timeline []timelineEntry
+	content, err = os.ReadFile(filepath.Join(dir, "timeline.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &timeline))
+	require.Len(t, timeline, 2)
+	assert.Equal(t, "hcloud.stepEnableBackups", timeline[0].Step)
+	assert.Equal(t, "hcloud.failingStep", timeline[1].Step)
+	assert.True(t, timeline[1].Halted)
+
+	var audit []auditEntry
+	content, err = os.ReadFile(filepath.Join(dir, "api_audit.json"))
+	require.NoError(t, err)
+	require.NoError(t, json.Unmarshal(content, &audit))
+	require.Len(t, audit, 1)
+	assert.Equal(t, "GET", audit[0].Method)
+	assert.Equal(t, "/servers/1", audit[0].Path)
+	assert.Equal(t, 200, audit[0].Status)
+
+	content, err = os.ReadFile(filepath.Join(dir, "config.json"))
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "secret-token")
+
+	content, err = os.ReadFile(filepath.Join(dir, "server.json"))
+	require.NoError(t, err)
+	assert.Contains(t, string(content), "dummy-server")
+
+	content, err = os.ReadFile(filepath.Join(dir, "server.log"))
+	require.NoError(t, err)
+	assert.Equal(t, "kernel: boot\n", string(content))
+	assert.Contains(t, comm.StartCmd.Command, "journalctl -b --no-pager -n 1000")
+}
@@ -0,0 +1,102 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"time"
+
+	helperssh "github.com/hashicorp/packer-plugin-sdk/communicator/ssh"
+	"github.com/hashicorp/packer-plugin-sdk/pathing"
+	"github.com/hashicorp/packer-plugin-sdk/sdk-internals/communicator/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+	"golang.org/x/net/proxy"
+)
+
+// commConnectFunc returns the function opening a TCP connection to the address
+// the way the SSH communicator does: through the ssh_bastion_host or the
+// ssh_proxy_host when set, so the checks of the server also work when it is
+// only reachable over them.
+func commConnectFunc(c *Config, address string) (func() (net.Conn, error), error) {
+	if c.Comm.Type != "ssh" {
+		return ssh.ConnectFunc("tcp", address), nil
+	}
+
+	switch {
+	case c.Comm.SSHBastionHost != "":
+		bastionConfig, err := bastionClientConfig(c)
+		if err != nil {
+			return nil, fmt.Errorf("could not configure the bastion: %w", err)
+		}
+		bastionAddress := net.JoinHostPort(c.Comm.SSHBastionHost, strconv.Itoa(c.Comm.SSHBastionPort))
+		return ssh.BastionConnectFunc("tcp", bastionAddress, bastionConfig, "tcp", address), nil
+	case c.Comm.SSHProxyHost != "":
+		var auth *proxy.Auth
+		if c.Comm.SSHProxyUsername != "" {
+			auth = &proxy.Auth{User: c.Comm.SSHProxyUsername, Password: c.Comm.SSHProxyPassword}
+		}
+		proxyAddress := net.JoinHostPort(c.Comm.SSHProxyHost, strconv.Itoa(c.Comm.SSHProxyPort))
+		return ssh.ProxyConnectFunc(proxyAddress, auth, "tcp", address), nil
+	default:
+		return ssh.ConnectFunc("tcp", address), nil
+	}
+}
+
+// bastionClientConfig returns the SSH client configuration of the bastion, as
+// configured for the communicator. The interactive authentication is not
+// supported, as the checks must not prompt in the middle of the build.
+func bastionClientConfig(c *Config) (*gossh.ClientConfig, error) {
+	var auth []gossh.AuthMethod
+
+	if c.Comm.SSHBastionPassword != "" {
+		auth = append(auth, gossh.Password(c.Comm.SSHBastionPassword))
+	}
+
+	if c.Comm.SSHBastionPrivateKeyFile != "" {
+		keyPath, err := pathing.ExpandUser(c.Comm.SSHBastionPrivateKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		var signer gossh.Signer
+		if c.Comm.SSHBastionCertificateFile != "" {
+			certPath, err := pathing.ExpandUser(c.Comm.SSHBastionCertificateFile)
+			if err != nil {
+				return nil, err
+			}
+			signer, err = helperssh.FileSignerWithCert(keyPath, certPath)
+			if err != nil {
+				return nil, err
+			}
+		} else {
+			signer, err = helperssh.FileSigner(keyPath)
+			if err != nil {
+				return nil, err
+			}
+		}
+		auth = append(auth, gossh.PublicKeys(signer))
+	}
+
+	if c.Comm.SSHBastionAgentAuth {
+		authSock := os.Getenv("SSH_AUTH_SOCK")
+		if authSock == "" {
+			return nil, fmt.Errorf("SSH_AUTH_SOCK is not set")
+		}
+		sshAgent, err := net.Dial("unix", authSock)
+		if err != nil {
+			return nil, fmt.Errorf("could not connect to the SSH agent socket %q: %w", authSock, err)
+		}
+		auth = append(auth, gossh.PublicKeysCallback(agent.NewClient(sshAgent).Signers))
+	}
+
+	return &gossh.ClientConfig{
+		User:            c.Comm.SSHBastionUsername,
+		Auth:            auth,
+		HostKeyCallback: gossh.InsecureIgnoreHostKey(), //nolint:gosec
+		Timeout:         30 * time.Second,
+	}, nil
+}
@@ -0,0 +1,39 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"net"
+	"testing"
+
+	"github.com/hashicorp/packer-plugin-sdk/communicator"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCommConnectFunc(t *testing.T) {
+	target, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer target.Close()
+
+	// A closed port, refusing the connections to the proxy
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	proxyPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	c := &Config{Comm: communicator.Config{Type: "ssh"}}
+	connect, err := commConnectFunc(c, target.Addr().String())
+	require.NoError(t, err)
+	conn, err := connect()
+	require.NoError(t, err)
+	conn.Close()
+
+	c.Comm.SSHProxyHost = "127.0.0.1"
+	c.Comm.SSHProxyPort = proxyPort
+	connect, err = commConnectFunc(c, target.Addr().String())
+	require.NoError(t, err)
+	_, err = connect()
+	assert.Error(t, err, "the connection must go through the proxy")
+}
@@ -0,0 +1,161 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"strings"
+)
+
+// cloudConfig is the subset of the cloud-config modules generated by the
+// builder. It is encoded as JSON, which is valid YAML.
+type cloudConfig struct {
+	BootCmd    []string          `json:"bootcmd,omitempty"`
+	APT        *cloudConfigAPT   `json:"apt,omitempty"`
+	WriteFiles []cloudConfigFile `json:"write_files,omitempty"`
+	RunCmd     []string          `json:"runcmd,omitempty"`
+}
+
+// cloudConfigFile is a file written on the first boot, see the write_files
+// module.
+type cloudConfigFile struct {
+	Path        string `json:"path"`
+	Content     string `json:"content"`
+	Encoding    string `json:"encoding,omitempty"`
+	Permissions string `json:"permissions,omitempty"`
+}
+
+// cloudConfigAPT configures the apt mirrors, see the apt_configure module.
+type cloudConfigAPT struct {
+	Primary  []cloudConfigAPTMirror `json:"primary,omitempty"`
+	Security []cloudConfigAPTMirror `json:"security,omitempty"`
+}
+
+type cloudConfigAPTMirror struct {
+	Arches []string `json:"arches"`
+	URI    string   `json:"uri"`
+}
+
+// buildUserData merges the user data of the user with the cloud-config
+// generated from the builder options. Both are combined in a MIME multi-part
+// archive, which cloud-init processes part by part.
+func buildUserData(c *Config, userData string) (string, error) {
+	generated := cloudConfig{}
+
+	if len(c.DNSServers) > 0 {
+		generated.BootCmd = append(generated.BootCmd, dnsServersCommand(c.DNSServers))
+	}
+
+	if c.APTMirror != "" || c.APTSecurityMirror != "" {
+		generated.APT = &cloudConfigAPT{}
+		if c.APTMirror != "" {
+			generated.APT.Primary = []cloudConfigAPTMirror{{Arches: []string{"default"}, URI: c.APTMirror}}
+		}
+		if c.APTSecurityMirror != "" {
+			generated.APT.Security = []cloudConfigAPTMirror{{Arches: []string{"default"}, URI: c.APTSecurityMirror}}
+		}
+	}
+
+	if c.BootstrapScript != "" {
+		script, err := os.ReadFile(c.BootstrapScript)
+		if err != nil {
+			return "", fmt.Errorf("could not read bootstrap_script: %w", err)
+		}
+		generated.WriteFiles = append(generated.WriteFiles, cloudConfigFile{
+			Path:        bootstrapScriptPath,
+			Content:     base64.StdEncoding.EncodeToString(script),
+			Encoding:    "b64",
+			Permissions: "0700",
+		})
+		generated.RunCmd = append(generated.RunCmd, bootstrapCommand())
+	}
+
+	if len(generated.BootCmd) == 0 && generated.APT == nil && len(generated.RunCmd) == 0 {
+		return userData, nil
+	}
+
+	var content bytes.Buffer
+	encoder := json.NewEncoder(&content)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(generated); err != nil {
+		return "", err
+	}
+
+	// The generated lists are appended to the ones of a cloud-config given as
+	// user data, instead of replacing them
+	parts := []userDataPart{{
+		contentType: "text/cloud-config",
+		mergeType:   cloudConfigMergeType,
+		content:     "#cloud-config\n" + content.String(),
+	}}
+	if userData != "" {
+		if strings.HasPrefix(userData, "Content-Type:") || strings.HasPrefix(userData, "MIME-Version:") {
+			return "", fmt.Errorf("user data already is a MIME multi-part archive, and cannot be merged with the generated cloud-config")
+		}
+		// cloud-init detects the type of text/plain parts from their first line.
+		parts = append([]userDataPart{{contentType: "text/plain", content: userData}}, parts...)
+	}
+
+	return multipartUserData(parts)
+}
+
+// cloudConfigMergeType merges the lists and maps of a cloud-config part into the
+// ones of the previous parts, instead of replacing them.
+const cloudConfigMergeType = "list(append)+dict(recurse_array)+str()"
+
+type userDataPart struct {
+	contentType string
+	mergeType   string
+	content     string
+}
+
+func multipartUserData(parts []userDataPart) (string, error) {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for _, part := range parts {
+		header := textproto.MIMEHeader{
+			"Content-Type": {part.contentType + `; charset="utf-8"`},
+		}
+		if part.mergeType != "" {
+			header.Set("Merge-Type", part.mergeType)
+		}
+		w, err := writer.CreatePart(header)
+		if err != nil {
+			return "", err
+		}
+		if _, err := w.Write([]byte(part.content)); err != nil {
+			return "", err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return "", err
+	}
+
+	header := fmt.Sprintf("Content-Type: multipart/mixed; boundary=\"%s\"\nMIME-Version: 1.0\n\n", writer.Boundary())
+	return header + body.String(), nil
+}
+
+// dnsServersCommand returns a shell command configuring the DNS resolvers,
+// using systemd-resolved when it is running, or /etc/resolv.conf otherwise.
+func dnsServersCommand(servers []string) string {
+	nameservers := make([]string, 0, len(servers))
+	for _, server := range servers {
+		nameservers = append(nameservers, "nameserver "+server)
+	}
+	return fmt.Sprintf(
+		"if [ -d /run/systemd/resolve ]; then "+
+			"mkdir -p /etc/systemd/resolved.conf.d && "+
+			"printf '[Resolve]\\nDNS=%s\\nDomains=~.\\n' > /etc/systemd/resolved.conf.d/99-packer-dns.conf && "+
+			"systemctl restart systemd-resolved; "+
+			"else printf '%s\\n' > /etc/resolv.conf; fi",
+		strings.Join(servers, " "),
+		strings.Join(nameservers, "\\n"),
+	)
+}
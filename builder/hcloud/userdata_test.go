@@ -0,0 +1,107 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package hcloud
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildUserData(t *testing.T) {
+	t.Run("nothing generated", func(t *testing.T) {
+		userData, err := buildUserData(&Config{}, "#!/bin/sh\necho hello\n")
+		require.NoError(t, err)
+		assert.Equal(t, "#!/bin/sh\necho hello\n", userData)
+	})
+
+	t.Run("merged", func(t *testing.T) {
+		userData, err := buildUserData(&Config{DNSServers: []string{"1.1.1.1", "9.9.9.9"}}, "#!/bin/sh\necho hello\n")
+		require.NoError(t, err)
+
+		parts, _ := parseMultipartUserData(t, userData)
+		require.Len(t, parts, 2)
+		assert.Equal(t, "#!/bin/sh\necho hello\n", parts[0])
+		assert.True(t, strings.HasPrefix(parts[1], "#cloud-config\n{\"bootcmd\":["))
+		assert.Contains(t, parts[1], `DNS=1.1.1.1 9.9.9.9`)
+		assert.Contains(t, parts[1], `nameserver 1.1.1.1\\nnameserver 9.9.9.9`)
+	})
+
+	t.Run("apt mirror", func(t *testing.T) {
+		userData, err := buildUserData(&Config{APTMirror: "https://mirror.hetzner.com/debian/packages"}, "")
+		require.NoError(t, err)
+
+		parts, _ := parseMultipartUserData(t, userData)
+		require.Len(t, parts, 1)
+		assert.Equal(t, "#cloud-config\n"+
+			`{"apt":{"primary":[{"arches":["default"],"uri":"https://mirror.hetzner.com/debian/packages"}]}}`+"\n", parts[0])
+	})
+
+	t.Run("bootstrap script", func(t *testing.T) {
+		script := filepath.Join(t.TempDir(), "bootstrap.sh")
+		require.NoError(t, os.WriteFile(script, []byte("#!/bin/sh\necho hello\n"), 0o644))
+
+		userData, err := buildUserData(&Config{BootstrapScript: script}, "")
+		require.NoError(t, err)
+
+		parts, _ := parseMultipartUserData(t, userData)
+		require.Len(t, parts, 1)
+		assert.Equal(t, "#cloud-config\n"+
+			`{"write_files":[{"path":"/var/lib/packer-bootstrap/bootstrap.sh","content":"IyEvYmluL3NoCmVjaG8gaGVsbG8K","encoding":"b64","permissions":"0700"}],`+
+			`"runcmd":["/var/lib/packer-bootstrap/bootstrap.sh > /var/log/packer-bootstrap.log 2>&1 && touch /var/lib/packer-bootstrap/done || touch /var/lib/packer-bootstrap/failed"]}`+"\n", parts[0])
+	})
+
+	t.Run("merged with cloud-config", func(t *testing.T) {
+		userData, err := buildUserData(&Config{DNSServers: []string{"1.1.1.1"}}, "#cloud-config\nruncmd:\n  - echo hello\n")
+		require.NoError(t, err)
+
+		parts, headers := parseMultipartUserData(t, userData)
+		require.Len(t, parts, 2)
+		assert.Equal(t, "#cloud-config\nruncmd:\n  - echo hello\n", parts[0])
+		assert.Empty(t, headers[0].Get("Merge-Type"))
+		assert.True(t, strings.HasPrefix(parts[1], "#cloud-config\n"))
+		assert.Equal(t, "list(append)+dict(recurse_array)+str()", headers[1].Get("Merge-Type"))
+	})
+
+	t.Run("already multipart", func(t *testing.T) {
+		_, err := buildUserData(&Config{DNSServers: []string{"1.1.1.1"}}, "Content-Type: multipart/mixed\n")
+		assert.Error(t, err)
+	})
+}
+
+func parseMultipartUserData(t *testing.T, userData string) ([]string, []textproto.MIMEHeader) {
+	t.Helper()
+
+	header, body, ok := strings.Cut(userData, "\n\n")
+	require.True(t, ok)
+	contentType, _, _ := strings.Cut(strings.Split(header, "\n")[0], ": ")
+	assert.Equal(t, "Content-Type", contentType)
+
+	_, params, err := mime.ParseMediaType(strings.TrimPrefix(strings.Split(header, "\n")[0], "Content-Type: "))
+	require.NoError(t, err)
+
+	parts := []string{}
+	headers := []textproto.MIMEHeader{}
+	reader := multipart.NewReader(strings.NewReader(body), params["boundary"])
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		content, err := io.ReadAll(part)
+		require.NoError(t, err)
+		parts = append(parts, string(content))
+		headers = append(headers, part.Header)
+	}
+	return parts, headers
+}
@@ -0,0 +1,131 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config,DatasourceOutput
+
+package channel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/hcl2helper"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/heroalex/packer-plugin-hcloud/post-processor/promote"
+	"github.com/heroalex/packer-plugin-hcloud/version"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+type Config struct {
+	HCloudToken string `mapstructure:"token"`
+	Endpoint    string `mapstructure:"endpoint"`
+
+	Channel      string `mapstructure:"channel"`
+	Architecture string `mapstructure:"architecture"`
+}
+
+type DatasourceOutput struct {
+	ID           int64             `mapstructure:"id"`
+	Name         string            `mapstructure:"name"`
+	Architecture string            `mapstructure:"architecture"`
+	Labels       map[string]string `mapstructure:"labels"`
+}
+
+type Datasource struct {
+	config Config
+}
+
+func (d *Datasource) ConfigSpec() hcldec.ObjectSpec {
+	return d.config.FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Configure(raws ...interface{}) error {
+	err := config.Decode(&d.config, nil, raws...)
+	if err != nil {
+		return err
+	}
+
+	c := &d.config
+
+	// Defaults
+	if c.HCloudToken == "" {
+		c.HCloudToken = os.Getenv("HCLOUD_TOKEN")
+	}
+	if c.Endpoint == "" {
+		if os.Getenv("HCLOUD_ENDPOINT") != "" {
+			c.Endpoint = os.Getenv("HCLOUD_ENDPOINT")
+		} else {
+			c.Endpoint = hcloud.Endpoint
+		}
+	}
+	if c.Architecture == "" {
+		c.Architecture = string(hcloud.ArchitectureX86)
+	}
+
+	var errs *packersdk.MultiError
+	if c.HCloudToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("token is missing, make sure to configure your Hetzner Cloud token"))
+	}
+	if c.Channel == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("channel is required"))
+	}
+	switch hcloud.Architecture(c.Architecture) {
+	case hcloud.ArchitectureX86, hcloud.ArchitectureARM:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("architecture must be one of 'x86' or 'arm', got '%s'", c.Architecture))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(c.HCloudToken)
+	return nil
+}
+
+func (d *Datasource) OutputSpec() hcldec.ObjectSpec {
+	return (&DatasourceOutput{}).FlatMapstructure().HCL2Spec()
+}
+
+func (d *Datasource) Execute() (cty.Value, error) {
+	client := hcloud.NewClient(
+		hcloud.WithToken(d.config.HCloudToken),
+		hcloud.WithEndpoint(d.config.Endpoint),
+		hcloud.WithApplication("hcloud-packer", version.PluginVersion.String()),
+		hcloud.WithDebugWriter(log.Writer()),
+	)
+
+	images, err := client.Image.AllWithOpts(context.TODO(), hcloud.ImageListOpts{
+		ListOpts:     hcloud.ListOpts{LabelSelector: fmt.Sprintf("%s=%s", promote.ChannelLabel, d.config.Channel)},
+		Type:         []hcloud.ImageType{hcloud.ImageTypeSnapshot},
+		Architecture: []hcloud.Architecture{hcloud.Architecture(d.config.Architecture)},
+		Status:       []hcloud.ImageStatus{hcloud.ImageStatusAvailable},
+	})
+	if err != nil {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("Could not fetch images of channel '%s': %w", d.config.Channel, err)
+	}
+	if len(images) == 0 {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("no image found in channel '%s' for architecture '%s'", d.config.Channel, d.config.Architecture)
+	}
+	if len(images) > 1 {
+		return cty.NullVal(cty.EmptyObject), fmt.Errorf("more than one image found in channel '%s' for architecture '%s'", d.config.Channel, d.config.Architecture)
+	}
+
+	output := DatasourceOutput{
+		ID:           images[0].ID,
+		Name:         images[0].Description,
+		Architecture: string(images[0].Architecture),
+		Labels:       images[0].Labels,
+	}
+	return hcl2helper.HCL2ValueFromConfig(output, d.OutputSpec()), nil
+}
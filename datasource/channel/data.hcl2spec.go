@@ -0,0 +1,66 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package channel
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	HCloudToken  *string `mapstructure:"token" cty:"token" hcl:"token"`
+	Endpoint     *string `mapstructure:"endpoint" cty:"endpoint" hcl:"endpoint"`
+	Channel      *string `mapstructure:"channel" cty:"channel" hcl:"channel"`
+	Architecture *string `mapstructure:"architecture" cty:"architecture" hcl:"architecture"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"token":        &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
+		"endpoint":     &hcldec.AttrSpec{Name: "endpoint", Type: cty.String, Required: false},
+		"channel":      &hcldec.AttrSpec{Name: "channel", Type: cty.String, Required: false},
+		"architecture": &hcldec.AttrSpec{Name: "architecture", Type: cty.String, Required: false},
+	}
+	return s
+}
+
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatDatasourceOutput struct {
+	ID           *int64            `mapstructure:"id" cty:"id" hcl:"id"`
+	Name         *string           `mapstructure:"name" cty:"name" hcl:"name"`
+	Architecture *string           `mapstructure:"architecture" cty:"architecture" hcl:"architecture"`
+	Labels       map[string]string `mapstructure:"labels" cty:"labels" hcl:"labels"`
+}
+
+// FlatMapstructure returns a new FlatDatasourceOutput.
+// FlatDatasourceOutput is an auto-generated flat version of DatasourceOutput.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*DatasourceOutput) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatDatasourceOutput)
+}
+
+// HCL2Spec returns the hcl spec of a DatasourceOutput.
+// This spec is used by HCL to read the fields of DatasourceOutput.
+// The decoded values from this spec will then be applied to a FlatDatasourceOutput.
+func (*FlatDatasourceOutput) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"id":           &hcldec.AttrSpec{Name: "id", Type: cty.Number, Required: false},
+		"name":         &hcldec.AttrSpec{Name: "name", Type: cty.String, Required: false},
+		"architecture": &hcldec.AttrSpec{Name: "architecture", Type: cty.String, Required: false},
+		"labels":       &hcldec.AttrSpec{Name: "labels", Type: cty.Map(cty.String), Required: false},
+	}
+	return s
+}
@@ -0,0 +1,46 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package channel
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/zclconf/go-cty/cty"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestDatasource_Impl(t *testing.T) {
+	var _ packersdk.Datasource = (*Datasource)(nil)
+}
+
+func TestDatasourceExecute(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/images?architecture=arm&label_selector=channel%3Dstable&page=1&status=available&type=snapshot",
+			Status: 200,
+			JSONRaw: `{
+				"images": [{ "id": 42, "description": "web-1", "architecture": "arm", "labels": { "channel": "stable" } }]
+			}`,
+		},
+	}))
+	defer server.Close()
+
+	d := &Datasource{}
+	err := d.Configure(map[string]interface{}{
+		"token":        "dummy",
+		"endpoint":     server.URL,
+		"channel":      "stable",
+		"architecture": "arm",
+	})
+	require.NoError(t, err)
+
+	value, err := d.Execute()
+	require.NoError(t, err)
+	assert.Equal(t, cty.NumberIntVal(42), value.GetAttr("id"))
+	assert.Equal(t, cty.StringVal("web-1"), value.GetAttr("name"))
+}
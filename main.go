@@ -10,12 +10,21 @@ import (
 	"github.com/hashicorp/packer-plugin-sdk/plugin"
 
 	"github.com/heroalex/packer-plugin-hcloud/builder/hcloud"
+	"github.com/heroalex/packer-plugin-hcloud/datasource/channel"
+	"github.com/heroalex/packer-plugin-hcloud/post-processor/promote"
+	smoketest "github.com/heroalex/packer-plugin-hcloud/post-processor/smoke-test"
 	"github.com/heroalex/packer-plugin-hcloud/version"
 )
 
 func main() {
 	pps := plugin.NewSet()
 	pps.RegisterBuilder(plugin.DEFAULT_NAME, new(hcloud.Builder))
+	// Explicit name of the default builder, to tell it apart from future
+	// builders of this plugin, e.g. hcloud-import.
+	pps.RegisterBuilder("server", new(hcloud.Builder))
+	pps.RegisterPostProcessor("smoke-test", new(smoketest.PostProcessor))
+	pps.RegisterPostProcessor("promote", new(promote.PostProcessor))
+	pps.RegisterDatasource("channel", new(channel.Datasource))
 	pps.SetVersion(version.PluginVersion)
 	err := pps.Run()
 	if err != nil {
@@ -0,0 +1,198 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package promote
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+
+	hcloudbuilder "github.com/heroalex/packer-plugin-hcloud/builder/hcloud"
+	"github.com/heroalex/packer-plugin-hcloud/version"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+// ChannelLabel is the label holding the name of the channel an image was
+// promoted to.
+const ChannelLabel = "channel"
+
+// SupersededLabel is the label holding the unix timestamp at which an image was
+// removed from its channel.
+const SupersededLabel = "channel-superseded-at"
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	HCloudToken string `mapstructure:"token"`
+	Endpoint    string `mapstructure:"endpoint"`
+
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+
+	Channel               string        `mapstructure:"channel"`
+	DeleteSupersededAfter time.Duration `mapstructure:"delete_superseded_after"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "hcloud-promote",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	c := &p.config
+
+	// Defaults
+	if c.HCloudToken == "" {
+		c.HCloudToken = os.Getenv("HCLOUD_TOKEN")
+	}
+	if c.Endpoint == "" {
+		if os.Getenv("HCLOUD_ENDPOINT") != "" {
+			c.Endpoint = os.Getenv("HCLOUD_ENDPOINT")
+		} else {
+			c.Endpoint = hcloud.Endpoint
+		}
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = 500 * time.Millisecond
+	}
+
+	var errs *packersdk.MultiError
+	if c.HCloudToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("token is missing, make sure to configure your Hetzner Cloud token"))
+	}
+	if c.Channel == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("channel is required"))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(c.HCloudToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != hcloudbuilder.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only promote snapshots from the hcloud builder", artifact.BuilderId())
+	}
+
+	imageID, err := hcloudbuilder.ParseArtifactID(artifact.Id())
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Could not parse snapshot ID '%s': %w", artifact.Id(), err)
+	}
+
+	client := hcloud.NewClient(
+		hcloud.WithToken(p.config.HCloudToken),
+		hcloud.WithEndpoint(p.config.Endpoint),
+		hcloud.WithPollOpts(hcloud.PollOpts{BackoffFunc: hcloud.ConstantBackoff(p.config.PollInterval)}),
+		hcloud.WithApplication("hcloud-packer", version.PluginVersion.String()),
+		hcloud.WithDebugWriter(log.Writer()),
+	)
+
+	image, _, err := client.Image.GetByID(ctx, imageID)
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Could not fetch snapshot '%d': %w", imageID, err)
+	}
+	if image == nil {
+		return nil, false, false, fmt.Errorf("Could not find snapshot '%d'", imageID)
+	}
+
+	previous, err := client.Image.AllWithOpts(ctx, hcloud.ImageListOpts{
+		ListOpts:     hcloud.ListOpts{LabelSelector: fmt.Sprintf("%s=%s", ChannelLabel, p.config.Channel)},
+		Type:         []hcloud.ImageType{hcloud.ImageTypeSnapshot},
+		Architecture: []hcloud.Architecture{image.Architecture},
+	})
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Could not fetch images of channel '%s': %w", p.config.Channel, err)
+	}
+
+	ui.Say(fmt.Sprintf("Promoting snapshot %d to channel '%s'...", image.ID, p.config.Channel))
+	labels := maps.Clone(image.Labels)
+	if labels == nil {
+		labels = make(map[string]string)
+	}
+	labels[ChannelLabel] = p.config.Channel
+	delete(labels, SupersededLabel)
+	if _, _, err := client.Image.Update(ctx, image, hcloud.ImageUpdateOpts{Labels: labels}); err != nil {
+		return nil, false, false, fmt.Errorf("Could not promote snapshot '%d': %w", image.ID, err)
+	}
+
+	for _, old := range previous {
+		if old.ID == image.ID {
+			continue
+		}
+		ui.Say(fmt.Sprintf("Removing snapshot %d from channel '%s'...", old.ID, p.config.Channel))
+		labels := maps.Clone(old.Labels)
+		delete(labels, ChannelLabel)
+		labels[SupersededLabel] = strconv.FormatInt(time.Now().Unix(), 10)
+		if _, _, err := client.Image.Update(ctx, old, hcloud.ImageUpdateOpts{Labels: labels}); err != nil {
+			return nil, false, false, fmt.Errorf("Could not remove snapshot '%d' from channel: %w", old.ID, err)
+		}
+	}
+
+	if p.config.DeleteSupersededAfter > 0 {
+		if err := deleteSuperseded(ctx, ui, client, time.Now().Add(-p.config.DeleteSupersededAfter)); err != nil {
+			return nil, false, false, err
+		}
+	}
+
+	return artifact, true, false, nil
+}
+
+// deleteSuperseded deletes the images that are out of every channel since
+// before the given time.
+func deleteSuperseded(ctx context.Context, ui packersdk.Ui, client *hcloud.Client, before time.Time) error {
+	images, err := client.Image.AllWithOpts(ctx, hcloud.ImageListOpts{
+		ListOpts: hcloud.ListOpts{LabelSelector: fmt.Sprintf("%s,!%s", SupersededLabel, ChannelLabel)},
+		Type:     []hcloud.ImageType{hcloud.ImageTypeSnapshot},
+	})
+	if err != nil {
+		return fmt.Errorf("Could not fetch superseded images: %w", err)
+	}
+
+	for _, image := range images {
+		superseded, err := strconv.ParseInt(image.Labels[SupersededLabel], 10, 64)
+		if err != nil {
+			ui.Error(fmt.Sprintf("Ignoring snapshot %d with invalid label %s=%s", image.ID, SupersededLabel, image.Labels[SupersededLabel]))
+			continue
+		}
+		if time.Unix(superseded, 0).After(before) {
+			continue
+		}
+
+		ui.Say(fmt.Sprintf("Deleting superseded snapshot %d...", image.ID))
+		if _, err := client.Image.Delete(ctx, image); err != nil {
+			return fmt.Errorf("Could not delete superseded snapshot '%d': %w", image.ID, err)
+		}
+	}
+	return nil
+}
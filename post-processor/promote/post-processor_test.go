@@ -0,0 +1,106 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package promote
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/schema"
+)
+
+func TestPostProcessor_Impl(t *testing.T) {
+	var _ packersdk.PostProcessor = (*PostProcessor)(nil)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"token": "dummy",
+	})
+	assert.ErrorContains(t, err, "channel is required")
+}
+
+func TestPostProcessorPostProcess(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "GET", Path: "/images/42",
+			Status: 200,
+			JSONRaw: `{
+				"image": { "id": 42, "type": "snapshot", "architecture": "x86", "labels": { "app": "web" } }
+			}`,
+		},
+		{Method: "GET", Path: "/images?architecture=x86&label_selector=channel%3Dstable&page=1&type=snapshot",
+			Status: 200,
+			JSONRaw: `{
+				"images": [{ "id": 41, "type": "snapshot", "architecture": "x86", "labels": { "app": "web", "channel": "stable" } }]
+			}`,
+		},
+		{Method: "PUT", Path: "/images/42",
+			Want: func(t *testing.T, req *http.Request) {
+				payload := &schema.ImageUpdateRequest{}
+				require.NoError(t, jsonDecode(req, payload))
+				assert.Equal(t, map[string]string{"app": "web", "channel": "stable"}, *payload.Labels)
+			},
+			Status: 200,
+			JSONRaw: `{
+				"image": { "id": 42 }
+			}`,
+		},
+		{Method: "PUT", Path: "/images/41",
+			Want: func(t *testing.T, req *http.Request) {
+				payload := &schema.ImageUpdateRequest{}
+				require.NoError(t, jsonDecode(req, payload))
+				labels := *payload.Labels
+				assert.Equal(t, "web", labels["app"])
+				assert.NotContains(t, labels, "channel")
+				assert.Contains(t, labels, "channel-superseded-at")
+			},
+			Status: 200,
+			JSONRaw: `{
+				"image": { "id": 41 }
+			}`,
+		},
+		{Method: "GET", Path: "/images?label_selector=channel-superseded-at%2C%21channel&page=1&type=snapshot",
+			Status: 200,
+			JSONRaw: `{
+				"images": [
+					{ "id": 40, "type": "snapshot", "labels": { "channel-superseded-at": "1700000000" } },
+					{ "id": 41, "type": "snapshot", "labels": { "channel-superseded-at": "4102444800" } }
+				]
+			}`,
+		},
+		{Method: "DELETE", Path: "/images/40",
+			Status: 204,
+		},
+	}))
+	defer server.Close()
+
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"token":    "dummy",
+		"endpoint": server.URL,
+		"channel":  "stable",
+
+		"delete_superseded_after": "24h",
+	})
+	require.NoError(t, err)
+
+	artifact := &packersdk.MockArtifact{BuilderIdValue: "hcloud.builder", IdValue: "42"}
+	result, keep, _, err := p.PostProcess(context.Background(), &packersdk.MockUi{}, artifact)
+	require.NoError(t, err)
+	assert.Equal(t, artifact, result)
+	assert.True(t, keep)
+}
+
+func jsonDecode(req *http.Request, v any) error {
+	return json.NewDecoder(req.Body).Decode(v)
+}
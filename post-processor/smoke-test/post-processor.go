@@ -0,0 +1,310 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+//go:generate packer-sdc mapstructure-to-hcl2 -type Config
+
+package smoketest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"time"
+
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/hashicorp/packer-plugin-sdk/common"
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/hashicorp/packer-plugin-sdk/template/config"
+	"github.com/hashicorp/packer-plugin-sdk/template/interpolate"
+	"github.com/hashicorp/packer-plugin-sdk/uuid"
+
+	hcloudbuilder "github.com/heroalex/packer-plugin-hcloud/builder/hcloud"
+	"github.com/heroalex/packer-plugin-hcloud/version"
+	"github.com/hetznercloud/hcloud-go/v2/hcloud"
+)
+
+type Config struct {
+	common.PackerConfig `mapstructure:",squash"`
+
+	HCloudToken string `mapstructure:"token"`
+	Endpoint    string `mapstructure:"endpoint"`
+
+	PollInterval time.Duration `mapstructure:"poll_interval"`
+
+	ServerCount int      `mapstructure:"server_count"`
+	ServerType  string   `mapstructure:"server_type"`
+	Location    string   `mapstructure:"location"`
+	UserData    string   `mapstructure:"user_data"`
+	SSHKeys     []string `mapstructure:"ssh_keys"`
+
+	LoadBalancerType string        `mapstructure:"load_balancer_type"`
+	Protocol         string        `mapstructure:"protocol"`
+	Port             int           `mapstructure:"port"`
+	HealthCheckPath  string        `mapstructure:"health_check_path"`
+	Timeout          time.Duration `mapstructure:"timeout"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) ConfigSpec() hcldec.ObjectSpec { return p.config.FlatMapstructure().HCL2Spec() }
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		PluginType:         "hcloud-smoke-test",
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	c := &p.config
+
+	// Defaults
+	if c.HCloudToken == "" {
+		c.HCloudToken = os.Getenv("HCLOUD_TOKEN")
+	}
+	if c.Endpoint == "" {
+		if os.Getenv("HCLOUD_ENDPOINT") != "" {
+			c.Endpoint = os.Getenv("HCLOUD_ENDPOINT")
+		} else {
+			c.Endpoint = hcloud.Endpoint
+		}
+	}
+	if c.PollInterval == 0 {
+		c.PollInterval = 500 * time.Millisecond
+	}
+	if c.ServerCount == 0 {
+		c.ServerCount = 2
+	}
+	if c.LoadBalancerType == "" {
+		c.LoadBalancerType = "lb11"
+	}
+	if c.Protocol == "" {
+		c.Protocol = string(hcloud.LoadBalancerServiceProtocolHTTP)
+	}
+	if c.Port == 0 {
+		c.Port = 80
+	}
+	if c.HealthCheckPath == "" {
+		c.HealthCheckPath = "/"
+	}
+	if c.Timeout == 0 {
+		c.Timeout = 10 * time.Minute
+	}
+
+	var errs *packersdk.MultiError
+	if c.HCloudToken == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("token is missing, make sure to configure your Hetzner Cloud token"))
+	}
+	if c.Location == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("location is required"))
+	}
+	if c.ServerType == "" {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("server type is required"))
+	}
+	if c.ServerCount < 1 {
+		errs = packersdk.MultiErrorAppend(
+			errs, errors.New("server_count must be at least 1"))
+	}
+	switch hcloud.LoadBalancerServiceProtocol(c.Protocol) {
+	case hcloud.LoadBalancerServiceProtocolHTTP, hcloud.LoadBalancerServiceProtocolTCP:
+	default:
+		errs = packersdk.MultiErrorAppend(
+			errs, fmt.Errorf("protocol must be one of 'http' or 'tcp', got '%s'", c.Protocol))
+	}
+
+	if errs != nil && len(errs.Errors) > 0 {
+		return errs
+	}
+
+	packersdk.LogSecretFilter.Set(c.HCloudToken)
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ctx context.Context, ui packersdk.Ui, artifact packersdk.Artifact) (packersdk.Artifact, bool, bool, error) {
+	if artifact.BuilderId() != hcloudbuilder.BuilderId {
+		return nil, false, false, fmt.Errorf(
+			"Unknown artifact type: %s\nCan only smoke test snapshots from the hcloud builder", artifact.BuilderId())
+	}
+
+	imageID, err := hcloudbuilder.ParseArtifactID(artifact.Id())
+	if err != nil {
+		return nil, false, false, fmt.Errorf("Could not parse snapshot ID '%s': %w", artifact.Id(), err)
+	}
+
+	client := hcloud.NewClient(
+		hcloud.WithToken(p.config.HCloudToken),
+		hcloud.WithEndpoint(p.config.Endpoint),
+		hcloud.WithPollOpts(hcloud.PollOpts{BackoffFunc: hcloud.ConstantBackoff(p.config.PollInterval)}),
+		hcloud.WithApplication("hcloud-packer", version.PluginVersion.String()),
+		hcloud.WithDebugWriter(log.Writer()),
+	)
+
+	smokeTest := &smokeTest{config: &p.config, client: client, ui: ui}
+	defer smokeTest.cleanup()
+
+	if err := smokeTest.run(ctx, imageID); err != nil {
+		return nil, false, false, err
+	}
+
+	ui.Say(fmt.Sprintf("Smoke test of snapshot %d succeeded", imageID))
+	return artifact, true, false, nil
+}
+
+type smokeTest struct {
+	config *Config
+	client *hcloud.Client
+	ui     packersdk.Ui
+
+	serverIds      []int64
+	loadBalancerId int64
+}
+
+func (s *smokeTest) run(ctx context.Context, imageID int64) error {
+	c := s.config
+	name := fmt.Sprintf("packer-smoke-test-%s", uuid.TimeOrderedUUID())
+
+	sshKeys := make([]*hcloud.SSHKey, 0, len(c.SSHKeys))
+	for _, idOrName := range c.SSHKeys {
+		sshKey, _, err := s.client.SSHKey.Get(ctx, idOrName)
+		if err != nil {
+			return fmt.Errorf("Could not fetch SSH key '%s': %w", idOrName, err)
+		}
+		if sshKey == nil {
+			return fmt.Errorf("Could not find SSH key '%s'", idOrName)
+		}
+		sshKeys = append(sshKeys, sshKey)
+	}
+
+	s.ui.Say(fmt.Sprintf("Creating %d servers from snapshot %d...", c.ServerCount, imageID))
+	targets := make([]hcloud.LoadBalancerCreateOptsTarget, 0, c.ServerCount)
+	for i := 0; i < c.ServerCount; i++ {
+		result, _, err := s.client.Server.Create(ctx, hcloud.ServerCreateOpts{
+			Name:       fmt.Sprintf("%s-%d", name, i),
+			ServerType: &hcloud.ServerType{Name: c.ServerType},
+			Image:      &hcloud.Image{ID: imageID},
+			Location:   &hcloud.Location{Name: c.Location},
+			UserData:   c.UserData,
+			SSHKeys:    sshKeys,
+		})
+		if err != nil {
+			return fmt.Errorf("Could not create server: %w", err)
+		}
+
+		// We use this in cleanup
+		s.serverIds = append(s.serverIds, result.Server.ID)
+
+		if err := s.client.Action.WaitFor(ctx, result.Action); err != nil {
+			return fmt.Errorf("Could not create server: %w", err)
+		}
+
+		targets = append(targets, hcloud.LoadBalancerCreateOptsTarget{
+			Type:   hcloud.LoadBalancerTargetTypeServer,
+			Server: hcloud.LoadBalancerCreateOptsTargetServer{Server: result.Server},
+		})
+	}
+
+	s.ui.Say("Creating load balancer...")
+	healthCheck := &hcloud.LoadBalancerCreateOptsServiceHealthCheck{
+		Protocol: hcloud.LoadBalancerServiceProtocol(c.Protocol),
+		Port:     hcloud.Ptr(c.Port),
+	}
+	if healthCheck.Protocol == hcloud.LoadBalancerServiceProtocolHTTP {
+		healthCheck.HTTP = &hcloud.LoadBalancerCreateOptsServiceHealthCheckHTTP{
+			Path: hcloud.Ptr(c.HealthCheckPath),
+		}
+	}
+	result, _, err := s.client.LoadBalancer.Create(ctx, hcloud.LoadBalancerCreateOpts{
+		Name:             name,
+		LoadBalancerType: &hcloud.LoadBalancerType{Name: c.LoadBalancerType},
+		Location:         &hcloud.Location{Name: c.Location},
+		Targets:          targets,
+		Services: []hcloud.LoadBalancerCreateOptsService{{
+			Protocol:        hcloud.LoadBalancerServiceProtocol(c.Protocol),
+			ListenPort:      hcloud.Ptr(c.Port),
+			DestinationPort: hcloud.Ptr(c.Port),
+			HealthCheck:     healthCheck,
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("Could not create load balancer: %w", err)
+	}
+
+	// We use this in cleanup
+	s.loadBalancerId = result.LoadBalancer.ID
+
+	if err := s.client.Action.WaitFor(ctx, result.Action); err != nil {
+		return fmt.Errorf("Could not create load balancer: %w", err)
+	}
+
+	s.ui.Say("Waiting for all targets to become healthy...")
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+
+	ticker := time.NewTicker(c.PollInterval)
+	defer ticker.Stop()
+	for {
+		loadBalancer, _, err := s.client.LoadBalancer.GetByID(ctx, s.loadBalancerId)
+		if err != nil {
+			return fmt.Errorf("Could not fetch load balancer: %w", err)
+		}
+		if loadBalancer == nil {
+			return fmt.Errorf("Could not find load balancer '%d'", s.loadBalancerId)
+		}
+		if allTargetsHealthy(loadBalancer, c.Port) {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("Load balancer targets did not become healthy: %w", ctx.Err())
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *smokeTest) cleanup() {
+	if s.loadBalancerId != 0 {
+		s.ui.Say("Deleting load balancer...")
+		_, err := s.client.LoadBalancer.Delete(context.TODO(), &hcloud.LoadBalancer{ID: s.loadBalancerId})
+		if err != nil {
+			s.ui.Error(fmt.Sprintf("Could not delete load balancer (please delete it manually): %s", err))
+		}
+	}
+
+	for _, serverID := range s.serverIds {
+		s.ui.Say(fmt.Sprintf("Destroying server %d...", serverID))
+		_, _, err := s.client.Server.DeleteWithResult(context.TODO(), &hcloud.Server{ID: serverID})
+		if err != nil {
+			s.ui.Error(fmt.Sprintf("Could not destroy server (please destroy it manually): %s", err))
+		}
+	}
+}
+
+func allTargetsHealthy(loadBalancer *hcloud.LoadBalancer, port int) bool {
+	if len(loadBalancer.Targets) == 0 {
+		return false
+	}
+	for _, target := range loadBalancer.Targets {
+		healthy := false
+		for _, status := range target.HealthStatus {
+			if status.ListenPort == port && status.Status == hcloud.LoadBalancerTargetHealthStatusStatusHealthy {
+				healthy = true
+			}
+		}
+		if !healthy {
+			return false
+		}
+	}
+	return true
+}
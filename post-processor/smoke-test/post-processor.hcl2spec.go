@@ -0,0 +1,71 @@
+// Code generated by "packer-sdc mapstructure-to-hcl2"; DO NOT EDIT.
+
+package smoketest
+
+import (
+	"github.com/hashicorp/hcl/v2/hcldec"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents of a field with a `mapstructure:,squash` tag are bubbled up.
+type FlatConfig struct {
+	PackerBuildName     *string           `mapstructure:"packer_build_name" cty:"packer_build_name" hcl:"packer_build_name"`
+	PackerBuilderType   *string           `mapstructure:"packer_builder_type" cty:"packer_builder_type" hcl:"packer_builder_type"`
+	PackerCoreVersion   *string           `mapstructure:"packer_core_version" cty:"packer_core_version" hcl:"packer_core_version"`
+	PackerDebug         *bool             `mapstructure:"packer_debug" cty:"packer_debug" hcl:"packer_debug"`
+	PackerForce         *bool             `mapstructure:"packer_force" cty:"packer_force" hcl:"packer_force"`
+	PackerOnError       *string           `mapstructure:"packer_on_error" cty:"packer_on_error" hcl:"packer_on_error"`
+	PackerUserVars      map[string]string `mapstructure:"packer_user_variables" cty:"packer_user_variables" hcl:"packer_user_variables"`
+	PackerSensitiveVars []string          `mapstructure:"packer_sensitive_variables" cty:"packer_sensitive_variables" hcl:"packer_sensitive_variables"`
+	HCloudToken         *string           `mapstructure:"token" cty:"token" hcl:"token"`
+	Endpoint            *string           `mapstructure:"endpoint" cty:"endpoint" hcl:"endpoint"`
+	PollInterval        *string           `mapstructure:"poll_interval" cty:"poll_interval" hcl:"poll_interval"`
+	ServerCount         *int              `mapstructure:"server_count" cty:"server_count" hcl:"server_count"`
+	ServerType          *string           `mapstructure:"server_type" cty:"server_type" hcl:"server_type"`
+	Location            *string           `mapstructure:"location" cty:"location" hcl:"location"`
+	UserData            *string           `mapstructure:"user_data" cty:"user_data" hcl:"user_data"`
+	SSHKeys             []string          `mapstructure:"ssh_keys" cty:"ssh_keys" hcl:"ssh_keys"`
+	LoadBalancerType    *string           `mapstructure:"load_balancer_type" cty:"load_balancer_type" hcl:"load_balancer_type"`
+	Protocol            *string           `mapstructure:"protocol" cty:"protocol" hcl:"protocol"`
+	Port                *int              `mapstructure:"port" cty:"port" hcl:"port"`
+	HealthCheckPath     *string           `mapstructure:"health_check_path" cty:"health_check_path" hcl:"health_check_path"`
+	Timeout             *string           `mapstructure:"timeout" cty:"timeout" hcl:"timeout"`
+}
+
+// FlatMapstructure returns a new FlatConfig.
+// FlatConfig is an auto-generated flat version of Config.
+// Where the contents a fields with a `mapstructure:,squash` tag are bubbled up.
+func (*Config) FlatMapstructure() interface{ HCL2Spec() map[string]hcldec.Spec } {
+	return new(FlatConfig)
+}
+
+// HCL2Spec returns the hcl spec of a Config.
+// This spec is used by HCL to read the fields of Config.
+// The decoded values from this spec will then be applied to a FlatConfig.
+func (*FlatConfig) HCL2Spec() map[string]hcldec.Spec {
+	s := map[string]hcldec.Spec{
+		"packer_build_name":          &hcldec.AttrSpec{Name: "packer_build_name", Type: cty.String, Required: false},
+		"packer_builder_type":        &hcldec.AttrSpec{Name: "packer_builder_type", Type: cty.String, Required: false},
+		"packer_core_version":        &hcldec.AttrSpec{Name: "packer_core_version", Type: cty.String, Required: false},
+		"packer_debug":               &hcldec.AttrSpec{Name: "packer_debug", Type: cty.Bool, Required: false},
+		"packer_force":               &hcldec.AttrSpec{Name: "packer_force", Type: cty.Bool, Required: false},
+		"packer_on_error":            &hcldec.AttrSpec{Name: "packer_on_error", Type: cty.String, Required: false},
+		"packer_user_variables":      &hcldec.AttrSpec{Name: "packer_user_variables", Type: cty.Map(cty.String), Required: false},
+		"packer_sensitive_variables": &hcldec.AttrSpec{Name: "packer_sensitive_variables", Type: cty.List(cty.String), Required: false},
+		"token":                      &hcldec.AttrSpec{Name: "token", Type: cty.String, Required: false},
+		"endpoint":                   &hcldec.AttrSpec{Name: "endpoint", Type: cty.String, Required: false},
+		"poll_interval":              &hcldec.AttrSpec{Name: "poll_interval", Type: cty.String, Required: false},
+		"server_count":               &hcldec.AttrSpec{Name: "server_count", Type: cty.Number, Required: false},
+		"server_type":                &hcldec.AttrSpec{Name: "server_type", Type: cty.String, Required: false},
+		"location":                   &hcldec.AttrSpec{Name: "location", Type: cty.String, Required: false},
+		"user_data":                  &hcldec.AttrSpec{Name: "user_data", Type: cty.String, Required: false},
+		"ssh_keys":                   &hcldec.AttrSpec{Name: "ssh_keys", Type: cty.List(cty.String), Required: false},
+		"load_balancer_type":         &hcldec.AttrSpec{Name: "load_balancer_type", Type: cty.String, Required: false},
+		"protocol":                   &hcldec.AttrSpec{Name: "protocol", Type: cty.String, Required: false},
+		"port":                       &hcldec.AttrSpec{Name: "port", Type: cty.Number, Required: false},
+		"health_check_path":          &hcldec.AttrSpec{Name: "health_check_path", Type: cty.String, Required: false},
+		"timeout":                    &hcldec.AttrSpec{Name: "timeout", Type: cty.String, Required: false},
+	}
+	return s
+}
@@ -0,0 +1,99 @@
+// Copyright (c) HashiCorp, Inc.
+// SPDX-License-Identifier: MPL-2.0
+
+package smoketest
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+
+	packersdk "github.com/hashicorp/packer-plugin-sdk/packer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/hetznercloud/hcloud-go/v2/hcloud/exp/mockutil"
+)
+
+func TestPostProcessor_Impl(t *testing.T) {
+	var _ packersdk.PostProcessor = (*PostProcessor)(nil)
+}
+
+func TestPostProcessorConfigure(t *testing.T) {
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"token":       "dummy",
+		"location":    "nbg1",
+		"server_type": "cpx11",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 2, p.config.ServerCount)
+	assert.Equal(t, "lb11", p.config.LoadBalancerType)
+	assert.Equal(t, "http", p.config.Protocol)
+	assert.Equal(t, 80, p.config.Port)
+
+	p = &PostProcessor{}
+	err = p.Configure(map[string]interface{}{
+		"token":       "dummy",
+		"location":    "nbg1",
+		"server_type": "cpx11",
+		"protocol":    "udp",
+	})
+	assert.ErrorContains(t, err, "protocol must be one of 'http' or 'tcp'")
+}
+
+func TestPostProcessorPostProcess(t *testing.T) {
+	server := httptest.NewServer(mockutil.Handler(t, []mockutil.Request{
+		{Method: "POST", Path: "/servers",
+			Status: 201,
+			JSONRaw: `{
+				"server": { "id": 8, "name": "dummy-server" },
+				"action": { "id": 3, "status": "success" }
+			}`,
+		},
+		{Method: "POST", Path: "/load_balancers",
+			Status: 201,
+			JSONRaw: `{
+				"load_balancer": { "id": 5 },
+				"action": { "id": 4, "status": "success" }
+			}`,
+		},
+		{Method: "GET", Path: "/load_balancers/5",
+			Status: 200,
+			JSONRaw: `{
+				"load_balancer": { "id": 5, "targets": [{
+					"type": "server",
+					"server": { "id": 8 },
+					"health_status": [{ "listen_port": 80, "status": "healthy" }]
+				}]}
+			}`,
+		},
+		{Method: "DELETE", Path: "/load_balancers/5",
+			Status: 204,
+		},
+		{Method: "DELETE", Path: "/servers/8",
+			Status: 200,
+			JSONRaw: `{
+				"action": { "id": 6, "status": "running" }
+			}`,
+		},
+	}))
+	defer server.Close()
+
+	p := &PostProcessor{}
+	err := p.Configure(map[string]interface{}{
+		"token":        "dummy",
+		"endpoint":     server.URL,
+		"location":     "nbg1",
+		"server_type":  "cpx11",
+		"server_count": 1,
+	})
+	require.NoError(t, err)
+
+	artifact := &packersdk.MockArtifact{BuilderIdValue: "hcloud.builder", IdValue: "42"}
+	result, keep, forceOverride, err := p.PostProcess(context.Background(), &packersdk.MockUi{}, artifact)
+	require.NoError(t, err)
+	assert.Equal(t, artifact, result)
+	assert.True(t, keep)
+	assert.False(t, forceOverride)
+}